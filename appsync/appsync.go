@@ -0,0 +1,218 @@
+// Package appsync bridges AWS AppSync direct Lambda resolvers to HTTP
+// handlers, so existing REST handlers can double as AppSync data sources.
+// Each resolver invocation becomes a POST request to /{parentTypeName}/{fieldName},
+// for example /Query/getUser, with the resolver arguments as the JSON body.
+// Routes can be mapped to existing REST endpoints with the Map method:
+//
+//	adapter := appsync.New(router)
+//	adapter.Map("Query", "getUser", "GET", "/users/{id}")
+//	lambda.Start(adapter.Proxy)
+//
+// The JSON body of successful responses is returned as the field value.
+// Responses with a status code of 400 or above are returned as errors.
+package appsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// Info describes the GraphQL field being resolved.
+type Info struct {
+	FieldName        string                 `json:"fieldName"`
+	ParentTypeName   string                 `json:"parentTypeName"`
+	Variables        map[string]interface{} `json:"variables"`
+	SelectionSetList []string               `json:"selectionSetList"`
+}
+
+// Request contains the headers of the GraphQL request received by AppSync.
+type Request struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// Event is the payload sent by AppSync to direct Lambda resolvers.
+type Event struct {
+	Arguments json.RawMessage        `json:"arguments"`
+	Identity  json.RawMessage        `json:"identity"`
+	Source    json.RawMessage        `json:"source"`
+	Request   Request                `json:"request"`
+	Info      Info                   `json:"info"`
+	Prev      json.RawMessage        `json:"prev"`
+	Stash     map[string]interface{} `json:"stash"`
+}
+
+// BatchResult is the result of a single event of a BatchInvoke operation.
+// The resolver response mapping template must return the Data field, or
+// raise the error, of each result.
+type BatchResult struct {
+	Data         json.RawMessage `json:"data"`
+	ErrorMessage string          `json:"errorMessage,omitempty"`
+	ErrorType    string          `json:"errorType,omitempty"`
+}
+
+type contextKey struct{}
+
+// EventFromContext returns the resolver event that generated the request.
+func EventFromContext(ctx context.Context) (Event, bool) {
+	event, ok := ctx.Value(contextKey{}).(Event)
+	return event, ok
+}
+
+type route struct {
+	method string
+	path   string
+}
+
+// Adapter converts resolver events into requests for the wrapped handler.
+type Adapter struct {
+	handler http.Handler
+	routes  map[string]route
+}
+
+// New creates a new Adapter that sends the resolver events to the given
+// handler.
+func New(handler http.Handler) *Adapter {
+	return &Adapter{
+		handler: handler,
+		routes:  make(map[string]route),
+	}
+}
+
+// Map routes the resolver of the given field to the method and path.
+// Segments of the path in the form {name} are replaced with the value of
+// the argument with the same name. Arguments are still sent in the body
+// of the request, except for GET and DELETE requests where the arguments
+// that are not used in the path are sent in the query string.
+func (a *Adapter) Map(typeName, fieldName, method, path string) {
+	a.routes[typeName+"."+fieldName] = route{
+		method: strings.ToUpper(method),
+		path:   path,
+	}
+}
+
+// Proxy sends a resolver event to the handler and returns the JSON body of
+// the response as the value of the field.
+func (a *Adapter) Proxy(event Event) (json.RawMessage, error) {
+	req, err := a.EventToHTTPRequest(event)
+	if err != nil {
+		return nil, core.NewLoggedError("Could not convert AppSync event to request: %v", err)
+	}
+
+	w := core.NewProxyResponseWriter()
+	a.handler.ServeHTTP(http.ResponseWriter(w), req)
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		return nil, core.NewLoggedError("Error while generating AppSync response: %v", err)
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		body, err = base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%d %s: %s", resp.StatusCode, http.StatusText(resp.StatusCode), strings.TrimSpace(string(body)))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	if !json.Valid(body) {
+		// plain text responses are returned as a GraphQL string
+		return json.Marshal(string(body))
+	}
+	return json.RawMessage(body), nil
+}
+
+// ProxyBatch sends each event of a BatchInvoke operation to the handler.
+// Errors are returned in the result of the event that caused them.
+func (a *Adapter) ProxyBatch(events []Event) ([]BatchResult, error) {
+	results := make([]BatchResult, len(events))
+	for i, event := range events {
+		data, err := a.Proxy(event)
+		if err != nil {
+			results[i] = BatchResult{ErrorMessage: err.Error(), ErrorType: "HandlerError"}
+			continue
+		}
+		results[i] = BatchResult{Data: data}
+	}
+	return results, nil
+}
+
+// EventToHTTPRequest converts a resolver event into an http.Request object.
+// The event is stored in the context of the request and can be read with
+// EventFromContext.
+func (a *Adapter) EventToHTTPRequest(event Event) (*http.Request, error) {
+	arguments := map[string]interface{}{}
+	if len(event.Arguments) > 0 {
+		// numbers are kept as json.Number, as float64 values would expand
+		// large Int arguments in exponent notation
+		decoder := json.NewDecoder(bytes.NewReader(event.Arguments))
+		decoder.UseNumber()
+		if err := decoder.Decode(&arguments); err != nil {
+			return nil, err
+		}
+	}
+
+	r, ok := a.routes[event.Info.ParentTypeName+"."+event.Info.FieldName]
+	if !ok {
+		r = route{
+			method: http.MethodPost,
+			path:   "/" + url.PathEscape(event.Info.ParentTypeName) + "/" + url.PathEscape(event.Info.FieldName),
+		}
+	}
+
+	path, unused := expandPath(r.path, arguments)
+	var body []byte
+	if r.method == http.MethodGet || r.method == http.MethodDelete {
+		query := url.Values{}
+		for name, value := range unused {
+			query.Set(name, fmt.Sprint(value))
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+	} else if len(event.Arguments) > 0 {
+		body = event.Arguments
+	}
+
+	req, err := http.NewRequest(r.method, core.DefaultServerAddress+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for h, value := range event.Request.Headers {
+		req.Header.Set(h, value)
+	}
+	// the headers are sent by the GraphQL client, it must not be able to
+	// forge the custom headers read by the RequestAccessor
+	core.RemoveCustomHeaders(req.Header)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, event)), nil
+}
+
+// expandPath replaces the {name} segments of the path with the escaped
+// value of the arguments and returns the arguments that were not used.
+func expandPath(path string, arguments map[string]interface{}) (string, map[string]interface{}) {
+	unused := make(map[string]interface{})
+	for name, value := range arguments {
+		placeholder := "{" + name + "}"
+		if strings.Contains(path, placeholder) {
+			path = strings.Replace(path, placeholder, url.PathEscape(fmt.Sprint(value)), -1)
+			continue
+		}
+		unused[name] = value
+	}
+	return path, unused
+}
@@ -0,0 +1,13 @@
+package appsync_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAppSync(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AppSync Suite")
+}
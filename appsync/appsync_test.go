@@ -0,0 +1,93 @@
+package appsync_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/appsync"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AppSync adapter tests", func() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Mutation/createUser", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		event, _ := appsync.EventFromContext(req.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"input":` + string(body) + `,"auth":"` + req.Header.Get("Authorization") + `","field":"` + event.Info.FieldName + `"}`))
+	})
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/users/missing" {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(req.Method + " " + req.URL.Path + " " + req.URL.Query().Get("expand")))
+	})
+
+	Context("Converting resolver events", func() {
+		It("Posts the arguments to the type and field path", func() {
+			adapter := appsync.New(mux)
+			data, err := adapter.Proxy(appsync.Event{
+				Arguments: json.RawMessage(`{"name":"alice"}`),
+				Request:   appsync.Request{Headers: map[string]string{"authorization": "token"}},
+				Info:      appsync.Info{ParentTypeName: "Mutation", FieldName: "createUser"},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal(`{"input":{"name":"alice"},"auth":"token","field":"createUser"}`))
+		})
+
+		It("Drops the custom headers sent by the client", func() {
+			adapter := appsync.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte(`"` + req.Header.Get(core.APIGwContextHeader) + `|` + req.Header.Get("Authorization") + `"`))
+			}))
+			data, err := adapter.Proxy(appsync.Event{
+				Request: appsync.Request{Headers: map[string]string{
+					"authorization":                 "token",
+					"x-golambdaproxy-apigw-context": `{"stage":"forged"}`,
+				}},
+				Info: appsync.Info{ParentTypeName: "Query", FieldName: "me"},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal(`"|token"`))
+		})
+
+		It("Maps fields to REST endpoints", func() {
+			adapter := appsync.New(mux)
+			adapter.Map("Query", "getUser", "GET", "/users/{id}")
+			data, err := adapter.Proxy(appsync.Event{
+				Arguments: json.RawMessage(`{"id":"a b","expand":true}`),
+				Info:      appsync.Info{ParentTypeName: "Query", FieldName: "getUser"},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal(`"GET /users/a b true"`))
+		})
+
+		It("Expands large integer arguments", func() {
+			adapter := appsync.New(mux)
+			adapter.Map("Query", "getUser", "GET", "/users/{id}")
+			data, err := adapter.Proxy(appsync.Event{
+				Arguments: json.RawMessage(`{"id":1234567,"expand":12345678901}`),
+				Info:      appsync.Info{ParentTypeName: "Query", FieldName: "getUser"},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal(`"GET /users/1234567 12345678901"`))
+		})
+
+		It("Returns errors for failed responses", func() {
+			adapter := appsync.New(mux)
+			adapter.Map("Query", "getUser", "GET", "/users/{id}")
+			results, err := adapter.ProxyBatch([]appsync.Event{
+				{Arguments: json.RawMessage(`{"id":"missing"}`), Info: appsync.Info{ParentTypeName: "Query", FieldName: "getUser"}},
+				{Arguments: json.RawMessage(`{"id":"1"}`), Info: appsync.Info{ParentTypeName: "Query", FieldName: "getUser"}},
+			})
+			Expect(err).To(BeNil())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].ErrorMessage).To(Equal("404 Not Found: user not found"))
+			Expect(string(results[1].Data)).To(Equal(`"GET /users/1 "`))
+		})
+	})
+})
@@ -0,0 +1,184 @@
+// Package audit records a summary of each request and response handled by
+// the framework adapters and delivers it to a pluggable Sink. The Logger
+// wraps any http.Handler, so the same audit trail can be added to Gin, Chi,
+// Gorilla Mux, Negroni or plain handlers without custom middleware.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// Record is the audit summary of a single request and its response.
+type Record struct {
+	Time          time.Time     `json:"time"`
+	RequestID     string        `json:"requestId,omitempty"`
+	Principal     string        `json:"principal,omitempty"`
+	SourceIP      string        `json:"sourceIp,omitempty"`
+	UserAgent     string        `json:"userAgent,omitempty"`
+	Method        string        `json:"method"`
+	Path          string        `json:"path"`
+	Status        int           `json:"status"`
+	ResponseBytes int           `json:"responseBytes"`
+	Latency       time.Duration `json:"latency"`
+}
+
+// Sink receives the audit records generated by a Logger.
+type Sink interface {
+	Write(record Record) error
+}
+
+// SinkFunc is an adapter that allows the use of ordinary functions as
+// audit sinks.
+type SinkFunc func(record Record) error
+
+// Write calls f(record).
+func (f SinkFunc) Write(record Record) error {
+	return f(record)
+}
+
+// NewJSONSink returns a Sink that writes each record as a line of JSON
+// to the given writer. When the writer is os.Stdout the records are
+// delivered to CloudWatch Logs.
+func NewJSONSink(w io.Writer) Sink {
+	s := &jsonSink{}
+	s.encoder = json.NewEncoder(w)
+	return s
+}
+
+type jsonSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func (s *jsonSink) Write(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(record)
+}
+
+// Logger generates audit records for the requests served by the handlers
+// it wraps.
+type Logger struct {
+	sink Sink
+}
+
+// New creates a new Logger that delivers its records to the given sink.
+func New(sink Sink) *Logger {
+	return &Logger{
+		sink: sink,
+	}
+}
+
+// Handler returns an http.Handler that serves requests with the next
+// handler and writes an audit record once the response is complete.
+// When the sink implements the Flusher interface the buffered records are
+// flushed before the handler returns, at the end of the Lambda invocation.
+func (l *Logger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rw := core.NewResponseRecorder(w)
+
+		next.ServeHTTP(rw, req)
+
+		record := l.newRecord(req)
+		record.Time = start
+		record.Status = rw.StatusCode()
+		record.ResponseBytes = int(rw.Written())
+		record.Latency = time.Since(start)
+
+		if err := l.sink.Write(record); err != nil {
+			log.Println("Could not write audit record")
+			log.Println(err)
+		}
+		if err := l.Flush(); err != nil {
+			log.Println("Could not flush audit records")
+			log.Println(err)
+		}
+	})
+}
+
+// Flush delivers the records buffered by the sink, if it implements the
+// Flusher interface.
+func (l *Logger) Flush() error {
+	if flusher, ok := l.sink.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// newRecord populates the request fields of a record. The identity of the
+// caller is read from the API Gateway context stored in the request context
+// by the conversion of the API Gateway and Function URL events, never from
+// the headers, which the client controls. The other events only provide the
+// request ID.
+func (l *Logger) newRecord(req *http.Request) Record {
+	record := Record{
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		UserAgent: req.UserAgent(),
+	}
+
+	if apiGwContext, ok := core.GetAPIGatewayContextFromContext(req.Context()); ok {
+		record.RequestID = apiGwContext.RequestID
+		record.SourceIP = apiGwContext.Identity.SourceIP
+		record.Principal = principal(apiGwContext.Authorizer, apiGwContext.Identity.UserArn, apiGwContext.Identity.CognitoIdentityID)
+	} else if v2Context, ok := core.GetAPIGatewayV2ContextFromContext(req.Context()); ok {
+		record.RequestID = v2Context.RequestID
+		record.SourceIP = v2Context.HTTP.SourceIP
+		record.Principal = principalV2(v2Context.Authorizer)
+	} else if functionURLContext, ok := core.GetFunctionURLContextFromContext(req.Context()); ok {
+		record.RequestID = functionURLContext.RequestID
+		record.SourceIP = functionURLContext.HTTP.SourceIP
+		if functionURLContext.Authorizer != nil && functionURLContext.Authorizer.IAM != nil {
+			record.Principal = functionURLContext.Authorizer.IAM.UserARN
+		}
+	} else if requestID, ok := core.RequestIDFromContext(req.Context()); ok {
+		record.RequestID = requestID
+	}
+	return record
+}
+
+// principal returns the identity of the caller, preferring the principal
+// returned by a custom authorizer, then the subject of a JWT or Cognito
+// authorizer, and finally the IAM or Cognito identity of the caller.
+func principal(authorizer map[string]interface{}, userArn string, cognitoIdentityID string) string {
+	if id, ok := authorizer["principalId"].(string); ok && id != "" {
+		return id
+	}
+	if claims, ok := authorizer["claims"].(map[string]interface{}); ok {
+		if sub, ok := claims["sub"].(string); ok && sub != "" {
+			return sub
+		}
+	}
+	if userArn != "" {
+		return userArn
+	}
+	return cognitoIdentityID
+}
+
+// principalV2 returns the identity of the caller of an HTTP API, preferring
+// the subject of a JWT authorizer, then the IAM or Cognito identity of the
+// caller.
+func principalV2(authorizer *events.APIGatewayV2HTTPRequestContextAuthorizerDescription) string {
+	if authorizer == nil {
+		return ""
+	}
+	if authorizer.JWT != nil && authorizer.JWT.Claims["sub"] != "" {
+		return authorizer.JWT.Claims["sub"]
+	}
+	if authorizer.IAM != nil {
+		if authorizer.IAM.UserARN != "" {
+			return authorizer.IAM.UserARN
+		}
+		return authorizer.IAM.CognitoIdentity.IdentityID
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/audit"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit logger tests", func() {
+	Context("Recording requests", func() {
+		It("Writes a record with the request and response summary", func() {
+			records := []audit.Record{}
+			logger := audit.New(audit.SinkFunc(func(record audit.Record) error {
+				records = append(records, record)
+				return nil
+			}))
+
+			handler := logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+			}))
+			adapter := httpadapter.New(handler)
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/orders",
+				HTTPMethod: "POST",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					RequestID: "req-1",
+					Identity: events.APIGatewayRequestIdentity{
+						SourceIP: "10.0.0.1",
+					},
+					Authorizer: map[string]interface{}{
+						"principalId": "user-1",
+					},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(1).To(Equal(len(records)))
+			Expect("POST").To(Equal(records[0].Method))
+			Expect("/orders").To(Equal(records[0].Path))
+			Expect(http.StatusCreated).To(Equal(records[0].Status))
+			Expect(7).To(Equal(records[0].ResponseBytes))
+			Expect("req-1").To(Equal(records[0].RequestID))
+			Expect("10.0.0.1").To(Equal(records[0].SourceIP))
+			Expect("user-1").To(Equal(records[0].Principal))
+			Expect(records[0].Time.IsZero()).To(BeFalse())
+		})
+
+		It("Reads the principal from JWT claims", func() {
+			var record audit.Record
+			logger := audit.New(audit.SinkFunc(func(r audit.Record) error {
+				record = r
+				return nil
+			}))
+			adapter := httpadapter.New(logger.Handler(http.NotFoundHandler()))
+
+			adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/missing",
+				HTTPMethod: "GET",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{"sub": "subject-1"},
+					},
+				},
+			})
+
+			Expect(http.StatusNotFound).To(Equal(record.Status))
+			Expect("subject-1").To(Equal(record.Principal))
+		})
+
+		It("Reads the HTTP API context", func() {
+			var record audit.Record
+			logger := audit.New(audit.SinkFunc(func(r audit.Record) error {
+				record = r
+				return nil
+			}))
+			adapter := httpadapter.New(logger.Handler(http.NotFoundHandler()))
+
+			adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RawPath: "/missing",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					RequestID: "req-2",
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+						Method:   "GET",
+						SourceIP: "10.0.0.2",
+					},
+					Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+						JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+							Claims: map[string]string{"sub": "subject-2"},
+						},
+					},
+				},
+			})
+
+			Expect(http.StatusNotFound).To(Equal(record.Status))
+			Expect("req-2").To(Equal(record.RequestID))
+			Expect("10.0.0.2").To(Equal(record.SourceIP))
+			Expect("subject-2").To(Equal(record.Principal))
+		})
+
+		It("Reads the Function URL context", func() {
+			var record audit.Record
+			logger := audit.New(audit.SinkFunc(func(r audit.Record) error {
+				record = r
+				return nil
+			}))
+			adapter := httpadapter.New(logger.Handler(http.NotFoundHandler()))
+
+			adapter.ProxyFunctionURL(events.LambdaFunctionURLRequest{
+				RawPath: "/missing",
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					RequestID: "req-3",
+					HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+						Method:   "GET",
+						SourceIP: "10.0.0.3",
+					},
+					Authorizer: &events.LambdaFunctionURLRequestContextAuthorizerDescription{
+						IAM: &events.LambdaFunctionURLRequestContextAuthorizerIAMDescription{
+							UserARN: "arn:aws:iam::123456789012:user/alice",
+						},
+					},
+				},
+			})
+
+			Expect("req-3").To(Equal(record.RequestID))
+			Expect("10.0.0.3").To(Equal(record.SourceIP))
+			Expect("arn:aws:iam::123456789012:user/alice").To(Equal(record.Principal))
+		})
+	})
+
+	Context("Forged identities", func() {
+		It("Ignores the API Gateway context header", func() {
+			var record audit.Record
+			logger := audit.New(audit.SinkFunc(func(r audit.Record) error {
+				record = r
+				return nil
+			}))
+
+			req := httptest.NewRequest("GET", "/orders", nil)
+			req.Header.Set(core.APIGwContextHeader, `{"requestId":"forged","identity":{"sourceIp":"1.2.3.4"},"authorizer":{"principalId":"admin"}}`)
+			logger.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), req)
+
+			Expect(record.Principal).To(BeEmpty())
+			Expect(record.SourceIP).To(BeEmpty())
+			Expect(record.RequestID).To(BeEmpty())
+		})
+	})
+
+	Context("JSON sink", func() {
+		It("Writes one JSON document per record", func() {
+			buf := &bytes.Buffer{}
+			sink := audit.NewJSONSink(buf)
+			Expect(sink.Write(audit.Record{Method: "GET", Path: "/a", Status: 200})).To(BeNil())
+			Expect(sink.Write(audit.Record{Method: "GET", Path: "/b", Status: 404})).To(BeNil())
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			Expect(2).To(Equal(len(lines)))
+			record := audit.Record{}
+			Expect(json.Unmarshal(lines[1], &record)).To(BeNil())
+			Expect("/b").To(Equal(record.Path))
+			Expect(404).To(Equal(record.Status))
+		})
+	})
+})
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Firehose PutRecordBatch limits
+const (
+	firehoseMaxBatchRecords = 500
+	firehoseMaxBatchBytes   = 4 * 1024 * 1024
+)
+
+// defaultS3MaxBatchRecords is the number of records buffered by the S3Sink
+// before an object is uploaded in the middle of an invocation.
+const defaultS3MaxBatchRecords = 1000
+
+// Flusher is implemented by sinks that buffer records. The Logger calls
+// Flush when a request completes so that no records are left in memory
+// when Lambda freezes the container at the end of the invocation.
+type Flusher interface {
+	Flush() error
+}
+
+// FirehoseClient is the subset of the Kinesis Firehose API used by the
+// FirehoseSink. Implementations normally wrap the PutRecordBatch method of
+// the AWS SDK client and must return an error when any record is rejected.
+type FirehoseClient interface {
+	PutRecordBatch(ctx context.Context, deliveryStreamName string, records [][]byte) error
+}
+
+// S3Client is the subset of the Amazon S3 API used by the S3Sink.
+// Implementations normally wrap the PutObject method of the AWS SDK client.
+type S3Client interface {
+	PutObject(ctx context.Context, bucket string, key string, body []byte) error
+}
+
+// FirehoseSink buffers audit records and ships them to a Kinesis Firehose
+// delivery stream in batches. Each record is delivered as a line of JSON.
+type FirehoseSink struct {
+	client     FirehoseClient
+	streamName string
+
+	mu      sync.Mutex
+	records [][]byte
+	size    int
+}
+
+// NewFirehoseSink creates a new FirehoseSink that delivers records to the
+// given delivery stream.
+func NewFirehoseSink(client FirehoseClient, deliveryStreamName string) *FirehoseSink {
+	return &FirehoseSink{
+		client:     client,
+		streamName: deliveryStreamName,
+	}
+}
+
+// Write adds the record to the current batch. The batch is sent to Firehose
+// when it reaches the limits of the PutRecordBatch API.
+func (s *FirehoseSink) Write(record Record) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.records) > 0 && (len(s.records) == firehoseMaxBatchRecords || s.size+len(data) > firehoseMaxBatchBytes) {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.records = append(s.records, data)
+	s.size += len(data)
+	return nil
+}
+
+// Flush sends the buffered records to Firehose.
+func (s *FirehoseSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *FirehoseSink) flush() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	err := s.client.PutRecordBatch(context.Background(), s.streamName, s.records)
+	if err != nil {
+		return fmt.Errorf("Could not put %d audit records to %s: %v", len(s.records), s.streamName, err)
+	}
+	s.records = nil
+	s.size = 0
+	return nil
+}
+
+// S3Sink buffers audit records and uploads them to an S3 bucket as
+// newline-delimited JSON objects. Objects are keyed by the upload time
+// under the configured prefix: prefix/yyyy/mm/dd/hh/<timestamp>-<random>.json
+type S3Sink struct {
+	client     S3Client
+	bucket     string
+	prefix     string
+	maxRecords int
+
+	mu      sync.Mutex
+	buffer  bytes.Buffer
+	records int
+}
+
+// NewS3Sink creates a new S3Sink that uploads records to the given bucket
+// under the given key prefix.
+func NewS3Sink(client S3Client, bucket string, prefix string) *S3Sink {
+	return &S3Sink{
+		client:     client,
+		bucket:     bucket,
+		prefix:     prefix,
+		maxRecords: defaultS3MaxBatchRecords,
+	}
+}
+
+// SetMaxBatchRecords sets the number of records buffered before an object
+// is uploaded without waiting for the end of the request.
+func (s *S3Sink) SetMaxBatchRecords(maxRecords int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxRecords = maxRecords
+}
+
+// Write adds the record to the current object.
+func (s *S3Sink) Write(record Record) error {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer.Write(data)
+	s.records++
+	if s.maxRecords > 0 && s.records >= s.maxRecords {
+		return s.flush()
+	}
+	return nil
+}
+
+// Flush uploads the buffered records to S3.
+func (s *S3Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *S3Sink) flush() error {
+	if s.records == 0 {
+		return nil
+	}
+	key, err := s.objectKey(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	body := make([]byte, s.buffer.Len())
+	copy(body, s.buffer.Bytes())
+	if err := s.client.PutObject(context.Background(), s.bucket, key, body); err != nil {
+		return fmt.Errorf("Could not upload %d audit records to s3://%s/%s: %v", s.records, s.bucket, key, err)
+	}
+	s.buffer.Reset()
+	s.records = 0
+	return nil
+}
+
+func (s *S3Sink) objectKey(now time.Time) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	prefix := s.prefix
+	if prefix != "" && prefix[len(prefix)-1] != '/' {
+		prefix += "/"
+	}
+	return fmt.Sprintf("%s%s%d-%s.json", prefix, now.Format("2006/01/02/15/"), now.UnixNano(), hex.EncodeToString(suffix)), nil
+}
+
+// encodeRecord marshals a record as a single line of JSON.
+func encodeRecord(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,127 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/audit"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeFirehose struct {
+	stream  string
+	batches [][][]byte
+	err     error
+}
+
+func (f *fakeFirehose) PutRecordBatch(ctx context.Context, deliveryStreamName string, records [][]byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.stream = deliveryStreamName
+	f.batches = append(f.batches, records)
+	return nil
+}
+
+type fakeS3 struct {
+	bucket  string
+	objects map[string]string
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, bucket string, key string, body []byte) error {
+	f.bucket = bucket
+	f.objects[key] = string(body)
+	return nil
+}
+
+var _ = Describe("Audit sinks tests", func() {
+	Context("Firehose sink", func() {
+		It("Buffers records until flushed", func() {
+			client := &fakeFirehose{}
+			sink := audit.NewFirehoseSink(client, "audit-stream")
+
+			Expect(sink.Write(audit.Record{Path: "/a"})).To(BeNil())
+			Expect(sink.Write(audit.Record{Path: "/b"})).To(BeNil())
+			Expect(0).To(Equal(len(client.batches)))
+
+			Expect(sink.Flush()).To(BeNil())
+			Expect("audit-stream").To(Equal(client.stream))
+			Expect(1).To(Equal(len(client.batches)))
+			Expect(2).To(Equal(len(client.batches[0])))
+			Expect(strings.HasSuffix(string(client.batches[0][0]), "\n")).To(BeTrue())
+
+			Expect(sink.Flush()).To(BeNil())
+			Expect(1).To(Equal(len(client.batches)))
+		})
+
+		It("Splits batches at the PutRecordBatch record limit", func() {
+			client := &fakeFirehose{}
+			sink := audit.NewFirehoseSink(client, "audit-stream")
+			for i := 0; i < 501; i++ {
+				Expect(sink.Write(audit.Record{Path: "/a"})).To(BeNil())
+			}
+			Expect(1).To(Equal(len(client.batches)))
+			Expect(500).To(Equal(len(client.batches[0])))
+
+			Expect(sink.Flush()).To(BeNil())
+			Expect(2).To(Equal(len(client.batches)))
+			Expect(1).To(Equal(len(client.batches[1])))
+		})
+
+		It("Returns the client errors", func() {
+			client := &fakeFirehose{err: errors.New("throttled")}
+			sink := audit.NewFirehoseSink(client, "audit-stream")
+			Expect(sink.Write(audit.Record{Path: "/a"})).To(BeNil())
+			Expect(sink.Flush()).ToNot(BeNil())
+		})
+
+		It("Is flushed by the logger at the end of the request", func() {
+			client := &fakeFirehose{}
+			logger := audit.New(audit.NewFirehoseSink(client, "audit-stream"))
+			adapter := httpadapter.New(logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("ok"))
+			})))
+
+			_, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/ping", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(1).To(Equal(len(client.batches)))
+		})
+	})
+
+	Context("S3 sink", func() {
+		It("Uploads newline-delimited JSON objects under the prefix", func() {
+			client := &fakeS3{objects: map[string]string{}}
+			sink := audit.NewS3Sink(client, "audit-bucket", "logs")
+
+			Expect(sink.Write(audit.Record{Path: "/a"})).To(BeNil())
+			Expect(sink.Write(audit.Record{Path: "/b"})).To(BeNil())
+			Expect(0).To(Equal(len(client.objects)))
+			Expect(sink.Flush()).To(BeNil())
+
+			Expect("audit-bucket").To(Equal(client.bucket))
+			Expect(1).To(Equal(len(client.objects)))
+			for key, body := range client.objects {
+				Expect(strings.HasPrefix(key, "logs/")).To(BeTrue())
+				Expect(strings.HasSuffix(key, ".json")).To(BeTrue())
+				Expect(2).To(Equal(strings.Count(body, "\n")))
+			}
+		})
+
+		It("Uploads when the batch size is reached", func() {
+			client := &fakeS3{objects: map[string]string{}}
+			sink := audit.NewS3Sink(client, "audit-bucket", "")
+			sink.SetMaxBatchRecords(2)
+
+			Expect(sink.Write(audit.Record{Path: "/a"})).To(BeNil())
+			Expect(0).To(Equal(len(client.objects)))
+			Expect(sink.Write(audit.Record{Path: "/b"})).To(BeNil())
+			Expect(1).To(Equal(len(client.objects)))
+		})
+	})
+})
@@ -0,0 +1,111 @@
+// Package auth protects handlers with HTTP Basic authentication and static
+// bearer tokens. Credentials are loaded from a secrets.Source, normally a
+// Secrets Manager secret or a Parameter Store parameter, so internal ALB
+// and Function URL endpoints can be protected without an authorizer.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+)
+
+// Credentials are the users and tokens accepted by the Middleware. The
+// secret or parameter must contain the JSON representation of this struct:
+//
+//	{"users": {"alice": "password"}, "tokens": ["token-1"]}
+type Credentials struct {
+	Users  map[string]string `json:"users"`
+	Tokens []string          `json:"tokens"`
+}
+
+type contextKey struct{}
+
+// UserFromContext returns the name of the user authenticated with Basic
+// authentication. Requests authenticated with a bearer token have no user.
+func UserFromContext(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(contextKey{}).(string)
+	return user, ok
+}
+
+// Middleware authenticates requests before passing them to the wrapped
+// handler.
+type Middleware struct {
+	source secrets.Source
+	realm  string
+}
+
+// New creates a new Middleware that reads the credentials from the given
+// source each time a request is authenticated. Sources returned by the
+// secrets.Client are cached, the credentials are not fetched on every request.
+func New(source secrets.Source) *Middleware {
+	return &Middleware{
+		source: source,
+		realm:  "Restricted",
+	}
+}
+
+// SetRealm sets the realm returned in the WWW-Authenticate header.
+func (m *Middleware) SetRealm(realm string) {
+	m.realm = realm
+}
+
+// Handler returns an http.Handler that authenticates the request and calls
+// the next handler. Requests without valid credentials receive a 401
+// response. When the credentials cannot be loaded the request receives a
+// 500 response.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		credentials, err := m.credentials()
+		if err != nil {
+			log.Println("Could not load credentials")
+			log.Println(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if user, pass, ok := req.BasicAuth(); ok {
+			if expected, exists := credentials.Users[user]; exists && equal(pass, expected) {
+				next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextKey{}, user)))
+				return
+			}
+		} else if token := bearerToken(req); token != "" {
+			for _, expected := range credentials.Tokens {
+				if equal(token, expected) {
+					next.ServeHTTP(w, req)
+					return
+				}
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+m.realm+`"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func (m *Middleware) credentials() (Credentials, error) {
+	credentials := Credentials{}
+	value, err := m.source.Value()
+	if err != nil {
+		return credentials, err
+	}
+	err = json.Unmarshal([]byte(value), &credentials)
+	return credentials, err
+}
+
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return header[7:]
+	}
+	return ""
+}
+
+func equal(given string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
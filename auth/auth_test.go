@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/auth"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth middleware tests", func() {
+	source := secrets.StaticSource(`{"users":{"alice":"wonderland"},"tokens":["token-1"]}`)
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+		w.Write([]byte("user:" + user))
+	})
+	adapter := httpadapter.New(auth.New(source).Handler(whoami))
+	withAuthorization := func(value string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Path:       "/",
+			HTTPMethod: "GET",
+			Headers:    map[string]string{"Authorization": value},
+		}
+	}
+
+	It("Accepts valid Basic credentials", func() {
+		resp, err := adapter.Proxy(withAuthorization("Basic " + base64.StdEncoding.EncodeToString([]byte("alice:wonderland"))))
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.Body).To(Equal("user:alice"))
+	})
+
+	It("Rejects invalid Basic credentials", func() {
+		resp, _ := adapter.Proxy(withAuthorization("Basic " + base64.StdEncoding.EncodeToString([]byte("alice:nope"))))
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(resp.Headers["Www-Authenticate"]).To(Equal(`Basic realm="Restricted"`))
+	})
+
+	It("Accepts valid bearer tokens", func() {
+		resp, _ := adapter.Proxy(withAuthorization("Bearer token-1"))
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.Body).To(Equal("user:"))
+
+		resp, _ = adapter.Proxy(withAuthorization("Bearer token-2"))
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Rejects requests without credentials", func() {
+		resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/", HTTPMethod: "GET"})
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Returns 500 when the credentials are invalid", func() {
+		broken := httpadapter.New(auth.New(secrets.StaticSource("not json")).Handler(whoami))
+		resp, _ := broken.Proxy(withAuthorization("Bearer token-1"))
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+})
@@ -0,0 +1,119 @@
+// Package canary routes a share of the incoming requests to an alternate
+// http.Handler. It makes it possible to canary a new framework, or a new
+// implementation of a handler, inside a single Lambda function: wrap both
+// handlers with New and pass the result to any of the adapters, for example
+// httpadapter.New.
+package canary
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultHeader is the request header that forces a request to the canary
+// ("true") or to the primary handler ("false").
+const DefaultHeader = "X-Canary"
+
+// Router is an http.Handler that sends each request either to the primary
+// or to the canary handler.
+type Router struct {
+	core.RequestAccessor
+
+	primary    http.Handler
+	canary     http.Handler
+	percentage float64
+	header     string
+	stageVar   string
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New creates a new Router. By default all requests are sent to the primary
+// handler unless they carry the DefaultHeader set to "true".
+func New(primary http.Handler, canary http.Handler) *Router {
+	return &Router{
+		primary: primary,
+		canary:  canary,
+		header:  DefaultHeader,
+		rand:    rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// SetPercentage sets the percentage, between 0 and 100, of the requests that
+// are randomly routed to the canary handler.
+func (r *Router) SetPercentage(percentage float64) {
+	r.percentage = percentage
+}
+
+// SetHeader changes the name of the header used to force the routing of a
+// request. An empty name disables the header.
+func (r *Router) SetHeader(name string) {
+	r.header = name
+}
+
+// SetStageVariable instructs the router to read its routing decision from
+// the given API Gateway stage variable. A value of "true" or "false" routes
+// all requests to the canary or primary handler, a number overrides the
+// percentage set with SetPercentage. This is useful with API Gateway canary
+// deployments, which can override stage variables for the canary traffic.
+func (r *Router) SetStageVariable(name string) {
+	r.stageVar = name
+}
+
+// ServeHTTP implementation from the http.Handler interface.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.useCanary(req) {
+		r.canary.ServeHTTP(w, req)
+		return
+	}
+	r.primary.ServeHTTP(w, req)
+}
+
+// useCanary decides where the request goes. The header takes precedence over
+// the stage variable, which takes precedence over the configured percentage.
+func (r *Router) useCanary(req *http.Request) bool {
+	if r.header != "" {
+		if forced, ok := parseBool(req.Header.Get(r.header)); ok {
+			return forced
+		}
+	}
+
+	percentage := r.percentage
+	if r.stageVar != "" {
+		if stageVars, err := r.GetAPIGatewayStageVars(req); err == nil {
+			value := stageVars[r.stageVar]
+			if forced, ok := parseBool(value); ok {
+				return forced
+			}
+			if p, err := strconv.ParseFloat(value, 64); err == nil {
+				percentage = p
+			}
+		}
+	}
+
+	if percentage <= 0 {
+		return false
+	}
+	if percentage >= 100 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()*100 < percentage
+}
+
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
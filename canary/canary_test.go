@@ -0,0 +1,82 @@
+package canary_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/canary"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, name)
+	})
+}
+
+var _ = Describe("Canary router tests", func() {
+	newRequest := func(headers map[string]string, stageVars map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Path:           "/",
+			HTTPMethod:     "GET",
+			Headers:        headers,
+			StageVariables: stageVars,
+		}
+	}
+
+	Context("Routing decisions", func() {
+		It("Sends requests to the primary handler by default", func() {
+			adapter := httpadapter.New(canary.New(namedHandler("primary"), namedHandler("canary")))
+			resp, err := adapter.Proxy(newRequest(nil, nil))
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("primary"))
+		})
+
+		It("Honors the canary header", func() {
+			router := canary.New(namedHandler("primary"), namedHandler("canary"))
+			adapter := httpadapter.New(router)
+			resp, _ := adapter.Proxy(newRequest(map[string]string{canary.DefaultHeader: "true"}, nil))
+			Expect(resp.Body).To(Equal("canary"))
+
+			router.SetPercentage(100)
+			resp, _ = adapter.Proxy(newRequest(map[string]string{canary.DefaultHeader: "false"}, nil))
+			Expect(resp.Body).To(Equal("primary"))
+		})
+
+		It("Routes by percentage", func() {
+			router := canary.New(namedHandler("primary"), namedHandler("canary"))
+			router.SetPercentage(100)
+			adapter := httpadapter.New(router)
+			resp, _ := adapter.Proxy(newRequest(nil, nil))
+			Expect(resp.Body).To(Equal("canary"))
+
+			router.SetPercentage(50)
+			counts := map[string]int{}
+			for i := 0; i < 200; i++ {
+				resp, _ = adapter.Proxy(newRequest(nil, nil))
+				counts[resp.Body]++
+			}
+			Expect(counts["primary"]).To(BeNumerically(">", 0))
+			Expect(counts["canary"]).To(BeNumerically(">", 0))
+		})
+
+		It("Reads the decision from a stage variable", func() {
+			router := canary.New(namedHandler("primary"), namedHandler("canary"))
+			router.SetStageVariable("canary")
+			adapter := httpadapter.New(router)
+
+			resp, _ := adapter.Proxy(newRequest(nil, map[string]string{"canary": "true"}))
+			Expect(resp.Body).To(Equal("canary"))
+
+			resp, _ = adapter.Proxy(newRequest(nil, map[string]string{"canary": "100"}))
+			Expect(resp.Body).To(Equal("canary"))
+
+			resp, _ = adapter.Proxy(newRequest(nil, map[string]string{"canary": "0"}))
+			Expect(resp.Body).To(Equal("primary"))
+		})
+	})
+})
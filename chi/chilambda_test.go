@@ -6,6 +6,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/awslabs/aws-lambda-go-api-proxy/chi"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/go-chi/chi"
 
 	. "github.com/onsi/ginkgo"
@@ -35,4 +36,41 @@ var _ = Describe("ChiLambda tests", func() {
 			Expect(resp.StatusCode).To(Equal(200))
 		})
 	})
+
+	Context("VPC Lattice request", func() {
+		It("Proxies the event and exposes the caller identity", func() {
+			r := chi.NewRouter()
+			r.Post("/orders", func(w http.ResponseWriter, req *http.Request) {
+				accessor := core.RequestAccessor{}
+				latticeContext, err := accessor.GetVPCLatticeContext(req)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Add("Vary", "Accept")
+				w.Header().Add("Vary", "Origin")
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(latticeContext.Identity.Principal + " " + req.URL.Query().Get("id") + " " + req.Header.Get("X-Tag")))
+			})
+
+			adapter := chiadapter.New(r)
+
+			resp, err := adapter.ProxyVPCLattice(core.VPCLatticeRequest{
+				Version:               "2.0",
+				Path:                  "/orders",
+				Method:                "POST",
+				Headers:               map[string][]string{"x-tag": {"a", "b"}},
+				QueryStringParameters: map[string][]string{"id": {"7"}},
+				RequestContext: core.VPCLatticeRequestContext{
+					Identity: core.VPCLatticeIdentity{Principal: "arn:aws:iam::123456789012:role/caller"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(resp.StatusDescription).To(Equal("202 Accepted"))
+			Expect(resp.Body).To(Equal("arn:aws:iam::123456789012:role/caller 7 a"))
+			Expect(resp.Headers["Vary"]).To(Equal("Accept,Origin"))
+		})
+	})
 })
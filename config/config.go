@@ -0,0 +1,130 @@
+// Package config resolves adapter options from a Secrets Manager secret or
+// a Parameter Store parameter, so the behavior of the adapters can be tuned
+// without redeploying the function. The options are loaded at cold start
+// and refreshed when the TTL of the secrets.Client cache expires:
+//
+//	client := secrets.NewClient()
+//	provider := config.New(client.Parameter("/my-api/adapter"), ginLambda)
+//	if err := provider.Load(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	func Handler(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+//		provider.Refresh()
+//		return ginLambda.Proxy(req)
+//	}
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+)
+
+// Options is the JSON document stored in the secret or parameter. Fields
+// that are omitted reset the corresponding option to its default value.
+type Options struct {
+	// BasePath is stripped from the request paths, see the StripBasePaths
+	// method of the adapter
+	BasePath string `json:"basePath"`
+	// BasePaths are stripped from the request paths along with BasePath
+	BasePaths []string `json:"basePaths"`
+	// BinaryContentTypes is passed to the SetBinaryContentTypes method of
+	// the adapter
+	BinaryContentTypes []string `json:"binaryContentTypes"`
+	// IntegrityHeader is one of "", "content-md5" or "etag"
+	IntegrityHeader string `json:"integrityHeader"`
+	// RateLimit is the number of requests per second served by each
+	// container, see core.RateLimiter. Zero disables the limit.
+	RateLimit float64 `json:"rateLimit"`
+	// RateLimitBurst is the number of requests served at once before the
+	// rate limit applies
+	RateLimitBurst int `json:"rateLimitBurst"`
+}
+
+// Adapter is the set of methods used to configure an adapter. All the
+// adapters in this repository implement it through the core.RequestAccessor
+// and core.ResponseOptions structs they embed.
+type Adapter interface {
+	StripBasePaths(basePaths ...string) []string
+	SetBinaryContentTypes(types ...string)
+	SetIntegrityHeader(h core.IntegrityHeader)
+	SetRateLimiter(limiter *core.RateLimiter)
+}
+
+// Provider loads the options from a source and applies them to an adapter.
+type Provider struct {
+	source  secrets.Source
+	adapter Adapter
+	current string
+}
+
+// New creates a new Provider that configures the adapter with the options
+// stored in the given source.
+func New(source secrets.Source, adapter Adapter) *Provider {
+	return &Provider{
+		source:  source,
+		adapter: adapter,
+	}
+}
+
+// Load reads the options from the source and applies them to the adapter.
+func (p *Provider) Load() error {
+	value, err := p.source.Value()
+	if err != nil {
+		return err
+	}
+	if value == p.current {
+		return nil
+	}
+
+	options := Options{}
+	if err := json.Unmarshal([]byte(value), &options); err != nil {
+		return fmt.Errorf("Could not unmarshal adapter options: %v", err)
+	}
+	if err := p.apply(options); err != nil {
+		return err
+	}
+	p.current = value
+	return nil
+}
+
+// Refresh reloads the options and logs any error, leaving the previous
+// options in place. It is meant to be called at the beginning of each
+// invocation, before the event is sent to the adapter.
+func (p *Provider) Refresh() {
+	if err := p.Load(); err != nil {
+		log.Println("Could not refresh adapter options")
+		log.Println(err)
+	}
+}
+
+func (p *Provider) apply(options Options) error {
+	integrityHeader := core.NoIntegrityHeader
+	switch strings.ToLower(options.IntegrityHeader) {
+	case "":
+	case "content-md5":
+		integrityHeader = core.ContentMD5Header
+	case "etag":
+		integrityHeader = core.ETagHeader
+	default:
+		return fmt.Errorf("Unknown integrity header: %s", options.IntegrityHeader)
+	}
+	if options.RateLimit < 0 {
+		return fmt.Errorf("Invalid rate limit: %v", options.RateLimit)
+	}
+	var limiter *core.RateLimiter
+	if options.RateLimit > 0 {
+		limiter = core.NewRateLimiter(options.RateLimit, options.RateLimitBurst)
+	}
+
+	p.adapter.StripBasePaths(append([]string{options.BasePath}, options.BasePaths...)...)
+	p.adapter.SetBinaryContentTypes(options.BinaryContentTypes...)
+	p.adapter.SetIntegrityHeader(integrityHeader)
+	p.adapter.SetRateLimiter(limiter)
+	return nil
+}
@@ -0,0 +1,112 @@
+package config_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/config"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mutableSource struct {
+	value string
+	err   error
+}
+
+func (s *mutableSource) Value() (string, error) {
+	return s.value, s.err
+}
+
+var _ = Describe("Config provider tests", func() {
+	echoPath := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	})
+	ordersRequest := events.APIGatewayProxyRequest{Path: "/v1/orders", HTTPMethod: "GET"}
+
+	It("Applies the options to the adapter", func() {
+		adapter := httpadapter.New(echoPath)
+		source := &mutableSource{value: `{"basePath":"v1","integrityHeader":"etag"}`}
+		provider := config.New(source, adapter)
+		Expect(provider.Load()).To(BeNil())
+
+		resp, err := adapter.Proxy(ordersRequest)
+		Expect(err).To(BeNil())
+		Expect(resp.Body).To(Equal("/orders"))
+		Expect(resp.Headers["Etag"]).ToNot(BeEmpty())
+	})
+
+	It("Applies the base paths and binary content types", func() {
+		adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/x-custom")
+			w.Write([]byte(req.URL.Path))
+		}))
+		source := &mutableSource{value: `{"basePaths":["v1","v2"],"binaryContentTypes":["application/x-*"]}`}
+		provider := config.New(source, adapter)
+		Expect(provider.Load()).To(BeNil())
+
+		resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/v2/orders", HTTPMethod: "GET"})
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeTrue())
+		Expect(resp.Body).To(Equal(base64.StdEncoding.EncodeToString([]byte("/orders"))))
+
+		source.value = `{}`
+		Expect(provider.Load()).To(BeNil())
+		resp, err = adapter.Proxy(events.APIGatewayProxyRequest{Path: "/v2/orders", HTTPMethod: "GET"})
+		Expect(err).To(BeNil())
+		Expect(resp.IsBase64Encoded).To(BeFalse())
+		Expect(resp.Body).To(Equal("/v2/orders"))
+	})
+
+	It("Updates the adapter when the options change", func() {
+		adapter := httpadapter.New(echoPath)
+		source := &mutableSource{value: `{"basePath":"v1"}`}
+		provider := config.New(source, adapter)
+		Expect(provider.Load()).To(BeNil())
+
+		source.value = `{}`
+		provider.Refresh()
+		resp, _ := adapter.Proxy(ordersRequest)
+		Expect(resp.Body).To(Equal("/v1/orders"))
+	})
+
+	It("Keeps the previous options on errors", func() {
+		adapter := httpadapter.New(echoPath)
+		source := &mutableSource{value: `{"basePath":"v1"}`}
+		provider := config.New(source, adapter)
+		Expect(provider.Load()).To(BeNil())
+
+		source.err = errors.New("unavailable")
+		Expect(provider.Load()).ToNot(BeNil())
+		source.err = nil
+		source.value = `{"integrityHeader":"sha1"}`
+		Expect(provider.Load()).ToNot(BeNil())
+
+		resp, _ := adapter.Proxy(ordersRequest)
+		Expect(resp.Body).To(Equal("/orders"))
+	})
+
+	It("Applies the rate limit", func() {
+		adapter := httpadapter.New(echoPath)
+		source := &mutableSource{value: `{"rateLimit":0.001,"rateLimitBurst":1}`}
+		provider := config.New(source, adapter)
+		Expect(provider.Load()).To(BeNil())
+
+		resp, _ := adapter.Proxy(ordersRequest)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		resp, _ = adapter.Proxy(ordersRequest)
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+
+		source.value = `{"rateLimit":-1}`
+		Expect(provider.Load()).ToNot(BeNil())
+
+		source.value = `{}`
+		Expect(provider.Load()).To(BeNil())
+		resp, _ = adapter.Proxy(ordersRequest)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	})
+})
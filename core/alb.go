@@ -0,0 +1,140 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SetHealthCheckPath instructs the RequestAccessor object to answer the GET
+// and HEAD requests of Application Load Balancer events for the given path
+// with the given status code, without converting them or sending them to the
+// framework. This keeps the target health checks fast and out of the logs of
+// the application. A status code of zero defaults to 200, an empty path
+// removes the fast path.
+func (r *RequestAccessor) SetHealthCheckPath(path string, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	r.healthCheckPath = path
+	r.healthCheckStatus = status
+}
+
+// ALBHealthCheckResponse returns the response to the given event if it is a
+// request for the health check path set with SetHealthCheckPath. The adapters
+// return it from ProxyALB. Returns false if the event must be routed.
+func (r *RequestAccessor) ALBHealthCheckResponse(event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, bool) {
+	if r.healthCheckPath == "" || event.Path != r.healthCheckPath {
+		return events.ALBTargetGroupResponse{}, false
+	}
+	if method := eventMethod(event.HTTPMethod); method != http.MethodGet && method != http.MethodHead {
+		return events.ALBTargetGroupResponse{}, false
+	}
+	return events.ALBTargetGroupResponse{
+		StatusCode:        r.healthCheckStatus,
+		StatusDescription: fmt.Sprintf("%d %s", r.healthCheckStatus, http.StatusText(r.healthCheckStatus)),
+	}, true
+}
+
+// ALBTargetGroupRequestToHTTPRequest converts an Application Load Balancer
+// target group event into an http.Request object.
+// Returns the populated request with an additional custom header for the
+// load balancer request context. To access it use the
+// GetALBTargetGroupRequestContext method of the RequestAccessor object.
+// Both the single and the multi value headers and query string parameters
+// are supported, depending on the configuration of the target group. Unlike
+// API Gateway, the load balancer does not decode the query string
+// parameters: they are passed to the request as they were received, sorted
+// by name like those of the API Gateway events. The RemoteAddr of the request is the client address appended by the load
+// balancer to the X-Forwarded-For header.
+func (r *RequestAccessor) ALBTargetGroupRequestToHTTPRequest(req events.ALBTargetGroupRequest) (*http.Request, error) {
+	queryString := joinQuery(queryValues(req.QueryStringParameters, req.MultiValueQueryStringParameters), func(s string) string { return s })
+	if queryString != "" {
+		queryString = "?" + queryString
+	}
+
+	httpRequest, err := http.NewRequest(
+		eventMethod(req.HTTPMethod),
+		r.requestURL(req.Path)+queryString,
+		nil,
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert ALB request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
+		log.Println(err)
+		return nil, err
+	}
+
+	if len(req.MultiValueHeaders) > 0 {
+		for h, values := range req.MultiValueHeaders {
+			for _, value := range values {
+				httpRequest.Header.Add(h, value)
+			}
+		}
+	} else {
+		for h := range req.Headers {
+			httpRequest.Header.Add(h, req.Headers[h])
+		}
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, "")
+	r.setForwarded(httpRequest)
+	r.overrideMethod(httpRequest)
+	if err := r.setBody(httpRequest, req.Body, req.IsBase64Encoded); err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, "")
+
+	if err := r.addJSONHeader(httpRequest, ALBContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+
+	return r.withRequestValues(httpRequest, headerNames(req.Headers, req.MultiValueHeaders)), nil
+}
+
+// GetALBTargetGroupResponse converts the data passed to the response writer
+// into an events.ALBTargetGroupResponse object.
+// Headers are returned both as single and multi value headers, the load
+// balancer uses the ones matching the configuration of the target group.
+// Returns an error if the status code was not set on the response.
+func (r *ProxyResponseWriter) GetALBTargetGroupResponse() (events.ALBTargetGroupResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.ALBTargetGroupResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(r.isBinary())
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+
+	headers := make(map[string]string)
+	multiValueHeaders := make(map[string][]string)
+	for h := range r.headers {
+		headers[h] = r.headers.Get(h)
+		multiValueHeaders[h] = r.headers[h]
+	}
+
+	resp := events.ALBTargetGroupResponse{
+		StatusCode:        r.status,
+		StatusDescription: fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		Headers:           headers,
+		MultiValueHeaders: multiValueHeaders,
+		Body:              output,
+		IsBase64Encoded:   isBase64,
+	}
+	retry, err := r.checkPayloadSize(resp, len(output))
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+	if retry {
+		return r.GetALBTargetGroupResponse()
+	}
+	return resp, nil
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// APIGwV2ContextHeader is the custom header key used to store the
+// API Gateway HTTP API request context. To access the Context properties
+// use the GetAPIGatewayV2Context method of the RequestAccessor object.
+const APIGwV2ContextHeader = "X-GoLambdaProxy-ApiGw-V2-Context"
+
+// GetAPIGatewayV2Context extracts the API Gateway HTTP API request context
+// from a request's custom header.
+// Returns a populated events.APIGatewayV2HTTPRequestContext object from
+// the request, or from the request context when the header was removed.
+func (r *RequestAccessor) GetAPIGatewayV2Context(req *http.Request) (events.APIGatewayV2HTTPRequestContext, error) {
+	if req.Header.Get(r.headerName(APIGwV2ContextHeader)) == "" {
+		if context, ok := GetAPIGatewayV2ContextFromContext(req.Context()); ok {
+			return context, nil
+		}
+		return events.APIGatewayV2HTTPRequestContext{}, errors.New("No v2 context header in request")
+	}
+	context := events.APIGatewayV2HTTPRequestContext{}
+	err := r.decodeJSONHeader(req, APIGwV2ContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling v2 context")
+		log.Println(err)
+		return events.APIGatewayV2HTTPRequestContext{}, err
+	}
+	return context, nil
+}
+
+// APIGatewayV2HTTPRequestToHTTPRequest converts an API Gateway HTTP API
+// event, using the 2.0 payload format, into an http.Request object.
+// The request is built from the RawPath and RawQueryString of the event, the
+// query string is copied byte for byte, and the cookies are joined in the
+// Cookie header.
+// Returns the populated request with an additional two custom headers for the
+// stage variables and the request context, which includes the RouteKey. To
+// access these properties use the GetAPIGatewayStageVars and
+// GetAPIGatewayV2Context method of the RequestAccessor object. The path
+// parameters are stored in the context of the request and can be read with
+// the GetAPIGatewayPathParams method.
+func (r *RequestAccessor) APIGatewayV2HTTPRequestToHTTPRequest(req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	httpRequest, err := r.newV2Request(req.RequestContext.HTTP.Method, req.RequestContext.DomainName, r.stageRelativePath(req.RawPath, req.RequestContext.Stage), req.RawQueryString, req.Cookies, req.Headers, req.Body, req.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, req.RequestContext.HTTP.SourceIP)
+	httpRequest = setProto(httpRequest, req.RequestContext.HTTP.Protocol)
+
+	if err := r.addJSONHeader(httpRequest, APIGwV2ContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+	if err := r.addJSONHeader(httpRequest, APIGwStageVarsHeader, stageVariables(req.StageVariables)); err != nil {
+		return nil, err
+	}
+	httpRequest = withValue(httpRequest, apiGwV2ContextContextKey, req.RequestContext)
+	httpRequest = withValue(httpRequest, stageVarsContextKey, stageVariables(req.StageVariables))
+	routeKey := req.RouteKey
+	if routeKey == "" {
+		routeKey = req.RequestContext.RouteKey
+	}
+	httpRequest = withValue(httpRequest, resourceContextKey, routeKeyResource(routeKey))
+	httpRequest = r.withRequestID(httpRequest, req.RequestContext.RequestID)
+
+	return withPathParams(httpRequest, req.PathParameters), nil
+}
+
+// newV2Request builds an http.Request from the fields shared by the events
+// using the 2.0 payload format. Multiple values of the same header are
+// joined with commas in the event and are added to the request as is.
+func (r *RequestAccessor) newV2Request(method, domainName, rawPath, rawQueryString string, cookies []string, headers map[string]string, body string, isBase64Encoded bool) (*http.Request, error) {
+	httpRequest, err := http.NewRequest(
+		eventMethod(method),
+		r.requestURL(rawPath),
+		nil,
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert request %s:%s to http.Request\n", method, rawPath)
+		log.Println(err)
+		return nil, err
+	}
+	// the raw query string is used verbatim, without being parsed, so that
+	// the encoding, the order and the repeated keys of the parameters are
+	// preserved for handlers that verify request signatures
+	httpRequest.URL.RawQuery = rawQueryString
+
+	for h := range headers {
+		httpRequest.Header.Add(h, headers[h])
+	}
+	// the 2.0 payload format moves the cookies out of the headers, they are
+	// joined back in a single Cookie header so that req.Cookies() works.
+	// Cookies sent in a header anyway are kept in front.
+	if len(cookies) > 0 {
+		if existing := httpRequest.Header.Get("Cookie"); existing != "" {
+			cookies = append([]string{existing}, cookies...)
+		}
+		httpRequest.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, domainName)
+	r.setForwarded(httpRequest)
+	r.overrideMethod(httpRequest)
+	if err := r.setBody(httpRequest, body, isBase64Encoded); err != nil {
+		return nil, err
+	}
+
+	return r.withRequestValues(httpRequest, headerNames(headers, nil)), nil
+}
+
+// GetAPIGatewayV2HTTPResponse converts the data passed to the response
+// writer into an events.APIGatewayV2HTTPResponse object.
+// Multiple values of the same header are joined with commas, except for the
+// Set-Cookie headers which are returned in the Cookies field.
+// Returns an error if the status code was not set on the response.
+func (r *ProxyResponseWriter) GetAPIGatewayV2HTTPResponse() (events.APIGatewayV2HTTPResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.APIGatewayV2HTTPResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(false)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	headers, cookies := r.v2Headers()
+	resp := events.APIGatewayV2HTTPResponse{
+		StatusCode:      r.status,
+		Headers:         headers,
+		Body:            output,
+		IsBase64Encoded: isBase64,
+		Cookies:         cookies,
+	}
+	retry, err := r.checkPayloadSize(resp, len(output))
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	if retry {
+		return r.GetAPIGatewayV2HTTPResponse()
+	}
+	return resp, nil
+}
+
+// v2Headers returns the headers of the response in the 2.0 payload format.
+// Each Set-Cookie header becomes an entry of the cookies, API Gateway and
+// Function URLs ignore the Set-Cookie headers of the 2.0 responses. Empty
+// cookies are dropped.
+func (r *ProxyResponseWriter) v2Headers() (map[string]string, []string) {
+	headers := make(map[string]string)
+	var cookies []string
+	for h, values := range r.headers {
+		if http.CanonicalHeaderKey(h) == "Set-Cookie" {
+			for _, cookie := range values {
+				if cookie != "" {
+					cookies = append(cookies, cookie)
+				}
+			}
+			continue
+		}
+		headers[h] = strings.Join(values, ",")
+	}
+	return headers, cookies
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// AuthorizerContextHeader is the custom header key used to store the
+// request context of an API Gateway REQUEST authorizer event. To access the
+// Context properties use the GetCustomAuthorizerContext method of the
+// RequestAccessor object.
+const AuthorizerContextHeader = "X-GoLambdaProxy-Authorizer-Context"
+
+// AuthorizerMethodArnHeader is the custom header key used to store the ARN
+// of the method being authorized, which is normally the resource of the
+// statements in the returned policy.
+const AuthorizerMethodArnHeader = "X-GoLambdaProxy-Authorizer-MethodArn"
+
+// ErrUnauthorized is returned when the handler of an authorizer event
+// responds with a 401 status code. API Gateway only answers the client with
+// a 401 Unauthorized response when the function fails with this exact error
+// message.
+var ErrUnauthorized = errors.New("Unauthorized")
+
+// GetCustomAuthorizerContext extracts the request context of an API Gateway
+// REQUEST authorizer event from a request's custom header.
+// Returns a populated events.APIGatewayCustomAuthorizerRequestTypeRequestContext
+// object from the request.
+func (r *RequestAccessor) GetCustomAuthorizerContext(req *http.Request) (events.APIGatewayCustomAuthorizerRequestTypeRequestContext, error) {
+	if req.Header.Get(r.headerName(AuthorizerContextHeader)) == "" {
+		return events.APIGatewayCustomAuthorizerRequestTypeRequestContext{}, errors.New("No authorizer context header in request")
+	}
+	context := events.APIGatewayCustomAuthorizerRequestTypeRequestContext{}
+	err := r.decodeJSONHeader(req, AuthorizerContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling authorizer context")
+		log.Println(err)
+		return events.APIGatewayCustomAuthorizerRequestTypeRequestContext{}, err
+	}
+	return context, nil
+}
+
+// CustomAuthorizerRequestToHTTPRequest converts an API Gateway REQUEST
+// authorizer event into an http.Request object, so that authorizers can be
+// implemented with the same middlewares as the main handler. The request
+// has the method, path, headers and query string of the request being
+// authorized, and an empty body.
+// Returns the populated request with additional custom headers for the
+// method ARN, the stage variables and the authorizer context. To access the
+// context use the GetCustomAuthorizerContext method of the RequestAccessor
+// object.
+func (r *RequestAccessor) CustomAuthorizerRequestToHTTPRequest(req events.APIGatewayCustomAuthorizerRequestTypeRequest) (*http.Request, error) {
+	httpRequest, err := http.NewRequest(
+		eventMethod(req.HTTPMethod),
+		r.requestURL(r.stageRelativePath(req.Path, req.RequestContext.Stage)),
+		nil,
+	)
+	if err != nil {
+		fmt.Printf("Could not convert authorizer request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
+		log.Println(err)
+		return nil, err
+	}
+	// the server contract requires a non-nil body, middlewares reading it
+	// get an empty body
+	httpRequest.Body = http.NoBody
+	httpRequest.URL.RawQuery = encodeQuery(queryValues(req.QueryStringParameters, req.MultiValueQueryStringParameters))
+
+	if len(req.MultiValueHeaders) > 0 {
+		for h, values := range req.MultiValueHeaders {
+			for _, value := range values {
+				httpRequest.Header.Add(h, value)
+			}
+		}
+	} else {
+		for h := range req.Headers {
+			httpRequest.Header.Add(h, req.Headers[h])
+		}
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, "")
+	r.setForwarded(httpRequest)
+	setRemoteAddr(httpRequest, req.RequestContext.Identity.SourceIP)
+
+	if err := r.addJSONHeader(httpRequest, AuthorizerContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Add(r.headerName(AuthorizerMethodArnHeader), req.MethodArn)
+	if err := r.addJSONHeader(httpRequest, APIGwStageVarsHeader, stageVariables(req.StageVariables)); err != nil {
+		return nil, err
+	}
+
+	httpRequest = r.withRequestID(httpRequest, req.RequestContext.RequestID)
+	httpRequest = withValue(httpRequest, resourceContextKey, req.Resource)
+	return r.withRequestValues(httpRequest, headerNames(req.Headers, req.MultiValueHeaders)), nil
+}
+
+// GetCustomAuthorizerResponse converts the data passed to the response
+// writer into an events.APIGatewayCustomAuthorizerResponse object. The
+// handler is expected to write the authorizer response, with the principal
+// and the policy document, as a JSON body.
+// Returns ErrUnauthorized if the handler responded with a 401 status code,
+// and an error if the status code was not set, is not a success code or the
+// body is not a valid authorizer response.
+func (r *ProxyResponseWriter) GetCustomAuthorizerResponse() (events.APIGatewayCustomAuthorizerResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.APIGatewayCustomAuthorizerResponse{}, errors.New("Status code not set on response")
+	}
+	if r.status == http.StatusUnauthorized {
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+	if r.status < 200 || r.status > 299 {
+		return events.APIGatewayCustomAuthorizerResponse{}, fmt.Errorf("Authorizer handler responded with status %d", r.status)
+	}
+
+	resp := events.APIGatewayCustomAuthorizerResponse{}
+	if err := json.Unmarshal(r.body.Bytes(), &resp); err != nil {
+		log.Println("Could not unmarshal authorizer response")
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultBinaryContentTypes are the media types whose bodies are always
+// base64 encoded in ALB responses. A trailing /* matches all the subtypes.
+var DefaultBinaryContentTypes = []string{
+	"application/octet-stream",
+	"application/pdf",
+	"application/zip",
+	"application/gzip",
+	"application/x-protobuf",
+	"audio/*",
+	"font/*",
+	"image/*",
+	"video/*",
+}
+
+// SetBinaryContentTypes instructs the ResponseOptions object to base64
+// encode the bodies of the responses with one of the given media types, like
+// the binaryMediaTypes setting of API Gateway. Without this option only the
+// bodies that are not valid UTF-8 are base64 encoded, except for ALB
+// responses which use the DefaultBinaryContentTypes. A trailing * matches
+// all the types with the same prefix, for example image/* or
+// application/vnd.*, and */* matches all the types. Text, JSON and XML
+// types, such as image/svg+xml, are never considered binary.
+func (o *ResponseOptions) SetBinaryContentTypes(types ...string) {
+	o.binaryContentTypes = types
+}
+
+// SetBinaryContentTypes sets the media types whose bodies the writer base64
+// encodes, see the SetBinaryContentTypes method of ResponseOptions.
+func (r *ProxyResponseWriter) SetBinaryContentTypes(types ...string) {
+	r.options.binaryContentTypes = types
+}
+
+// isBinary returns true if the response body must be flagged as binary for
+// load balancers, which do not inspect the body: when it has a content
+// coding or a binary media type.
+func (r *ProxyResponseWriter) isBinary() bool {
+	if encoding := r.headers.Get(contentEncodingHeaderKey); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return true
+	}
+
+	contentType := r.headers.Get(contentTypeHeaderKey)
+	if contentType == "" || isCompressible(contentType) {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	types := r.options.binaryContentTypes
+	if types == nil {
+		types = DefaultBinaryContentTypes
+	}
+	for _, binaryType := range types {
+		binaryType = strings.ToLower(strings.TrimSpace(binaryType))
+		if binaryType == mediaType || binaryType == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(binaryType, "*") && strings.HasPrefix(mediaType, strings.TrimSuffix(binaryType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPartialContent returns true for the 206 responses to range requests.
+func (r *ProxyResponseWriter) isPartialContent() bool {
+	return r.status == http.StatusPartialContent || r.headers.Get("Content-Range") != ""
+}
+
+// isBinaryRange returns true for the partial responses carrying bytes of a
+// binary representation, or several ranges in a multipart/byteranges body.
+// A range can be valid UTF-8 even when the whole representation is not, so
+// these bodies are always base64 encoded.
+func (r *ProxyResponseWriter) isBinaryRange() bool {
+	if !r.isPartialContent() {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.headers.Get(contentTypeHeaderKey))
+	return mediaType == "multipart/byteranges" || r.isBinary()
+}
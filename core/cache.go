@@ -0,0 +1,203 @@
+package core
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache is an in-memory LRU cache of the responses of the handlers.
+// A warm Lambda container keeps the cache between invocations, so that hot
+// requests are answered without running the handler. Only the successful
+// responses to GET and HEAD requests are cached, keyed by method, host, path
+// and query string, and never when the request or the response disables
+// caching with Cache-Control, when the request has an Authorization or a
+// Cookie header, for authorizer and WebSocket events, or when the response
+// sets cookies. The request headers named in the Vary header of the
+// response must match. A ResponseCache can be shared by concurrent
+// invocations.
+type ResponseCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type cachedResponse struct {
+	key     string
+	status  int
+	headers http.Header
+	body    []byte
+	vary    map[string]string
+	stored  time.Time
+	expires time.Time
+}
+
+// NewResponseCache creates a new ResponseCache holding up to capacity
+// responses, each for the given time to live.
+func NewResponseCache(capacity int, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Len returns the number of responses in the cache.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Purge removes all the responses from the cache.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
+
+// SetResponseCache instructs the RequestAccessor object to answer the
+// requests from the given cache, see the CacheResponses method. A nil cache
+// disables caching, which is the default.
+func (r *RequestAccessor) SetResponseCache(cache *ResponseCache) {
+	r.responseCache = cache
+}
+
+// CacheResponses wraps the given handler and answers the requests from the
+// cache set with SetResponseCache, adding an Age header to the cached
+// responses. On a miss the response written by the handler to a
+// ProxyResponseWriter is stored in the cache. The adapters apply it before
+// sending requests to the framework.
+func (r *RequestAccessor) CacheResponses(next http.Handler) http.Handler {
+	cache := r.responseCache
+	if cache == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !r.cacheable(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if cache.serve(w, req) {
+			return
+		}
+		next.ServeHTTP(w, req)
+		if pw, ok := w.(*ProxyResponseWriter); ok {
+			cache.store(req, pw)
+		}
+	})
+}
+
+// cacheKey returns the key of the response to the request. The host is part
+// of the key because the same function can serve several custom domains.
+func cacheKey(req *http.Request) string {
+	return req.Method + " " + strings.ToLower(req.Host) + req.URL.EscapedPath() + "?" + req.URL.RawQuery
+}
+
+// cacheable returns true if the response to the request can be served from
+// the cache. The credentialed requests, and the requests converted from
+// authorizer and WebSocket events, are never cached.
+func (r *RequestAccessor) cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	for _, h := range []string{"Authorization", "Cookie", r.headerName(AuthorizerMethodArnHeader), r.headerName(WebsocketConnectionIDHeader)} {
+		if req.Header.Get(h) != "" {
+			return false
+		}
+	}
+	cacheControl := strings.ToLower(req.Header.Get("Cache-Control"))
+	return !strings.Contains(cacheControl, "no-cache") && !strings.Contains(cacheControl, "no-store")
+}
+
+// serve writes the cached response matching the request to w.
+// Returns false if there is no such response.
+func (c *ResponseCache) serve(w http.ResponseWriter, req *http.Request) bool {
+	c.mu.Lock()
+	element, ok := c.entries[cacheKey(req)]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+	entry := element.Value.(*cachedResponse)
+	now := c.now()
+	if now.After(entry.expires) {
+		c.remove(element)
+		c.mu.Unlock()
+		return false
+	}
+	for h, value := range entry.vary {
+		if req.Header.Get(h) != value {
+			c.mu.Unlock()
+			return false
+		}
+	}
+	c.order.MoveToFront(element)
+	c.mu.Unlock()
+
+	for h, values := range entry.headers {
+		w.Header()[h] = append([]string(nil), values...)
+	}
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(entry.stored).Seconds())))
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+	return true
+}
+
+// store adds the response written to w to the cache, if it can be cached.
+func (c *ResponseCache) store(req *http.Request, w *ProxyResponseWriter) {
+	if w.Status() != http.StatusOK || w.Header().Get("Set-Cookie") != "" {
+		return
+	}
+	cacheControl := strings.ToLower(w.Header().Get("Cache-Control"))
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "private") {
+		return
+	}
+	vary := map[string]string{}
+	for _, header := range w.Header().Values("Vary") {
+		for _, name := range strings.Split(header, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				vary[http.CanonicalHeaderKey(name)] = req.Header.Get(name)
+			}
+		}
+	}
+
+	now := c.now()
+	entry := &cachedResponse{
+		key:     cacheKey(req),
+		status:  w.Status(),
+		headers: w.Header().Clone(),
+		body:    append([]byte(nil), w.Body()...),
+		vary:    vary,
+		stored:  now,
+		expires: now.Add(c.ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *ResponseCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cachedResponse).key)
+}
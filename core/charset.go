@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Charset returns the lowercase value of the charset parameter of the given
+// Content-Type header. Returns an empty string if the header is invalid or
+// does not declare a charset.
+func Charset(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params["charset"]))
+}
+
+// isUTF8Charset returns true if text in the given charset can be passed to
+// API Gateway as a string without being transcoded
+func isUTF8Charset(charset string) bool {
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// DecodeBody converts a body encoded with the charset declared in the given
+// Content-Type header to a UTF-8 string. Bodies without a charset are
+// assumed to already be UTF-8. Returns an error if the charset is not
+// supported or the body cannot be decoded.
+func DecodeBody(contentType string, body []byte) (string, error) {
+	charset := Charset(contentType)
+	if isUTF8Charset(charset) {
+		return string(body), nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return "", fmt.Errorf("Unsupported charset %s: %v", charset, err)
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("Could not decode %s body: %v", charset, err)
+	}
+	return string(decoded), nil
+}
+
+// EncodeBody converts a UTF-8 string to the charset declared in the given
+// Content-Type header. It is the inverse of DecodeBody and can be used by
+// handlers to produce a body matching the Content-Type they set.
+func EncodeBody(contentType string, body string) ([]byte, error) {
+	charset := Charset(contentType)
+	if isUTF8Charset(charset) {
+		return []byte(body), nil
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("Unsupported charset %s: %v", charset, err)
+	}
+	encoded, err := encoding.NewEncoder().Bytes([]byte(body))
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode body as %s: %v", charset, err)
+	}
+	return encoded, nil
+}
+
+// ReadBodyString reads the body of the request and decodes it to a UTF-8
+// string using the charset of the request Content-Type. The body of the
+// request is replaced so that it can still be read by the handler.
+func ReadBodyString(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return DecodeBody(req.Header.Get(contentTypeHeaderKey), body)
+}
+
+// TranscodeRequestBodies instructs the RequestAccessor object to convert the
+// bodies of the requests declared in a charset other than UTF-8, such as
+// ISO-8859-1 or Shift_JIS, to UTF-8 and to update the charset of their
+// Content-Type header accordingly. By default the bodies are passed to the
+// handler as they were received: the bytes of base64 encoded bodies are not
+// changed, and text bodies are the UTF-8 string of the event. Multipart
+// bodies are never transcoded, the files they carry keep their raw bytes.
+func (r *RequestAccessor) TranscodeRequestBodies(transcode bool) {
+	r.transcodeBodies = transcode
+}
+
+// transcodes returns true if the bodies with the given Content-Type are
+// converted to UTF-8 by the TranscodeRequestBodies option.
+func (r *RequestAccessor) transcodes(contentType string) bool {
+	if !r.transcodeBodies || isUTF8Charset(Charset(contentType)) {
+		return false
+	}
+	return !strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "multipart/")
+}
+
+// transcodeBody converts the body of the request to UTF-8 if the option is
+// enabled and the Content-Type declares another charset. Text bodies of the
+// events are already UTF-8 strings, only base64 encoded bodies are decoded.
+// Returns the new body of the request.
+func (r *RequestAccessor) transcodeBody(httpRequest *http.Request, body []byte, isBase64Encoded bool) ([]byte, error) {
+	contentType := httpRequest.Header.Get(contentTypeHeaderKey)
+	if len(body) == 0 || !r.transcodes(contentType) {
+		return body, nil
+	}
+
+	if isBase64Encoded {
+		decoded, err := DecodeBody(contentType, body)
+		if err != nil {
+			return nil, err
+		}
+		body = []byte(decoded)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	params["charset"] = "utf-8"
+	httpRequest.Header.Set(contentTypeHeaderKey, mime.FormatMediaType(mediaType, params))
+	httpRequest.Header.Del("Content-Length")
+
+	httpRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	httpRequest.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
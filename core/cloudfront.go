@@ -0,0 +1,279 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CloudFrontConfigHeader is the custom header key used to store the
+// configuration of the CloudFront distribution that triggered a Lambda@Edge
+// function. To access it use the GetCloudFrontConfig method of the
+// RequestAccessor object.
+const CloudFrontConfigHeader = "X-GoLambdaProxy-CloudFront-Config"
+
+// CloudFrontEvent is the event received by Lambda@Edge functions associated
+// with the viewer request, origin request or origin response events of a
+// CloudFront distribution.
+type CloudFrontEvent struct {
+	Records []CloudFrontEventRecord `json:"Records"`
+}
+
+// CloudFrontEventRecord is a single record of a CloudFrontEvent.
+type CloudFrontEventRecord struct {
+	CF CloudFrontRecord `json:"cf"`
+}
+
+// CloudFrontRecord contains the distribution configuration and the request
+// of a CloudFrontEventRecord. Origin response events also contain the
+// response received from the origin.
+type CloudFrontRecord struct {
+	Config   CloudFrontConfig    `json:"config"`
+	Request  CloudFrontRequest   `json:"request"`
+	Response *CloudFrontResponse `json:"response,omitempty"`
+}
+
+// CloudFrontConfig identifies the distribution and the event that triggered
+// the function.
+type CloudFrontConfig struct {
+	DistributionDomainName string `json:"distributionDomainName"`
+	DistributionID         string `json:"distributionId"`
+	EventType              string `json:"eventType"`
+	RequestID              string `json:"requestId"`
+}
+
+// CloudFrontHeaders maps lowercase header names to their values.
+type CloudFrontHeaders map[string][]CloudFrontHeader
+
+// CloudFrontHeader is a single header value along with the original case
+// of the header name.
+type CloudFrontHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CloudFrontRequest is the viewer or origin request received by CloudFront.
+type CloudFrontRequest struct {
+	ClientIP    string                 `json:"clientIp"`
+	Headers     CloudFrontHeaders      `json:"headers"`
+	Method      string                 `json:"method"`
+	QueryString string                 `json:"querystring"`
+	URI         string                 `json:"uri"`
+	Body        *CloudFrontRequestBody `json:"body,omitempty"`
+}
+
+// CloudFrontRequestBody is included in the request when the body is exposed
+// to the function in the configuration of the distribution.
+type CloudFrontRequestBody struct {
+	InputTruncated bool   `json:"inputTruncated"`
+	Action         string `json:"action"`
+	Encoding       string `json:"encoding"`
+	Data           string `json:"data"`
+}
+
+// CloudFrontResponse is the response generated by a Lambda@Edge function
+// and returned by CloudFront to the viewer. It is also the response of the
+// origin in origin response events, in which case it has no body.
+type CloudFrontResponse struct {
+	Status            string            `json:"status"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           CloudFrontHeaders `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	BodyEncoding      string            `json:"bodyEncoding,omitempty"`
+}
+
+// GetCloudFrontConfig extracts the CloudFront distribution configuration
+// from a request's custom header.
+// Returns a populated CloudFrontConfig object from the request.
+func (r *RequestAccessor) GetCloudFrontConfig(req *http.Request) (CloudFrontConfig, error) {
+	if req.Header.Get(r.headerName(CloudFrontConfigHeader)) == "" {
+		return CloudFrontConfig{}, errors.New("No CloudFront config header in request")
+	}
+	config := CloudFrontConfig{}
+	err := r.decodeJSONHeader(req, CloudFrontConfigHeader, &config)
+	if err != nil {
+		log.Println("Erorr while unmarshalling CloudFront config")
+		log.Println(err)
+		return CloudFrontConfig{}, err
+	}
+	return config, nil
+}
+
+// CloudFrontEventToHTTPRequest converts the request of a Lambda@Edge event
+// into an http.Request object. CloudFront sends a single record per event.
+// The address of the viewer is set as the RemoteAddr of the request.
+// Returns the populated request with an additional custom header for the
+// distribution configuration. To access it use the GetCloudFrontConfig
+// method of the RequestAccessor object.
+func (r *RequestAccessor) CloudFrontEventToHTTPRequest(event CloudFrontEvent) (*http.Request, error) {
+	if len(event.Records) == 0 {
+		return nil, errors.New("No records in CloudFront event")
+	}
+	record := event.Records[0].CF
+	req := record.Request
+
+	path := r.requestURL(req.URI)
+	if req.QueryString != "" {
+		path += "?" + req.QueryString
+	}
+
+	httpRequest, err := http.NewRequest(
+		eventMethod(req.Method),
+		path,
+		nil,
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert CloudFront request %s:%s to http.Request\n", req.Method, req.URI)
+		log.Println(err)
+		return nil, err
+	}
+
+	names := []string{}
+	for name, values := range req.Headers {
+		for _, header := range values {
+			key := header.Key
+			if key == "" {
+				key = name
+			}
+			httpRequest.Header.Add(key, header.Value)
+			names = append(names, key)
+		}
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, record.Config.DistributionDomainName)
+	r.setForwarded(httpRequest)
+	r.overrideMethod(httpRequest)
+	body, isBase64Encoded := "", false
+	if req.Body != nil {
+		body, isBase64Encoded = req.Body.Data, req.Body.Encoding == "base64"
+	}
+	if err := r.setBody(httpRequest, body, isBase64Encoded); err != nil {
+		return nil, err
+	}
+	if req.ClientIP != "" {
+		httpRequest.RemoteAddr = remoteAddr(req.ClientIP)
+	}
+
+	if err := r.addJSONHeader(httpRequest, CloudFrontConfigHeader, record.Config); err != nil {
+		return nil, err
+	}
+
+	httpRequest = r.withRequestID(httpRequest, record.Config.RequestID)
+	return r.withRequestValues(httpRequest, names), nil
+}
+
+// GetCloudFrontResponse converts the data passed to the response writer
+// into a CloudFrontResponse object generated by a Lambda@Edge function.
+// Responses larger than MaxCloudFrontPayloadBytes, or the limit given to
+// SetResponseOverflowHandler, are passed to the overflow handler.
+// Returns an error if the status code was not set on the response.
+func (r *ProxyResponseWriter) GetCloudFrontResponse() (CloudFrontResponse, error) {
+	if r.status == defaultStatusCode {
+		return CloudFrontResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(false)
+	if err != nil {
+		return CloudFrontResponse{}, err
+	}
+	bodyEncoding := "text"
+	if isBase64 {
+		bodyEncoding = "base64"
+	}
+
+	resp := CloudFrontResponse{
+		Status:            strconv.Itoa(r.status),
+		StatusDescription: http.StatusText(r.status),
+		Headers:           r.cloudFrontHeaders(),
+		Body:              output,
+		BodyEncoding:      bodyEncoding,
+	}
+	retry, err := r.checkPayloadLimit(resp, len(output), MaxCloudFrontPayloadBytes)
+	if err != nil {
+		return CloudFrontResponse{}, err
+	}
+	if retry {
+		return r.GetCloudFrontResponse()
+	}
+	return resp, nil
+}
+
+// NewCloudFrontOriginResponseWriter returns a new ProxyResponseWriter object
+// initialized with the status and the headers of the origin response in the
+// given Lambda@Edge origin response event. Handlers can change the status
+// and the headers of the writer, and replace the body of the origin
+// response by writing a new one. Since most frameworks set a 200 status
+// code on the first write, handlers replacing the body should also call
+// WriteHeader.
+// Returns an error if the event does not contain an origin response.
+func (o *ResponseOptions) NewCloudFrontOriginResponseWriter(req *http.Request, event CloudFrontEvent) (*ProxyResponseWriter, error) {
+	if len(event.Records) == 0 || event.Records[0].CF.Response == nil {
+		return nil, errors.New("No origin response in CloudFront event")
+	}
+	origin := event.Records[0].CF.Response
+
+	status, err := strconv.Atoi(origin.Status)
+	if err != nil {
+		log.Printf("Invalid origin response status %s\n", origin.Status)
+		return nil, err
+	}
+
+	w := o.NewProxyResponseWriter(req)
+	w.status = status
+	for name, values := range origin.Headers {
+		for _, header := range values {
+			key := header.Key
+			if key == "" {
+				key = name
+			}
+			w.headers.Add(key, header.Value)
+		}
+	}
+	return w, nil
+}
+
+// GetCloudFrontOriginResponse converts the data passed to a response writer
+// created by NewCloudFrontOriginResponseWriter into the CloudFrontResponse
+// object returned by an origin response function. The body is only
+// included when the handler wrote one; otherwise CloudFront keeps the body
+// of the origin.
+func (r *ProxyResponseWriter) GetCloudFrontOriginResponse() (CloudFrontResponse, error) {
+	if r.body.Len() > 0 {
+		r.headers.Del("Content-Length")
+		return r.GetCloudFrontResponse()
+	}
+	if r.status == defaultStatusCode {
+		return CloudFrontResponse{}, errors.New("Status code not set on response")
+	}
+	foldTrailers(r.headers)
+	if r.options.stripHopByHop {
+		removeHopByHopHeaders(r.headers)
+	}
+
+	return CloudFrontResponse{
+		Status:            strconv.Itoa(r.status),
+		StatusDescription: http.StatusText(r.status),
+		Headers:           r.cloudFrontHeaders(),
+	}, nil
+}
+
+// cloudFrontHeaders returns the headers of the response keyed by their
+// lowercase names, as expected by CloudFront.
+func (r *ProxyResponseWriter) cloudFrontHeaders() CloudFrontHeaders {
+	headers := make(CloudFrontHeaders)
+	for h, values := range r.headers {
+		name := strings.ToLower(h)
+		for _, value := range values {
+			headers[name] = append(headers[name], CloudFrontHeader{Key: h, Value: value})
+		}
+	}
+	return headers
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// ColdStartHeader is the custom header set to "true" on the first request
+// converted since the function was initialized, and to "false" on the
+// following ones, when the AddColdStartHeader option is enabled. The value is
+// always stored in the request context, see ColdStartFromContext.
+const ColdStartHeader = "X-GoLambdaProxy-Cold-Start"
+
+// converted is set once the first request of the execution environment has
+// been converted
+var converted int32
+
+// AddColdStartHeader instructs the RequestAccessor object to add the
+// ColdStartHeader to the converted requests, for middlewares that can only
+// read headers.
+func (r *RequestAccessor) AddColdStartHeader(add bool) {
+	r.coldStartHeader = add
+}
+
+// ColdStartFromContext returns true if the request was the first one
+// converted since the function was initialized, so that handlers and metrics
+// middlewares can tag cold starts. The second value is false if the context
+// does not belong to a converted request.
+func ColdStartFromContext(ctx context.Context) (bool, bool) {
+	coldStart, ok := ctx.Value(coldStartContextKey).(bool)
+	return coldStart, ok
+}
+
+// withColdStart stores whether the request is the first one converted in
+// this execution environment in the context of the request.
+func (r *RequestAccessor) withColdStart(req *http.Request) *http.Request {
+	coldStart := atomic.CompareAndSwapInt32(&converted, 0, 1)
+	if r.coldStartHeader {
+		req.Header.Set(r.headerName(ColdStartHeader), strconv.FormatBool(coldStart))
+	}
+	return req.WithContext(context.WithValue(req.Context(), coldStartContextKey, coldStart))
+}
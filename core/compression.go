@@ -0,0 +1,273 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ZstdEncoding is the content coding name of Zstandard compression
+const ZstdEncoding = "zstd"
+
+// GzipEncoding is the content coding name of gzip compression
+const GzipEncoding = "gzip"
+
+// DefaultCompressionMinSize is the minimum size, in bytes, of the bodies
+// compressed when EnableCompression is called with a size of 0
+const DefaultCompressionMinSize = 1024
+
+// DefaultMaxDecompressedBodyBytes is the size, in bytes, past which the
+// DecompressRequestBodies option rejects a request body when no limit is
+// set with SetMaxRequestBodyBytes: ten times the payload limit of Lambda,
+// so that a small compressed body cannot exhaust the memory of the function
+const DefaultMaxDecompressedBodyBytes = 10 * MaxResponsePayloadBytes
+
+const contentEncodingHeaderKey = "Content-Encoding"
+const acceptEncodingHeaderKey = "Accept-Encoding"
+
+// compressors maps the supported content codings to their implementation
+var compressors = map[string]func([]byte) ([]byte, error){
+	ZstdEncoding: compressZstd,
+	GzipEncoding: compressGzip,
+}
+
+// decompressors maps the content codings of the request bodies decompressed
+// by the DecompressRequestBodies option to their implementation
+var decompressors = map[string]func(io.Reader) (io.ReadCloser, error){
+	GzipEncoding: gunzip,
+	"x-gzip":     gunzip,
+	"deflate":    zlib.NewReader,
+	ZstdEncoding: unzstd,
+}
+
+// DefaultCompressionEncodings lists the content codings used by
+// EnableCompression when no encoding is given, in order of preference
+var DefaultCompressionEncodings = []string{ZstdEncoding, GzipEncoding}
+
+// compressibleContentTypes lists the media types, in addition to text/*
+// and the +json/+xml suffixes, whose bodies are compressed
+var compressibleContentTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/xhtml+xml":  true,
+	"image/svg+xml":          true,
+}
+
+type compressionOptions struct {
+	minSize   int
+	encodings []string
+}
+
+// EnableCompression instructs the ResponseOptions object to compress the
+// bodies of at least minSize bytes with the best content coding accepted by
+// the client in the Accept-Encoding header of the request. When no encoding
+// is given the DefaultCompressionEncodings are used. Only text, JSON, XML
+// and JavaScript bodies are compressed, and never when the handler already
+// set a Content-Encoding. Compressed bodies are always base64 encoded, the
+// API must therefore be configured to treat the responses as binary.
+func (o *ResponseOptions) EnableCompression(minSize int, encodings ...string) {
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+	if len(encodings) == 0 {
+		encodings = DefaultCompressionEncodings
+	}
+	o.compression = &compressionOptions{
+		minSize:   minSize,
+		encodings: encodings,
+	}
+}
+
+// DisableCompression turns off response compression.
+func (o *ResponseOptions) DisableCompression() {
+	o.compression = nil
+}
+
+// compress applies the negotiated content coding to the body. It returns the
+// body unchanged when compression is disabled or not applicable.
+func (r *ProxyResponseWriter) compress(body []byte) ([]byte, bool, error) {
+	c := r.options.compression
+	if c == nil || r.request == nil || len(body) < c.minSize {
+		return body, false, nil
+	}
+	if r.headers.Get(contentEncodingHeaderKey) != "" || !isCompressible(r.headers.Get(contentTypeHeaderKey)) {
+		return body, false, nil
+	}
+	// the Content-Range of partial responses refers to the uncompressed
+	// representation
+	if r.isPartialContent() {
+		return body, false, nil
+	}
+
+	encoding := negotiateEncoding(r.request.Header.Get(acceptEncodingHeaderKey), c.encodings)
+	if encoding == "" {
+		return body, false, nil
+	}
+	compressed, err := compressors[encoding](body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.headers.Set(contentEncodingHeaderKey, encoding)
+	r.headers.Add("Vary", acceptEncodingHeaderKey)
+	r.headers.Del("Content-Length")
+	return compressed, true, nil
+}
+
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		compressibleContentTypes[mediaType]
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality
+// value in the Accept-Encoding header. Ties are broken by the order of the
+// supported encodings.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range supported {
+		if _, ok := compressors[encoding]; !ok {
+			continue
+		}
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+var zstdEncoder struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	err     error
+}
+
+func compressZstd(body []byte) ([]byte, error) {
+	zstdEncoder.once.Do(func() {
+		zstdEncoder.encoder, zstdEncoder.err = zstd.NewWriter(nil)
+	})
+	if zstdEncoder.err != nil {
+		return nil, zstdEncoder.err
+	}
+	return zstdEncoder.encoder.EncodeAll(body, nil), nil
+}
+
+func compressGzip(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecompressRequestBodies instructs the RequestAccessor object to decompress
+// the request bodies sent with a gzip, deflate or zstd Content-Encoding,
+// which most frameworks do not decode. The Content-Encoding header is
+// removed and the length of the request is the decompressed one. When a
+// limit is set with SetMaxRequestBodyBytes, the decompression stops past the
+// limit and the request is rejected. Without a limit, the conversion of the
+// request fails when the decompressed body is larger than
+// DefaultMaxDecompressedBodyBytes. Compressed bodies are always decoded in
+// memory, even with the StreamRequestBodies option.
+func (r *RequestAccessor) DecompressRequestBodies(decompress bool) {
+	r.decompressBodies = decompress
+}
+
+// decompresses returns true if the body of the request is decompressed by
+// the DecompressRequestBodies option.
+func (r *RequestAccessor) decompresses(req *http.Request) bool {
+	if !r.decompressBodies {
+		return false
+	}
+	_, ok := decompressors[strings.ToLower(strings.TrimSpace(req.Header.Get(contentEncodingHeaderKey)))]
+	return ok
+}
+
+// decompressBody decompresses the body of the request if the option is
+// enabled and the Content-Encoding is supported.
+// Returns the new body of the request.
+func (r *RequestAccessor) decompressBody(httpRequest *http.Request, body []byte) ([]byte, error) {
+	if len(body) == 0 || !r.decompresses(httpRequest) {
+		return body, nil
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(httpRequest.Header.Get(contentEncodingHeaderKey)))
+	reader, err := decompressors[encoding](bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decompress %s request body: %v", encoding, err)
+	}
+	defer reader.Close()
+
+	// a limit of max + 1 bytes is enough for EnforceMaxRequestBodyBytes to
+	// reject the request without decompressing all of it
+	max := r.maxBodyBytes
+	if max <= 0 {
+		max = DefaultMaxDecompressedBodyBytes
+	}
+	decompressed, err := ioutil.ReadAll(io.LimitReader(reader, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decompress %s request body: %v", encoding, err)
+	}
+	if r.maxBodyBytes <= 0 && int64(len(decompressed)) > max {
+		return nil, fmt.Errorf("Decompressed %s request body exceeds %d bytes", encoding, max)
+	}
+
+	httpRequest.Header.Del(contentEncodingHeaderKey)
+	httpRequest.Header.Del("Content-Length")
+	return decompressed, nil
+}
+
+func gunzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func unzstd(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
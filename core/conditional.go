@@ -0,0 +1,61 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// HandleConditionalRequests instructs the ResponseOptions object to answer
+// the conditional GET and HEAD requests whose If-None-Match header matches
+// the ETag of the response with a 304 Not Modified response without a body.
+// The successful responses without an ETag get a strong ETag computed over
+// the body, like with the ETagHeader integrity header. This saves bandwidth
+// and keeps the cacheable responses under the payload limits of Lambda.
+func (o *ResponseOptions) HandleConditionalRequests(enable bool) {
+	o.conditionalRequests = enable
+}
+
+// notModified adds the ETag to the response and turns it into a 304 Not
+// Modified response when the request is a conditional request matching the
+// ETag. The body is the encoded body of the response.
+// Returns true if the response is not modified.
+func (r *ProxyResponseWriter) notModified(body []byte) bool {
+	if !r.options.conditionalRequests || r.request == nil || r.status != http.StatusOK {
+		return false
+	}
+	if r.request.Method != http.MethodGet && r.request.Method != http.MethodHead {
+		return false
+	}
+
+	etag := r.headers.Get(etagHeaderKey)
+	if etag == "" {
+		digest := sha256.Sum256(body)
+		etag = "\"" + hex.EncodeToString(digest[:]) + "\""
+		r.headers.Set(etagHeaderKey, etag)
+	}
+	if !etagMatches(r.request.Header.Values("If-None-Match"), etag) {
+		return false
+	}
+
+	r.status = http.StatusNotModified
+	r.headers.Del("Content-Length")
+	r.headers.Del(contentMD5HeaderKey)
+	return true
+}
+
+// etagMatches returns true if one of the entity tags of the If-None-Match
+// headers matches the given ETag, using the weak comparison of RFC 7232.
+func etagMatches(ifNoneMatch []string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, header := range ifNoneMatch {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package core
+
+import "net/http"
+
+// ContentTypeDefault selects the Content-Type the ProxyResponseWriter sets
+// when the handler writes a body without setting one. API Gateway decides
+// whether to decode the base64 encoded bodies based on this header.
+type ContentTypeDefault int
+
+const (
+	// DetectContentType sets the Content-Type to the type detected by
+	// http.DetectContentType from the first bytes written, which is
+	// application/octet-stream when no type can be detected. This is the
+	// default.
+	DetectContentType ContentTypeDefault = iota
+	// FixedContentType sets the Content-Type to the type passed to
+	// SetContentTypeDefault
+	FixedContentType
+	// NoContentType leaves the Content-Type unset
+	NoContentType
+)
+
+// SetContentTypeDefault instructs the ResponseOptions object to apply the
+// given default to the responses written without a Content-Type by the
+// writers it creates. The contentType is only used with FixedContentType.
+func (o *ResponseOptions) SetContentTypeDefault(d ContentTypeDefault, contentType string) {
+	o.contentTypeDefault = d
+	o.defaultContentType = contentType
+}
+
+// setDefaultContentType sets the Content-Type of the response, if the
+// handler did not set one, when writing the given bytes of the body.
+func (r *ProxyResponseWriter) setDefaultContentType(body []byte) {
+	applyContentTypeDefault(r.Header(), r.options.contentTypeDefault, r.options.defaultContentType, body)
+}
+
+// applyContentTypeDefault sets the Content-Type header selected by the
+// default d for the given body, unless the header is already set.
+func applyContentTypeDefault(header http.Header, d ContentTypeDefault, contentType string, body []byte) {
+	if header.Get(contentTypeHeaderKey) != "" {
+		return
+	}
+	switch d {
+	case DetectContentType:
+		header.Add(contentTypeHeaderKey, http.DetectContentType(body))
+	case FixedContentType:
+		if contentType != "" {
+			header.Add(contentTypeHeaderKey, contentType)
+		}
+	}
+}
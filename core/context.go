@@ -0,0 +1,284 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// contextKey is the type of the keys used to store values in the context
+// of the converted http.Request objects. Using an unexported type prevents
+// collisions with keys defined in other packages.
+type contextKey int
+
+const (
+	rawEventContextKey contextKey = iota
+	eventTypeContextKey
+	pathParamsContextKey
+	headerNamesContextKey
+	decodedHeadersContextKey
+	apiGwContextContextKey
+	apiGwV2ContextContextKey
+	stageVarsContextKey
+	coldStartContextKey
+	requestIDContextKey
+	resourceContextKey
+	protocolContextKey
+	functionURLContextContextKey
+)
+
+// contextKeys lists all the keys of the values stored in the request
+// context during the conversion of the events.
+var contextKeys = []contextKey{
+	rawEventContextKey,
+	eventTypeContextKey,
+	pathParamsContextKey,
+	headerNamesContextKey,
+	decodedHeadersContextKey,
+	apiGwContextContextKey,
+	apiGwV2ContextContextKey,
+	stageVarsContextKey,
+	coldStartContextKey,
+	requestIDContextKey,
+	resourceContextKey,
+	protocolContextKey,
+	functionURLContextContextKey,
+}
+
+// withEventValues returns a copy of ctx with the values stored by the
+// conversion of the events in the from context.
+func withEventValues(ctx context.Context, from context.Context) context.Context {
+	for _, key := range contextKeys {
+		if value := from.Value(key); value != nil {
+			ctx = context.WithValue(ctx, key, value)
+		}
+	}
+	return ctx
+}
+
+// SetDeadlineMargin instructs the RequestAccessor object to cancel the
+// context of the requests converted with a context, such as the one received
+// by the Lambda handler, the given duration before the deadline of that
+// context. This leaves the handlers time to return a response after the
+// database calls and HTTP clients using the request context are cancelled,
+// instead of being frozen at the deadline. Contexts without a deadline are
+// not changed. A zero margin keeps the deadline of the context, which is the
+// default.
+func (r *RequestAccessor) SetDeadlineMargin(margin time.Duration) {
+	r.deadlineMargin = margin
+}
+
+// withDeadline returns a copy of ctx that expires deadlineMargin before the
+// deadline of ctx, and the function releasing its resources. The function
+// does nothing when ctx is returned as is.
+func (r *RequestAccessor) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || r.deadlineMargin <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline.Add(-r.deadlineMargin))
+}
+
+// RawEventFromContext returns the original JSON bytes of the event stored
+// in the given context by the RawEventToHTTPRequest method.
+func RawEventFromContext(ctx context.Context) ([]byte, bool) {
+	rawEvent, ok := ctx.Value(rawEventContextKey).([]byte)
+	return rawEvent, ok
+}
+
+// GetRawEvent extracts the original JSON bytes of the event from the
+// context of a request generated by the RawEventToHTTPRequest method.
+// The bytes are exactly those received from Lambda, which makes them
+// suitable for audit and compliance logs.
+func (r *RequestAccessor) GetRawEvent(req *http.Request) ([]byte, error) {
+	rawEvent, ok := RawEventFromContext(req.Context())
+	if !ok {
+		return nil, errors.New("No raw event in request context")
+	}
+	return rawEvent, nil
+}
+
+// PathParamsFromContext returns the API Gateway path parameters stored in
+// the given context by the ProxyEventToHTTPRequest and
+// APIGatewayV2HTTPRequestToHTTPRequest methods.
+func PathParamsFromContext(ctx context.Context) (map[string]string, bool) {
+	pathParams, ok := ctx.Value(pathParamsContextKey).(map[string]string)
+	return pathParams, ok
+}
+
+// GetAPIGatewayPathParams extracts the path parameters of the API Gateway
+// resource, such as the id of /orders/{id}, from the context of the
+// request.
+// Returns a map[string]string of the parameters and their values from the
+// request.
+func (r *RequestAccessor) GetAPIGatewayPathParams(req *http.Request) (map[string]string, error) {
+	pathParams, ok := PathParamsFromContext(req.Context())
+	if !ok {
+		return map[string]string{}, errors.New("No path parameters in request context")
+	}
+	return pathParams, nil
+}
+
+func withPathParams(req *http.Request, pathParams map[string]string) *http.Request {
+	if pathParams == nil {
+		pathParams = map[string]string{}
+	}
+	return req.WithContext(context.WithValue(req.Context(), pathParamsContextKey, pathParams))
+}
+
+// ResourceFromContext returns the API Gateway resource template matched by
+// the request, such as /users/{id}, stored in the given context by the
+// ProxyEventToHTTPRequest, APIGatewayV2HTTPRequestToHTTPRequest and
+// CustomAuthorizerRequestToHTTPRequest methods.
+func ResourceFromContext(ctx context.Context) (string, bool) {
+	resource, ok := ctx.Value(resourceContextKey).(string)
+	return resource, ok
+}
+
+// GetAPIGatewayResource extracts the resource template matched by the
+// request from its context. Unlike the path, the template has a low
+// cardinality, which makes it a good dimension for metrics and traces. For
+// HTTP APIs the template is the path of the route key, or $default.
+func (r *RequestAccessor) GetAPIGatewayResource(req *http.Request) (string, error) {
+	resource, ok := ResourceFromContext(req.Context())
+	if !ok {
+		return "", errors.New("No resource in request context")
+	}
+	return resource, nil
+}
+
+// routeKeyResource returns the resource template of an HTTP API route key:
+// the path of "GET /users/{id}", or the key itself for $default.
+func routeKeyResource(routeKey string) string {
+	if i := strings.Index(routeKey, " "); i >= 0 {
+		return routeKey[i+1:]
+	}
+	return routeKey
+}
+
+// HeaderNamesFromContext returns the original names of the request headers
+// stored in the given context when the PreserveHeaderCase option of the
+// RequestAccessor object is enabled. The map is keyed by the canonical
+// names of the headers.
+func HeaderNamesFromContext(ctx context.Context) (map[string]string, bool) {
+	headerNames, ok := ctx.Value(headerNamesContextKey).(map[string]string)
+	return headerNames, ok
+}
+
+// GetOriginalHeaderNames extracts the original names of the request headers,
+// as received in the event, from the context of a request converted with the
+// PreserveHeaderCase option enabled.
+// Returns a map[string]string of the original names keyed by the canonical
+// names of the headers.
+func (r *RequestAccessor) GetOriginalHeaderNames(req *http.Request) (map[string]string, error) {
+	headerNames, ok := HeaderNamesFromContext(req.Context())
+	if !ok {
+		return map[string]string{}, errors.New("No header names in request context")
+	}
+	return headerNames, nil
+}
+
+// GetAPIGatewayContextFromContext returns the API Gateway context stored in
+// the given context by the ProxyEventToHTTPRequest method. Unlike the custom
+// header, the value is not lost when a middleware removes the headers of the
+// request.
+func GetAPIGatewayContextFromContext(ctx context.Context) (events.APIGatewayProxyRequestContext, bool) {
+	apiGwContext, ok := ctx.Value(apiGwContextContextKey).(events.APIGatewayProxyRequestContext)
+	return apiGwContext, ok
+}
+
+// GetAPIGatewayV2ContextFromContext returns the API Gateway HTTP API request
+// context stored in the given context by the
+// APIGatewayV2HTTPRequestToHTTPRequest method.
+func GetAPIGatewayV2ContextFromContext(ctx context.Context) (events.APIGatewayV2HTTPRequestContext, bool) {
+	v2Context, ok := ctx.Value(apiGwV2ContextContextKey).(events.APIGatewayV2HTTPRequestContext)
+	return v2Context, ok
+}
+
+// GetFunctionURLContextFromContext returns the Lambda Function URL request
+// context stored in the given context by the
+// LambdaFunctionURLRequestToHTTPRequest method.
+func GetFunctionURLContextFromContext(ctx context.Context) (events.LambdaFunctionURLRequestContext, bool) {
+	functionURLContext, ok := ctx.Value(functionURLContextContextKey).(events.LambdaFunctionURLRequestContext)
+	return functionURLContext, ok
+}
+
+// GetAPIGatewayStageVarsFromContext returns the API Gateway stage variables
+// stored in the given context by the ProxyEventToHTTPRequest and
+// APIGatewayV2HTTPRequestToHTTPRequest methods.
+func GetAPIGatewayStageVarsFromContext(ctx context.Context) (map[string]string, bool) {
+	stageVars, ok := ctx.Value(stageVarsContextKey).(map[string]string)
+	return stageVars, ok
+}
+
+func withValue(req *http.Request, key contextKey, value interface{}) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), key, value))
+}
+
+func withRawEvent(req *http.Request, rawEvent []byte) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), rawEventContextKey, rawEvent))
+}
+
+// withRequestValues stores the values shared by all the converted requests in
+// their context: the original header names, the cache of the decoded custom
+// headers and the cold start indicator.
+func (r *RequestAccessor) withRequestValues(req *http.Request, names []string) *http.Request {
+	req = r.withHeaderNames(req, names)
+	req = withDecodedHeaders(req)
+	return r.withColdStart(req)
+}
+
+// decodedHeaders caches the values decoded from the JSON custom headers of a
+// request, so that the accessors unmarshal each header only once per request.
+type decodedHeaders struct {
+	mu     sync.Mutex
+	values map[string]decodedHeader
+}
+
+// decodedHeader is a decoded value along with the raw header it was decoded
+// from. A handler that changes the header invalidates the cached value.
+type decodedHeader struct {
+	raw   string
+	value interface{}
+}
+
+// withDecodedHeaders adds an empty cache of the decoded custom headers to the
+// context of the request. The cache is filled by the accessors on first use.
+func withDecodedHeaders(req *http.Request) *http.Request {
+	cache := &decodedHeaders{values: map[string]decodedHeader{}}
+	return req.WithContext(context.WithValue(req.Context(), decodedHeadersContextKey, cache))
+}
+
+// decodeJSONHeader unmarshals the JSON value of the custom header name into
+// v, which must be a pointer. For requests converted by the RequestAccessor
+// object the decoded value is cached in the request context, and later calls
+// copy it into v instead of unmarshalling the header again. Maps and slices
+// in the copied value are shared with the cache.
+func (r *RequestAccessor) decodeJSONHeader(req *http.Request, name string, v interface{}) error {
+	raw := req.Header.Get(r.headerName(name))
+	target := reflect.ValueOf(v).Elem()
+
+	cache, ok := req.Context().Value(decodedHeadersContextKey).(*decodedHeaders)
+	if !ok {
+		return json.Unmarshal([]byte(raw), v)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if decoded, ok := cache.values[name]; ok && decoded.raw == raw && reflect.TypeOf(decoded.value) == target.Type() {
+		target.Set(reflect.ValueOf(decoded.value))
+		return nil
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return err
+	}
+	cache.values[name] = decodedHeader{raw: raw, value: target.Interface()}
+	return nil
+}
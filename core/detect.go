@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// EventType identifies the shape of the event received by the function.
+type EventType int
+
+const (
+	// UnknownEvent is returned for payloads that are not supported
+	UnknownEvent EventType = iota
+	// APIGatewayProxyEvent is an API Gateway REST API proxy event, or an
+	// HTTP API event using the 1.0 payload format
+	APIGatewayProxyEvent
+	// APIGatewayV2HTTPEvent is an API Gateway HTTP API event using the 2.0
+	// payload format
+	APIGatewayV2HTTPEvent
+	// ALBTargetGroupEvent is an Application Load Balancer target group event
+	ALBTargetGroupEvent
+	// LambdaFunctionURLEvent is a Lambda Function URL event
+	LambdaFunctionURLEvent
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case APIGatewayProxyEvent:
+		return "APIGatewayProxy"
+	case APIGatewayV2HTTPEvent:
+		return "APIGatewayV2HTTP"
+	case ALBTargetGroupEvent:
+		return "ALBTargetGroup"
+	case LambdaFunctionURLEvent:
+		return "LambdaFunctionURL"
+	}
+	return "Unknown"
+}
+
+// eventShape contains the fields used to tell the supported events apart
+type eventShape struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	Resource       string `json:"resource"`
+	Path           string `json:"path"`
+	RawPath        string `json:"rawPath"`
+	RequestContext struct {
+		ELB        *json.RawMessage `json:"elb"`
+		DomainName string           `json:"domainName"`
+	} `json:"requestContext"`
+}
+
+// DetectEventType sniffs the raw JSON payload of an event and returns its
+// type. Function URL and HTTP API events share the 2.0 payload format,
+// they are told apart by the lambda-url domain of Function URLs. Events with
+// a path but no method, such as some console test events, are REST API
+// events.
+func DetectEventType(payload json.RawMessage) EventType {
+	shape := eventShape{}
+	if err := json.Unmarshal(payload, &shape); err != nil {
+		return UnknownEvent
+	}
+
+	switch {
+	case shape.RequestContext.ELB != nil:
+		return ALBTargetGroupEvent
+	case shape.Version == "2.0" || shape.RawPath != "":
+		if strings.Contains(shape.RequestContext.DomainName, ".lambda-url.") {
+			return LambdaFunctionURLEvent
+		}
+		return APIGatewayV2HTTPEvent
+	case shape.HTTPMethod != "" || shape.Resource != "" || shape.Path != "":
+		return APIGatewayProxyEvent
+	}
+	return UnknownEvent
+}
+
+// EventTypeFromContext returns the type of the event stored in the given
+// context by the AnyEventToHTTPRequest method.
+func EventTypeFromContext(ctx context.Context) (EventType, bool) {
+	eventType, ok := ctx.Value(eventTypeContextKey).(EventType)
+	return eventType, ok
+}
+
+// AnyEventToHTTPRequest detects the type of the raw JSON payload and
+// converts it into an http.Request object with the matching method of the
+// RequestAccessor object. REST API, HTTP API, ALB and Function URL events
+// are supported.
+// The original payload and the event type are stored in the context of the
+// returned request, they can be read with RawEventFromContext and
+// EventTypeFromContext.
+func (r *RequestAccessor) AnyEventToHTTPRequest(payload json.RawMessage) (*http.Request, EventType, error) {
+	eventType := DetectEventType(payload)
+
+	var httpRequest *http.Request
+	var err error
+	switch eventType {
+	case APIGatewayProxyEvent:
+		event := events.APIGatewayProxyRequest{}
+		if err = unmarshalEvent(payload, &event); err == nil {
+			httpRequest, err = r.ProxyEventToHTTPRequest(event)
+		}
+	case APIGatewayV2HTTPEvent:
+		event := events.APIGatewayV2HTTPRequest{}
+		if err = unmarshalEvent(payload, &event); err == nil {
+			httpRequest, err = r.APIGatewayV2HTTPRequestToHTTPRequest(event)
+		}
+	case ALBTargetGroupEvent:
+		event := events.ALBTargetGroupRequest{}
+		if err = unmarshalEvent(payload, &event); err == nil {
+			httpRequest, err = r.ALBTargetGroupRequestToHTTPRequest(event)
+		}
+	case LambdaFunctionURLEvent:
+		event := events.LambdaFunctionURLRequest{}
+		if err = unmarshalEvent(payload, &event); err == nil {
+			httpRequest, err = r.LambdaFunctionURLRequestToHTTPRequest(event)
+		}
+	default:
+		return nil, UnknownEvent, errors.New("Unknown event type")
+	}
+	if err != nil {
+		return nil, eventType, err
+	}
+
+	httpRequest = withRawEvent(httpRequest, []byte(payload))
+	httpRequest = httpRequest.WithContext(context.WithValue(httpRequest.Context(), eventTypeContextKey, eventType))
+	return httpRequest, eventType, nil
+}
+
+// GetResponse converts the data passed to the response writer into the
+// response object matching the given event type.
+func (r *ProxyResponseWriter) GetResponse(eventType EventType) (interface{}, error) {
+	switch eventType {
+	case APIGatewayProxyEvent:
+		return r.GetProxyResponse()
+	case APIGatewayV2HTTPEvent:
+		return r.GetAPIGatewayV2HTTPResponse()
+	case ALBTargetGroupEvent:
+		return r.GetALBTargetGroupResponse()
+	case LambdaFunctionURLEvent:
+		return r.GetLambdaFunctionURLResponse()
+	}
+	return nil, errors.New("Unknown event type")
+}
+
+// GatewayTimeoutFor returns the dafault Gateway Timeout (504) response for
+// the given event type.
+func GatewayTimeoutFor(eventType EventType) interface{} {
+	switch eventType {
+	case APIGatewayV2HTTPEvent:
+		return GatewayTimeoutV2()
+	case ALBTargetGroupEvent:
+		return ALBGatewayTimeout()
+	case LambdaFunctionURLEvent:
+		return GatewayTimeoutFunctionURL()
+	}
+	return GatewayTimeout()
+}
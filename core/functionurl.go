@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// FunctionURLContextHeader is the custom header key used to store the
+// Lambda Function URL request context. To access the Context properties
+// use the GetFunctionURLContext method of the RequestAccessor object.
+const FunctionURLContextHeader = "X-GoLambdaProxy-FunctionURL-Context"
+
+// GetFunctionURLContext extracts the Lambda Function URL request context
+// from a request's custom header.
+// Returns a populated events.LambdaFunctionURLRequestContext object from
+// the request.
+func (r *RequestAccessor) GetFunctionURLContext(req *http.Request) (events.LambdaFunctionURLRequestContext, error) {
+	if req.Header.Get(r.headerName(FunctionURLContextHeader)) == "" {
+		return events.LambdaFunctionURLRequestContext{}, errors.New("No function URL context header in request")
+	}
+	context := events.LambdaFunctionURLRequestContext{}
+	err := r.decodeJSONHeader(req, FunctionURLContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling function URL context")
+		log.Println(err)
+		return events.LambdaFunctionURLRequestContext{}, err
+	}
+	return context, nil
+}
+
+// LambdaFunctionURLRequestToHTTPRequest converts a Lambda Function URL event
+// into an http.Request object. Function URLs use the 2.0 payload format of
+// API Gateway HTTP APIs, without routes or stages.
+// Returns the populated request with an additional custom header for the
+// request context. To access it use the GetFunctionURLContext method of the
+// RequestAccessor object, or GetFunctionURLContextFromContext.
+func (r *RequestAccessor) LambdaFunctionURLRequestToHTTPRequest(req events.LambdaFunctionURLRequest) (*http.Request, error) {
+	httpRequest, err := r.newV2Request(req.RequestContext.HTTP.Method, req.RequestContext.DomainName, req.RawPath, req.RawQueryString, req.Cookies, req.Headers, req.Body, req.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, req.RequestContext.HTTP.SourceIP)
+	httpRequest = setProto(httpRequest, req.RequestContext.HTTP.Protocol)
+
+	if err := r.addJSONHeader(httpRequest, FunctionURLContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+
+	httpRequest = withValue(httpRequest, functionURLContextContextKey, req.RequestContext)
+	return r.withRequestID(httpRequest, req.RequestContext.RequestID), nil
+}
+
+// GetLambdaFunctionURLResponse converts the data passed to the response
+// writer into an events.LambdaFunctionURLResponse object.
+// Multiple values of the same header are joined with commas, except for the
+// Set-Cookie headers which are returned in the Cookies field.
+// Returns an error if the status code was not set on the response.
+func (r *ProxyResponseWriter) GetLambdaFunctionURLResponse() (events.LambdaFunctionURLResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.LambdaFunctionURLResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(false)
+	if err != nil {
+		return events.LambdaFunctionURLResponse{}, err
+	}
+
+	headers, cookies := r.v2Headers()
+	resp := events.LambdaFunctionURLResponse{
+		StatusCode:      r.status,
+		Headers:         headers,
+		Body:            output,
+		IsBase64Encoded: isBase64,
+		Cookies:         cookies,
+	}
+	retry, err := r.checkPayloadSize(resp, len(output))
+	if err != nil {
+		return events.LambdaFunctionURLResponse{}, err
+	}
+	if retry {
+		return r.GetLambdaFunctionURLResponse()
+	}
+	return resp, nil
+}
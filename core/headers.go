@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// DefaultListHeaders are the request headers split by SplitListHeaders when
+// it is called without names. Their values are comma separated lists by
+// definition, so splitting them does not change their meaning.
+var DefaultListHeaders = []string{
+	"Accept",
+	"Accept-Charset",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Cache-Control",
+	"If-Match",
+	"If-None-Match",
+	"Te",
+	"Via",
+}
+
+// DefaultUnsupportedHeaders are the request headers removed from all the
+// converted requests unless SetUnsupportedHeaders is called. The events
+// carry the complete body, so Expect: 100-continue cannot be honored, and
+// Proxy-Connection confuses servers when the request is replayed.
+var DefaultUnsupportedHeaders = []string{
+	"Expect",
+	"Proxy-Connection",
+}
+
+// HopByHopHeaders are the headers that are only meaningful for a single
+// connection and are removed by the StripHopByHopHeaders and
+// StripHopByHopResponseHeaders options, along with the headers listed in
+// the Connection header.
+var HopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// SetHeaderPrefix instructs the RequestAccessor object to use the given
+// prefix, instead of the DefaultHeaderPrefix, for the names of the custom
+// headers that hold the properties of the events, such as the API Gateway
+// context and the stage variables: with the "X-Lambda-" prefix the context
+// is stored in the X-Lambda-ApiGw-Context header. The Get methods of the
+// same RequestAccessor object read the renamed headers. An empty prefix
+// restores the default.
+func (r *RequestAccessor) SetHeaderPrefix(prefix string) {
+	r.headerPrefix = prefix
+}
+
+// SkipContextHeaders instructs the RequestAccessor object not to add the
+// custom headers holding the JSON encoded request context and stage
+// variables of the events to the converted requests. They add kilobytes to
+// each request and are not needed by handlers that do not read them. When
+// the headers are skipped, the Get methods reading them return an error,
+// except for the API Gateway context and stage variables, which are also
+// stored in the request context.
+func (r *RequestAccessor) SkipContextHeaders(skip bool) {
+	r.skipContextHeaders = skip
+}
+
+// addJSONHeader adds the JSON encoded value to the request in the custom
+// header with the given name, unless SkipContextHeaders is enabled.
+func (r *RequestAccessor) addJSONHeader(httpRequest *http.Request, name string, value interface{}) error {
+	if r.skipContextHeaders {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Could not marshal value for custom header %s\n", name)
+		return err
+	}
+	httpRequest.Header.Add(r.headerName(name), string(encoded))
+	return nil
+}
+
+// headerName returns the name of a custom header with the configured prefix.
+func (r *RequestAccessor) headerName(name string) string {
+	if r.headerPrefix == "" {
+		return name
+	}
+	return r.headerPrefix + strings.TrimPrefix(name, DefaultHeaderPrefix)
+}
+
+// SplitListHeaders instructs the RequestAccessor object to split the values
+// of the given request headers on commas, so that each element of the list
+// is a separate value of the http.Header. API Gateway joins the repeated
+// headers of the HTTP API and Function URL events with commas. Commas within
+// quoted strings are not split. With no names, the DefaultListHeaders are
+// split.
+func (r *RequestAccessor) SplitListHeaders(names ...string) {
+	if len(names) == 0 {
+		names = DefaultListHeaders
+	}
+	r.listHeaders = make([]string, len(names))
+	for i, name := range names {
+		r.listHeaders[i] = http.CanonicalHeaderKey(name)
+	}
+}
+
+// splitListHeaders splits the values of the list headers configured with
+// SplitListHeaders.
+func (r *RequestAccessor) splitListHeaders(header http.Header) {
+	for _, name := range r.listHeaders {
+		values, ok := header[name]
+		if !ok {
+			continue
+		}
+		split := []string{}
+		for _, value := range values {
+			split = append(split, splitList(value)...)
+		}
+		header[name] = split
+	}
+}
+
+// splitList splits a comma separated header value, ignoring the commas in
+// quoted strings and trimming the elements. Empty elements are removed.
+func splitList(value string) []string {
+	elements := []string{}
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				elements = appendElement(elements, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return appendElement(elements, value[start:])
+}
+
+func appendElement(elements []string, element string) []string {
+	if element = strings.TrimSpace(element); element != "" {
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// PreserveHeaderCase instructs the RequestAccessor object to record the
+// original names of the request headers, before they are converted to the
+// canonical MIME form of the http.Header keys. The names are stored in the
+// context of the request and can be read with the GetOriginalHeaderNames
+// method, for example to forward the request or verify a signature.
+func (r *RequestAccessor) PreserveHeaderCase(preserve bool) {
+	r.preserveHeaderCase = preserve
+}
+
+// withHeaderNames stores the original header names in the context of the
+// request if PreserveHeaderCase is enabled.
+func (r *RequestAccessor) withHeaderNames(req *http.Request, names []string) *http.Request {
+	if !r.preserveHeaderCase {
+		return req
+	}
+	headerNames := make(map[string]string, len(names))
+	for _, name := range names {
+		headerNames[http.CanonicalHeaderKey(name)] = name
+	}
+	return req.WithContext(context.WithValue(req.Context(), headerNamesContextKey, headerNames))
+}
+
+// headerNames returns the names of the single and multi value headers of an
+// event.
+func headerNames(headers map[string]string, multiValueHeaders map[string][]string) []string {
+	names := make([]string, 0, len(headers)+len(multiValueHeaders))
+	for name := range headers {
+		names = append(names, name)
+	}
+	for name := range multiValueHeaders {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SetUnsupportedHeaders replaces the list of headers removed from all the
+// converted requests, DefaultUnsupportedHeaders by default, because they are
+// meaningless for requests delivered as Lambda events. Calling it without
+// names keeps all the headers. The headers sent by the client with the
+// custom header prefix are always removed, see removeUnsupportedHeaders.
+func (r *RequestAccessor) SetUnsupportedHeaders(names ...string) {
+	r.unsupportedHeaders = append([]string{}, names...)
+}
+
+// removeUnsupportedHeaders deletes the unsupported headers from the header,
+// along with the headers using the custom header prefix. They are reserved
+// for the properties of the events: a client sending them could otherwise
+// forge the request context read by the Get methods and the middlewares.
+func (r *RequestAccessor) removeUnsupportedHeaders(header http.Header) {
+	names := r.unsupportedHeaders
+	if names == nil {
+		names = DefaultUnsupportedHeaders
+	}
+	for _, name := range names {
+		header.Del(name)
+	}
+	r.RemoveCustomHeaders(header)
+}
+
+// RemoveCustomHeaders deletes the headers using the DefaultHeaderPrefix or
+// the prefix set with SetHeaderPrefix from the header. The conversion
+// methods call it on the headers of the events; adapters building requests
+// from other sources of client headers should call it before adding their
+// own custom headers, so that the clients cannot forge them.
+func (r *RequestAccessor) RemoveCustomHeaders(header http.Header) {
+	RemoveCustomHeaders(header, r.headerPrefix)
+}
+
+// RemoveCustomHeaders deletes the headers using the DefaultHeaderPrefix, or
+// one of the given prefixes, from the header. Empty prefixes are ignored.
+func RemoveCustomHeaders(header http.Header, prefixes ...string) {
+	lower := []string{strings.ToLower(DefaultHeaderPrefix)}
+	for _, prefix := range prefixes {
+		if prefix != "" {
+			lower = append(lower, strings.ToLower(prefix))
+		}
+	}
+	for name := range header {
+		for _, prefix := range lower {
+			if strings.HasPrefix(strings.ToLower(name), prefix) {
+				delete(header, name)
+				break
+			}
+		}
+	}
+}
+
+// StripHopByHopHeaders instructs the RequestAccessor object to remove the
+// HopByHopHeaders, and the headers listed in the Connection header, from the
+// converted requests. They describe the connection between the client and
+// API Gateway or the load balancer, not the one with the handler.
+func (r *RequestAccessor) StripHopByHopHeaders(strip bool) {
+	r.stripHopByHop = strip
+}
+
+// StripHopByHopResponseHeaders instructs the ResponseOptions object to
+// remove the HopByHopHeaders, and the headers listed in the Connection
+// header, from the generated responses. API Gateway rejects or mishandles
+// several of them.
+func (o *ResponseOptions) StripHopByHopResponseHeaders(strip bool) {
+	o.stripHopByHop = strip
+}
+
+// removeHopByHopHeaders deletes the hop-by-hop headers from the header.
+func removeHopByHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range splitList(value) {
+			header.Del(name)
+		}
+	}
+	for _, name := range HopByHopHeaders {
+		header.Del(name)
+	}
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeHost converts a host, optionally including a port, to its
+// lowercase ASCII form. Internationalized domain names are punycode
+// encoded: "bücher.example.com" becomes "xn--bcher-kva.example.com".
+// Returns an error if the host is not a valid domain name.
+func NormalizeHost(host string) (string, error) {
+	name, port := splitHostPort(host)
+	if net.ParseIP(strings.Trim(name, "[]")) == nil {
+		ascii, err := idna.Lookup.ToASCII(name)
+		if err != nil {
+			return "", err
+		}
+		name = ascii
+	}
+	return joinHostPort(strings.ToLower(name), port), nil
+}
+
+// UnicodeHost converts a host, optionally including a port, to its
+// lowercase Unicode form, decoding punycode labels. It is the inverse
+// of NormalizeHost and is meant for display purposes.
+func UnicodeHost(host string) (string, error) {
+	name, port := splitHostPort(host)
+	if net.ParseIP(strings.Trim(name, "[]")) == nil {
+		unicode, err := idna.Lookup.ToUnicode(name)
+		if err != nil {
+			return "", err
+		}
+		name = unicode
+	}
+	return joinHostPort(strings.ToLower(name), port), nil
+}
+
+func splitHostPort(host string) (string, string) {
+	if name, port, err := net.SplitHostPort(host); err == nil {
+		if strings.Contains(name, ":") {
+			name = "[" + name + "]"
+		}
+		return name, port
+	}
+	return host, ""
+}
+
+func joinHostPort(name, port string) string {
+	if port == "" {
+		return name
+	}
+	return name + ":" + port
+}
+
+// normalizeServerAddress punycode encodes the host of the server address
+// used to build the request URL. The address is returned unchanged if it
+// cannot be parsed.
+func normalizeServerAddress(address string) string {
+	serverURL, err := url.Parse(address)
+	if err != nil || serverURL.Host == "" {
+		return address
+	}
+	host, err := NormalizeHost(serverURL.Host)
+	if err != nil {
+		return address
+	}
+	serverURL.Host = host
+	return serverURL.String()
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// VPCLatticeContextHeader is the custom header key used to store the VPC
+// Lattice request context. To access the Context properties use the
+// GetVPCLatticeContext method of the RequestAccessor object.
+const VPCLatticeContextHeader = "X-GoLambdaProxy-VPCLattice-Context"
+
+// VPCLatticeRequest is the event, using the 2.0 format, received by Lambda
+// functions registered as targets of a VPC Lattice target group.
+type VPCLatticeRequest struct {
+	Version               string                   `json:"version"`
+	Path                  string                   `json:"path"`
+	Method                string                   `json:"method"`
+	Headers               map[string][]string      `json:"headers"`
+	QueryStringParameters map[string][]string      `json:"queryStringParameters,omitempty"`
+	Body                  string                   `json:"body"`
+	IsBase64Encoded       bool                     `json:"isBase64Encoded"`
+	RequestContext        VPCLatticeRequestContext `json:"requestContext"`
+}
+
+// VPCLatticeRequestContext identifies the service network, service and
+// target group of the request and the caller that sent it.
+type VPCLatticeRequestContext struct {
+	ServiceNetworkARN string             `json:"serviceNetworkArn"`
+	ServiceARN        string             `json:"serviceArn"`
+	TargetGroupARN    string             `json:"targetGroupArn"`
+	Identity          VPCLatticeIdentity `json:"identity"`
+	Region            string             `json:"region"`
+	TimeEpoch         string             `json:"timeEpoch"`
+}
+
+// VPCLatticeIdentity contains the identity of the caller. The IAM fields are
+// only set when the service uses the AWS_IAM auth type.
+type VPCLatticeIdentity struct {
+	SourceVpcARN string `json:"sourceVpcArn"`
+	Type         string `json:"type"`
+	Principal    string `json:"principal"`
+	SessionName  string `json:"sessionName"`
+	X509SanDNS   string `json:"x509SanDns"`
+}
+
+// VPCLatticeResponse is the response returned to VPC Lattice.
+type VPCLatticeResponse struct {
+	StatusCode        int               `json:"statusCode"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	IsBase64Encoded   bool              `json:"isBase64Encoded"`
+}
+
+// GetVPCLatticeContext extracts the VPC Lattice request context from a
+// request's custom header.
+// Returns a populated VPCLatticeRequestContext object from the request.
+func (r *RequestAccessor) GetVPCLatticeContext(req *http.Request) (VPCLatticeRequestContext, error) {
+	if req.Header.Get(r.headerName(VPCLatticeContextHeader)) == "" {
+		return VPCLatticeRequestContext{}, errors.New("No VPC Lattice context header in request")
+	}
+	context := VPCLatticeRequestContext{}
+	err := r.decodeJSONHeader(req, VPCLatticeContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling VPC Lattice context")
+		log.Println(err)
+		return VPCLatticeRequestContext{}, err
+	}
+	return context, nil
+}
+
+// VPCLatticeRequestToHTTPRequest converts a VPC Lattice event into an
+// http.Request object. Only the 2.0 event format is supported, the target
+// group must be configured with the V2 Lambda event structure version.
+// Returns the populated request with an additional custom header for the
+// request context. To access it use the GetVPCLatticeContext method of the
+// RequestAccessor object.
+func (r *RequestAccessor) VPCLatticeRequestToHTTPRequest(req VPCLatticeRequest) (*http.Request, error) {
+	path := r.requestURL(req.Path)
+	if len(req.QueryStringParameters) > 0 {
+		path += "?" + encodeQuery(queryValues(nil, req.QueryStringParameters))
+	}
+
+	httpRequest, err := http.NewRequest(
+		eventMethod(req.Method),
+		path,
+		nil,
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert VPC Lattice request %s:%s to http.Request\n", req.Method, req.Path)
+		log.Println(err)
+		return nil, err
+	}
+
+	for h, values := range req.Headers {
+		for _, value := range values {
+			httpRequest.Header.Add(h, value)
+		}
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, "")
+	r.setForwarded(httpRequest)
+	r.overrideMethod(httpRequest)
+	if err := r.setBody(httpRequest, req.Body, req.IsBase64Encoded); err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, "")
+
+	if err := r.addJSONHeader(httpRequest, VPCLatticeContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+
+	return r.withRequestValues(httpRequest, headerNames(nil, req.Headers)), nil
+}
+
+// GetVPCLatticeResponse converts the data passed to the response writer
+// into a VPCLatticeResponse object. Multiple values of the same header are
+// joined with commas, except for Set-Cookie: the cookie attributes contain
+// commas, so only the last cookie set by the handler is returned. The other
+// cookies are discarded and a message is logged; handlers served through
+// VPC Lattice should set a single cookie per response.
+// Responses larger than the Lambda payload limit are passed to the overflow
+// handler, see SetResponseOverflowHandler.
+// Returns an error if the status code was not set on the response.
+func (r *ProxyResponseWriter) GetVPCLatticeResponse() (VPCLatticeResponse, error) {
+	if r.status == defaultStatusCode {
+		return VPCLatticeResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(false)
+	if err != nil {
+		return VPCLatticeResponse{}, err
+	}
+
+	headers := make(map[string]string)
+	for h, values := range r.headers {
+		if h == "Set-Cookie" && len(values) > 0 {
+			if len(values) > 1 {
+				log.Printf("VPC Lattice responses hold a single cookie, discarding %d Set-Cookie headers\n", len(values)-1)
+			}
+			headers[h] = values[len(values)-1]
+			continue
+		}
+		headers[h] = strings.Join(values, ",")
+	}
+
+	resp := VPCLatticeResponse{
+		StatusCode:        r.status,
+		StatusDescription: fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		Headers:           headers,
+		Body:              output,
+		IsBase64Encoded:   isBase64,
+	}
+	retry, err := r.checkPayloadSize(resp, len(output))
+	if err != nil {
+		return VPCLatticeResponse{}, err
+	}
+	if retry {
+		return r.GetVPCLatticeResponse()
+	}
+	return resp, nil
+}
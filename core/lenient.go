@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// singleValueFields maps the single value fields of the events that test
+// consoles and emulators sometimes encode as arrays to their multi value
+// counterpart
+var singleValueFields = map[string]string{
+	"headers":               "multiValueHeaders",
+	"queryStringParameters": "multiValueQueryStringParameters",
+	"pathParameters":        "",
+	"stageVariables":        "",
+}
+
+// multiValueFields are the multi value fields of the events that are
+// sometimes encoded as single strings
+var multiValueFields = []string{"multiValueHeaders", "multiValueQueryStringParameters"}
+
+// unmarshalEvent unmarshals the raw JSON payload of an event into v. When the
+// payload does not match the event type, it is normalized with
+// normalizeEvent and unmarshalled again, so that the events generated by test
+// consoles, older SAM versions and third-party emulators can be converted.
+func unmarshalEvent(payload []byte, v interface{}) error {
+	err := json.Unmarshal(payload, v)
+	if err == nil {
+		return nil
+	}
+	normalized, ok := normalizeEvent(payload)
+	if !ok {
+		return err
+	}
+	return json.Unmarshal(normalized, v)
+}
+
+// normalizeEvent rewrites the ambiguous fields of an event payload to the
+// types expected by the event structs: the arrays of the single value fields
+// are replaced by their last element, like API Gateway does, and copied to
+// the multi value field when the payload has none, the single strings of the
+// multi value fields and of the cookies become arrays, and the numbers and
+// booleans become strings. Returns false if the payload is not a JSON object.
+func normalizeEvent(payload []byte) ([]byte, bool) {
+	event := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, false
+	}
+
+	for field, multiField := range singleValueFields {
+		values, ok := event[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		_, hasMulti := event[multiField].(map[string]interface{})
+		multi := map[string]interface{}{}
+		hasList := false
+		for key, value := range values {
+			list, isList := value.([]interface{})
+			if !isList {
+				values[key] = scalarString(value)
+				multi[key] = []interface{}{values[key]}
+				continue
+			}
+			if len(list) == 0 {
+				delete(values, key)
+				continue
+			}
+			values[key] = scalarString(list[len(list)-1])
+			multi[key] = stringList(list)
+			hasList = true
+		}
+		// the converters prefer the multi value fields, which must then
+		// include all the single values
+		if multiField != "" && !hasMulti && hasList {
+			event[multiField] = multi
+		}
+	}
+
+	for _, field := range multiValueFields {
+		values, ok := event[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range values {
+			if list, isList := value.([]interface{}); isList {
+				values[key] = stringList(list)
+			} else {
+				values[key] = []interface{}{scalarString(value)}
+			}
+		}
+	}
+
+	if cookie, ok := event["cookies"].(string); ok {
+		event["cookies"] = []interface{}{cookie}
+	}
+
+	normalized, err := json.Marshal(event)
+	if err != nil {
+		return nil, false
+	}
+	return normalized, true
+}
+
+// scalarString returns the string form of a JSON scalar value. Null values
+// are returned as empty strings.
+func scalarString(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case float64, bool:
+		return fmt.Sprint(v)
+	}
+	return value
+}
+
+func stringList(list []interface{}) []interface{} {
+	for i, value := range list {
+		list[i] = scalarString(value)
+	}
+	return list
+}
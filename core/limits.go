@@ -0,0 +1,35 @@
+package core
+
+import "net/http"
+
+// SetMaxRequestBodyBytes instructs the RequestAccessor object to reject the
+// requests whose decoded body is larger than max bytes. They are answered
+// with a 413 Request Entity Too Large by the handler returned from
+// EnforceMaxRequestBodyBytes, without reaching the framework. This protects
+// the handlers from payloads close to the size limits of the events. A limit
+// of zero or less removes the check, which is the default.
+func (r *RequestAccessor) SetMaxRequestBodyBytes(max int64) {
+	r.maxBodyBytes = max
+}
+
+// EnforceMaxRequestBodyBytes wraps the given handler and answers requests
+// whose body exceeds the limit set with SetMaxRequestBodyBytes with a 413.
+// The body of the accepted requests is also limited while it is read, for
+// streamed bodies whose length is only estimated. The adapters apply it
+// before sending requests to the framework.
+func (r *RequestAccessor) EnforceMaxRequestBodyBytes(next http.Handler) http.Handler {
+	max := r.maxBodyBytes
+	if max <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ContentLength > max {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = http.MaxBytesReader(w, req.Body, max)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
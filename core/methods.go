@@ -0,0 +1,118 @@
+package core
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MethodOverrideHeader is the header read by the AllowMethodOverride option
+// to replace the method of POST requests.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// CommonMethods is the list of methods allowed by AllowCommonMethods
+var CommonMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+type methodPolicy struct {
+	allow   bool
+	methods map[string]bool
+}
+
+// AllowMethods instructs the RequestAccessor object to only accept requests
+// using one of the given methods. Requests with any other method are
+// answered with a 405 by the handler returned from EnforceMethodPolicy,
+// without reaching the framework. Calling AllowMethods with no methods
+// removes the policy.
+func (r *RequestAccessor) AllowMethods(methods ...string) {
+	r.setMethodPolicy(true, methods)
+}
+
+// AllowCommonMethods restricts the accepted methods to the CommonMethods,
+// rejecting TRACE, CONNECT and arbitrary verbs forwarded by API Gateway.
+func (r *RequestAccessor) AllowCommonMethods() {
+	r.AllowMethods(CommonMethods...)
+}
+
+// RejectMethods instructs the RequestAccessor object to answer requests
+// using one of the given methods with a 405. Calling RejectMethods with no
+// methods removes the policy.
+func (r *RequestAccessor) RejectMethods(methods ...string) {
+	r.setMethodPolicy(false, methods)
+}
+
+func (r *RequestAccessor) setMethodPolicy(allow bool, methods []string) {
+	if len(methods) == 0 {
+		r.methodPolicy = nil
+		return
+	}
+	policy := &methodPolicy{allow: allow, methods: make(map[string]bool)}
+	for _, method := range methods {
+		policy.methods[strings.ToUpper(method)] = true
+	}
+	r.methodPolicy = policy
+}
+
+// MethodAllowed returns true if the method policy accepts the given method.
+// All methods are allowed when no policy is set.
+func (r *RequestAccessor) MethodAllowed(method string) bool {
+	if r.methodPolicy == nil {
+		return true
+	}
+	return r.methodPolicy.methods[strings.ToUpper(method)] == r.methodPolicy.allow
+}
+
+// EnforceMethodPolicy wraps the given handler and answers requests whose
+// method is not allowed by the policy with a 405 Method Not Allowed. When
+// the policy lists the allowed methods they are returned in the Allow
+// header. The adapters apply it before sending requests to the framework.
+func (r *RequestAccessor) EnforceMethodPolicy(next http.Handler) http.Handler {
+	policy := r.methodPolicy
+	if policy == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if policy.methods[strings.ToUpper(req.Method)] == policy.allow {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if policy.allow {
+			allowed := make([]string, 0, len(policy.methods))
+			for method := range policy.methods {
+				allowed = append(allowed, method)
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	})
+}
+
+// AllowMethodOverride instructs the RequestAccessor object to replace the
+// method of POST requests with the value of their MethodOverrideHeader, for
+// the clients that can only issue GET and POST requests. The method is
+// replaced during the conversion, so the method policy and the routes of the
+// framework see the intended method.
+func (r *RequestAccessor) AllowMethodOverride(allow bool) {
+	r.methodOverride = allow
+}
+
+// overrideMethod applies the MethodOverrideHeader to POST requests if the
+// option is enabled. Values that are not a single word are ignored.
+func (r *RequestAccessor) overrideMethod(req *http.Request) {
+	if !r.methodOverride || req.Method != http.MethodPost {
+		return
+	}
+	method := strings.ToUpper(strings.TrimSpace(req.Header.Get(MethodOverrideHeader)))
+	if method == "" || strings.IndexFunc(method, func(c rune) bool { return c < 'A' || c > 'Z' }) >= 0 {
+		return
+	}
+	req.Method = method
+}
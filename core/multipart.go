@@ -0,0 +1,67 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ValidateMultipartBody reads the multipart body of the request, such as a
+// file upload, and checks that the boundary declared in the Content-Type
+// header delimits all of its parts. Uploads break when the body is not base64
+// encoded by API Gateway, which happens when multipart/form-data is not
+// listed in the binary media types of the API, or when the boundary is
+// altered. The body of the request is replaced so that it can still be read
+// by the handler.
+// Returns the number of parts in the body.
+func ValidateMultipartBody(req *http.Request) (int, error) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(contentTypeHeaderKey))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid multipart Content-Type: %v", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return 0, fmt.Errorf("Request is not multipart: %s", mediaType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return 0, errors.New("No boundary in multipart Content-Type")
+	}
+	if req.Body == nil {
+		return 0, errors.New("No body in multipart request")
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return 0, err
+	}
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	parts := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return parts, fmt.Errorf("Could not read part %d of multipart body: %v", parts+1, err)
+		}
+		_, err = io.Copy(ioutil.Discard, part)
+		part.Close()
+		if err != nil {
+			return parts, fmt.Errorf("Could not read part %d of multipart body: %v", parts+1, err)
+		}
+		parts++
+	}
+	if parts == 0 {
+		return 0, errors.New("No parts in multipart body")
+	}
+	return parts, nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import "io"
+
+// WriteObserver is called by the ProxyResponseWriter with each chunk of the
+// body written by the handler, before the chunk is buffered. The status code
+// and the headers of the response are already set when the first chunk is
+// observed. Observers can log the progress of large responses or stop them
+// early: an error returned by the observer is returned by the Write method
+// and the chunk is discarded. The chunk must not be modified or retained.
+type WriteObserver func(w *ProxyResponseWriter, chunk []byte) error
+
+// AddWriteObserver instructs the ResponseOptions object to register the
+// given observer on the writers it creates. Observers are called in the
+// order they were added.
+func (o *ResponseOptions) AddWriteObserver(observer WriteObserver) {
+	// the writers hold a copy of the options, the observers are copied so
+	// that the writers never append to the slice of the adapter
+	o.writeObservers = append(o.writeObservers[:len(o.writeObservers):len(o.writeObservers)], observer)
+}
+
+// AddWriteObserver registers the given observer on the writer, after the
+// observers of the options the writer was created with.
+func (r *ProxyResponseWriter) AddWriteObserver(observer WriteObserver) {
+	r.options.AddWriteObserver(observer)
+}
+
+// observeWrite passes the chunk to the observers of the writer.
+func (r *ProxyResponseWriter) observeWrite(chunk []byte) error {
+	for _, observer := range r.options.writeObservers {
+		if err := observer(r, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observedWriter hides the ReadFrom method of the ProxyResponseWriter, so
+// that io.Copy passes each chunk to the Write method.
+type observedWriter struct {
+	w *ProxyResponseWriter
+}
+
+func (o observedWriter) Write(chunk []byte) (int, error) {
+	return o.w.Write(chunk)
+}
+
+// copyObserved copies src to the body of the writer through its observers.
+func (r *ProxyResponseWriter) copyObserved(src io.Reader) (int64, error) {
+	return io.Copy(observedWriter{r}, src)
+}
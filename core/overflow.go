@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxResponsePayloadBytes is the maximum size of the JSON payload returned
+// by a synchronous Lambda invocation. Larger responses are rejected by the
+// Lambda runtime and the client receives an error without any detail.
+const MaxResponsePayloadBytes = 6 * 1024 * 1024
+
+// MaxCloudFrontPayloadBytes is the maximum size of the response generated by
+// a Lambda@Edge function for an origin request or an origin response
+// event. Responses generated for viewer events are limited to 40KB, set the
+// limit with SetResponseOverflowHandler for those functions.
+const MaxCloudFrontPayloadBytes = 1024 * 1024
+
+// OverflowHandler is called when the payload of a response would exceed the
+// limit given to SetResponseOverflowHandler. The handler receives the writer
+// holding the response of the API and the size of the payload, in bytes. It
+// can replace the response, for example with an error response or a
+// redirect to a copy of the body uploaded elsewhere, by calling the Reset
+// method of the writer and writing a new response. The handler is called
+// once per response; if the new response still exceeds the limit, or the
+// handler returns an error, the response conversion fails.
+type OverflowHandler func(w *ProxyResponseWriter, size int) error
+
+// SetResponseOverflowHandler instructs the ResponseOptions object to call the
+// given handler when the payload of a response would exceed limit bytes. A
+// limit of 0 or less uses MaxResponsePayloadBytes, or
+// MaxCloudFrontPayloadBytes for the CloudFront responses. Without a handler, the
+// oversized responses are reported as errors by the Get methods of the
+// ProxyResponseWriter.
+func (o *ResponseOptions) SetResponseOverflowHandler(limit int, handler OverflowHandler) {
+	o.maxPayloadBytes = limit
+	o.overflowHandler = handler
+}
+
+// OverflowStatus returns an OverflowHandler that replaces the oversized
+// responses with an empty response with the given status code, such as
+// http.StatusBadGateway.
+func OverflowStatus(status int) OverflowHandler {
+	return func(w *ProxyResponseWriter, size int) error {
+		w.Reset()
+		w.WriteHeader(status)
+		return nil
+	}
+}
+
+// checkPayloadSize measures the JSON payload of the response generated from
+// bodyLen bytes of encoded body. When it exceeds the limit, the overflow
+// handler is called and true is returned so that the caller generates the
+// response again.
+// Returns an error if the payload is too large and cannot be replaced.
+func (r *ProxyResponseWriter) checkPayloadSize(resp interface{}, bodyLen int) (bool, error) {
+	return r.checkPayloadLimit(resp, bodyLen, MaxResponsePayloadBytes)
+}
+
+// checkPayloadLimit is checkPayloadSize with the given default limit, used
+// when the options do not set one.
+func (r *ProxyResponseWriter) checkPayloadLimit(resp interface{}, bodyLen int, defaultLimit int) (bool, error) {
+	limit := r.options.maxPayloadBytes
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	// escaping can grow each byte of the body to six bytes of JSON, smaller
+	// bodies do not need to be measured
+	if bodyLen*6 < limit/2 {
+		return false, nil
+	}
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return false, err
+	}
+	if len(payload) <= limit {
+		return false, nil
+	}
+
+	if r.options.overflowHandler == nil || r.overflowHandled {
+		return false, fmt.Errorf("Response payload of %d bytes exceeds the limit of %d bytes", len(payload), limit)
+	}
+	// the handler usually calls Reset, the flag is set again once it
+	// returns so that it is not called for the response it wrote
+	r.overflowHandled = true
+	err = r.options.overflowHandler(r, len(payload))
+	r.overflowHandled = true
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"net/http"
+	"sync"
+)
+
+// maxPooledBodyBytes is the capacity above which the body buffers are not
+// returned to the pool, so that a single large response does not keep its
+// buffer in memory for the lifetime of the container.
+const maxPooledBodyBytes = 1024 * 1024
+
+var writerPool = sync.Pool{
+	New: func() interface{} {
+		return NewProxyResponseWriter()
+	},
+}
+
+// PoolResponseWriters instructs the ResponseOptions object to reuse the
+// ProxyResponseWriter objects, and their body buffers, across invocations.
+// The writers created by NewProxyResponseWriter are taken from a pool and
+// returned to it by their Release method, which the adapters call once the
+// response is generated. This reduces the allocations of functions serving
+// many requests. Handlers must not keep a reference to the writer after they
+// return.
+func (o *ResponseOptions) PoolResponseWriters(pool bool) {
+	o.poolWriters = pool
+}
+
+// acquireProxyResponseWriter returns a writer from the pool.
+func acquireProxyResponseWriter() *ProxyResponseWriter {
+	w := writerPool.Get().(*ProxyResponseWriter)
+	w.pooled = true
+	return w
+}
+
+// Release returns a writer created with the PoolResponseWriters option to
+// the pool. The writer and the values returned by its Get methods must not
+// be used by the caller afterwards, except for the responses generated
+// before the call, which do not share memory with the writer. Release does
+// nothing for the other writers and when called more than once.
+func (r *ProxyResponseWriter) Release() {
+	if !r.pooled {
+		return
+	}
+	r.pooled = false
+	if r.body.Cap() > maxPooledBodyBytes {
+		return
+	}
+
+	body := r.body
+	body.Reset()
+	*r = ProxyResponseWriter{
+		headers: make(http.Header),
+		body:    body,
+		status:  defaultStatusCode,
+	}
+	writerPool.Put(r)
+}
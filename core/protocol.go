@@ -0,0 +1,43 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ProtocolFromContext returns the HTTP protocol negotiated by the client,
+// such as HTTP/1.1 or HTTP/2.0, stored in the given context by the
+// ProxyEventToHTTPRequest, APIGatewayV2HTTPRequestToHTTPRequest and
+// LambdaFunctionURLRequestToHTTPRequest methods.
+func ProtocolFromContext(ctx context.Context) (string, bool) {
+	protocol, ok := ctx.Value(protocolContextKey).(string)
+	return protocol, ok
+}
+
+// GetRequestProtocol extracts the HTTP protocol reported in the request
+// context of the event from the context of the request. The Proto,
+// ProtoMajor and ProtoMinor fields of the request are set to the same
+// protocol, this accessor returns it even after a middleware changed them.
+// ALB events do not report the protocol.
+func (r *RequestAccessor) GetRequestProtocol(req *http.Request) (string, error) {
+	protocol, ok := ProtocolFromContext(req.Context())
+	if !ok {
+		return "", errors.New("No protocol in request context")
+	}
+	return protocol, nil
+}
+
+// setProto sets the protocol version of the request to the protocol of the
+// event and stores it in the request context. Events without a valid
+// protocol keep the HTTP/1.1 default of http.NewRequest.
+func setProto(httpRequest *http.Request, protocol string) *http.Request {
+	major, minor, ok := http.ParseHTTPVersion(protocol)
+	if !ok {
+		return httpRequest
+	}
+	httpRequest.Proto = protocol
+	httpRequest.ProtoMajor = major
+	httpRequest.ProtoMinor = minor
+	return withValue(httpRequest, protocolContextKey, protocol)
+}
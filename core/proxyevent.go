@@ -0,0 +1,87 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HTTPRequestToProxyEvent converts an http.Request object into the API
+// Gateway proxy event that ProxyEventToHTTPRequest would convert back into an
+// equivalent request. It is the inverse of the conversion and makes it easy
+// to build events in unit tests and to proxy real HTTP traffic into Lambda
+// style handlers.
+// The single and multi value fields of the headers and of the query string
+// are both populated, the single value ones holding the last value like API
+// Gateway does. Bodies that are not valid UTF-8, or that declare a
+// Content-Encoding, are base64 encoded. The body of the request is replaced
+// so that it can still be read.
+func HTTPRequestToProxyEvent(req *http.Request) (events.APIGatewayProxyRequest, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return events.APIGatewayProxyRequest{}, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	headers := map[string]string{}
+	multiValueHeaders := map[string][]string{}
+	for name, values := range req.Header {
+		if len(values) == 0 {
+			continue
+		}
+		headers[name] = values[len(values)-1]
+		multiValueHeaders[name] = values
+	}
+	if req.Host != "" && req.Header.Get("Host") == "" {
+		headers["Host"] = req.Host
+		multiValueHeaders["Host"] = []string{req.Host}
+	}
+
+	query := map[string]string{}
+	multiValueQuery := map[string][]string{}
+	for key, values := range req.URL.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		query[key] = values[len(values)-1]
+		multiValueQuery[key] = values
+	}
+
+	sourceIP := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		sourceIP = host
+	}
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      req.Method,
+		Path:                            req.URL.Path,
+		Headers:                         headers,
+		MultiValueHeaders:               multiValueHeaders,
+		QueryStringParameters:           query,
+		MultiValueQueryStringParameters: multiValueQuery,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: req.Method,
+			Path:       req.URL.Path,
+			Protocol:   req.Proto,
+			DomainName: req.Host,
+			Identity:   events.APIGatewayRequestIdentity{SourceIP: sourceIP},
+		},
+	}
+	if encoding := req.Header.Get("Content-Encoding"); utf8.Valid(body) && (encoding == "" || encoding == "identity") {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+	return event, nil
+}
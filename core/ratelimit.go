@@ -0,0 +1,85 @@
+package core
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the rate of the requests served by
+// the adapters, including the cached responses. A warm Lambda container keeps the bucket between invocations,
+// so the limit applies to each container. The usage plans and the stage
+// settings of API Gateway throttle the whole API. A RateLimiter can be
+// shared by concurrent invocations.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter allowing requestsPerSecond
+// requests per second on average, and bursts of up to burst requests. A
+// burst of less than one allows a single request at a time.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Allow takes a token from the bucket. Returns false and the time until
+// the next token is available if the bucket is empty.
+func (l *RateLimiter) Allow() (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Second
+	}
+	return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// SetRateLimiter instructs the RequestAccessor object to throttle the
+// requests with the given limiter, see the EnforceRateLimit method. A nil
+// limiter disables the throttling, which is the default.
+func (r *RequestAccessor) SetRateLimiter(limiter *RateLimiter) {
+	r.rateLimiter = limiter
+}
+
+// EnforceRateLimit wraps the given handler and answers the requests beyond
+// the rate of the limiter set with SetRateLimiter with a 429 Too Many
+// Requests, with a Retry-After header in seconds. The adapters apply it
+// before sending requests to the framework.
+func (r *RequestAccessor) EnforceRateLimit(next http.Handler) http.Handler {
+	limiter := r.rateLimiter
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ok, wait := limiter.Allow(); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
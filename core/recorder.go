@@ -0,0 +1,169 @@
+package core
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder is an http.ResponseWriter that records the status code
+// and the size of the response written by a handler and forwards the
+// response to the writer it wraps. Middlewares use it to observe the
+// responses of the handlers they wrap. The Flush, Hijack, ReadFrom and
+// CloseNotify methods are passed through to the wrapped writer, so that
+// the handlers keep access to the interfaces of the ProxyResponseWriter.
+type ResponseRecorder struct {
+	w                  http.ResponseWriter
+	header             http.Header
+	status             int
+	written            int64
+	body               io.Writer
+	contentTypeDefault ContentTypeDefault
+	defaultContentType string
+	closed             chan bool
+}
+
+// NewResponseRecorder returns a new ResponseRecorder that wraps the given
+// writer. When the writer is nil the response is discarded once recorded
+// and the recorder sets the Content-Type of the responses written without
+// one like a ProxyResponseWriter would, see SetContentTypeDefault.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	r := &ResponseRecorder{w: w}
+	if w != nil {
+		r.header = w.Header()
+	} else {
+		r.header = make(http.Header)
+	}
+	return r
+}
+
+// SetContentTypeDefault sets the Content-Type default applied by a recorder
+// created without a writer. It should match the default of the adapter
+// the recorded responses are compared with, see
+// ResponseOptions.SetContentTypeDefault. The default is ignored when the
+// recorder wraps a writer, the wrapped writer applies its own.
+func (r *ResponseRecorder) SetContentTypeDefault(d ContentTypeDefault, contentType string) {
+	r.contentTypeDefault = d
+	r.defaultContentType = contentType
+}
+
+// CopyBodyTo instructs the recorder to also write the bytes of the body
+// written by the handler to dst.
+func (r *ResponseRecorder) CopyBodyTo(dst io.Writer) {
+	r.body = dst
+}
+
+// Header implementation from the http.ResponseWriter interface.
+func (r *ResponseRecorder) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader implementation from the http.ResponseWriter interface. Only
+// the first final status code is recorded, informational 1xx status codes
+// are forwarded but not recorded.
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.status == 0 && status >= http.StatusOK {
+		r.status = status
+	}
+	if r.w != nil {
+		r.w.WriteHeader(status)
+	}
+}
+
+// Write implementation from the http.ResponseWriter interface.
+func (r *ResponseRecorder) Write(body []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.body != nil {
+		r.body.Write(body)
+	}
+	if r.w == nil {
+		applyContentTypeDefault(r.header, r.contentTypeDefault, r.defaultContentType, body)
+		r.written += int64(len(body))
+		return len(body), nil
+	}
+	n, err := r.w.Write(body)
+	r.written += int64(n)
+	return n, err
+}
+
+// recorderWriter hides the ReadFrom method of the ResponseRecorder, so
+// that io.Copy passes each chunk to the Write method.
+type recorderWriter struct {
+	r *ResponseRecorder
+}
+
+func (w recorderWriter) Write(chunk []byte) (int, error) {
+	return w.r.Write(chunk)
+}
+
+// ReadFrom implementation from the io.ReaderFrom interface. The body is
+// passed to the ReadFrom method of the wrapped writer when it has one and
+// the body is not copied, see CopyBodyTo.
+func (r *ResponseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	rf, ok := r.w.(io.ReaderFrom)
+	if !ok || r.body != nil {
+		return io.Copy(recorderWriter{r}, src)
+	}
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := rf.ReadFrom(src)
+	r.written += n
+	return n, err
+}
+
+// Flush implementation from the http.Flusher interface. Flush is a no-op
+// when the wrapped writer does not implement http.Flusher.
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.w.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implementation from the http.Hijacker interface. Returns
+// ErrHijackNotSupported when the wrapped writer does not implement
+// http.Hijacker.
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.w.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, ErrHijackNotSupported
+}
+
+// CloseNotify implementation from the http.CloseNotifier interface. The
+// returned channel never receives a value when the wrapped writer does not
+// implement http.CloseNotifier.
+func (r *ResponseRecorder) CloseNotify() <-chan bool {
+	if c, ok := r.w.(http.CloseNotifier); ok {
+		return c.CloseNotify()
+	}
+	if r.closed == nil {
+		r.closed = make(chan bool, 1)
+	}
+	return r.closed
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.w
+}
+
+// StatusCode returns the status code of the response, 200 OK when the
+// handler did not write one.
+func (r *ResponseRecorder) StatusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Written returns the number of bytes of the body written by the handler.
+func (r *ResponseRecorder) Written() int64 {
+	return r.written
+}
@@ -4,28 +4,44 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
 // CustomHostVariable is the name of the environment variable that contains
-// the custom hostname for the request. If this variable is not set the framework
-// reverts to `DefaultServerAddress`. The value for a custom host should include
-// a protocol: http://my-custom.host.com
+// the custom hostname for the request. It can be overridden for each accessor
+// with the SetServerAddress method. If this variable is not set the framework
+// uses the Host header or the domain name of the event, and reverts to
+// `DefaultServerAddress` when the event has neither. The value for a custom
+// host should include a protocol: http://my-custom.host.com
 const CustomHostVariable = "GO_API_HOST"
 
 // DefaultServerAddress is prepended to the path of each incoming reuqest
 const DefaultServerAddress = "https://aws-serverless-go-api.com"
 
+// DefaultHeaderPrefix is the prefix of the custom headers used to pass the
+// properties of the events to the handlers. It can be replaced with the
+// SetHeaderPrefix method of the RequestAccessor object.
+const DefaultHeaderPrefix = "X-GoLambdaProxy-"
+
 // APIGwContextHeader is the custom header key used to store the
 // API Gateway context. To access the Context properties use the
 // GetAPIGatewayContext method of the RequestAccessor object.
@@ -36,22 +52,72 @@ const APIGwContextHeader = "X-GoLambdaProxy-ApiGw-Context"
 // use the GetAPIGatewayStageVars method of the RequestAccessor object.
 const APIGwStageVarsHeader = "X-GoLambdaProxy-ApiGw-StageVars"
 
+// ALBContextHeader is the custom header key used to store the Application
+// Load Balancer request context. To access the Context properties use the
+// GetALBTargetGroupRequestContext method of the RequestAccessor object.
+const ALBContextHeader = "X-GoLambdaProxy-ALB-Context"
+
+// TrailingSlash selects how the RequestAccessor object normalizes the
+// trailing slash of the request paths before routing.
+type TrailingSlash int
+
+const (
+	// KeepTrailingSlash sends the paths to the framework as they were
+	// received. This is the default.
+	KeepTrailingSlash TrailingSlash = iota
+	// RemoveTrailingSlash removes the trailing slashes: /users/ becomes
+	// /users
+	RemoveTrailingSlash
+	// AddTrailingSlash adds a trailing slash to the paths that do not
+	// have one: /users becomes /users/
+	AddTrailingSlash
+)
+
 // RequestAccessor objects give access to custom API Gateway properties
 // in the request.
 type RequestAccessor struct {
-	stripBasePath string
+	stripBasePaths      []string
+	stripStage          bool
+	rewriteRules        []rewriteRule
+	trailingSlash       TrailingSlash
+	listHeaders         []string
+	serverAddress       string
+	headerPrefix        string
+	skipContextHeaders  bool
+	preserveHeaderCase  bool
+	stripHopByHop       bool
+	transcodeBodies     bool
+	streamBodies        bool
+	maxBodyBytes        int64
+	deadlineMargin      time.Duration
+	warmupMatcher       WarmupMatcher
+	healthCheckPath     string
+	healthCheckStatus   int
+	coldStartHeader     bool
+	skipRequestIDHeader bool
+	methodOverride      bool
+	unsupportedHeaders  []string
+	decompressBodies    bool
+	responseCache       *ResponseCache
+	methodPolicy        *methodPolicy
+	rateLimiter         *RateLimiter
 }
 
 // GetAPIGatewayContext extracts the API Gateway context object from a
 // request's custom header.
 // Returns a populated events.APIGatewayProxyRequestContext object from
-// the request.
+// the request. The header is decoded once per request, later calls return
+// the cached value. Without the header, the context stored in the request
+// context is returned.
 func (r *RequestAccessor) GetAPIGatewayContext(req *http.Request) (events.APIGatewayProxyRequestContext, error) {
-	if req.Header.Get(APIGwContextHeader) == "" {
+	if req.Header.Get(r.headerName(APIGwContextHeader)) == "" {
+		if context, ok := GetAPIGatewayContextFromContext(req.Context()); ok {
+			return context, nil
+		}
 		return events.APIGatewayProxyRequestContext{}, errors.New("No context header in request")
 	}
 	context := events.APIGatewayProxyRequestContext{}
-	err := json.Unmarshal([]byte(req.Header.Get(APIGwContextHeader)), &context)
+	err := r.decodeJSONHeader(req, APIGwContextHeader, &context)
 	if err != nil {
 		log.Println("Erorr while unmarshalling context")
 		log.Println(err)
@@ -60,16 +126,39 @@ func (r *RequestAccessor) GetAPIGatewayContext(req *http.Request) (events.APIGat
 	return context, nil
 }
 
+// GetALBTargetGroupRequestContext extracts the Application Load Balancer
+// request context from a request's custom header.
+// Returns a populated events.ALBTargetGroupRequestContext object from
+// the request.
+func (r *RequestAccessor) GetALBTargetGroupRequestContext(req *http.Request) (events.ALBTargetGroupRequestContext, error) {
+	if req.Header.Get(r.headerName(ALBContextHeader)) == "" {
+		return events.ALBTargetGroupRequestContext{}, errors.New("No ALB context header in request")
+	}
+	context := events.ALBTargetGroupRequestContext{}
+	err := r.decodeJSONHeader(req, ALBContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling ALB context")
+		log.Println(err)
+		return events.ALBTargetGroupRequestContext{}, err
+	}
+	return context, nil
+}
+
 // GetAPIGatewayStageVars extracts the API Gateway stage variables from a
 // request's custom header.
 // Returns a map[string]string of the stage variables and their values from
-// the request.
+// the request. The map is decoded once per request and shared by the later
+// calls, it should not be modified. Without the header, the stage variables
+// stored in the request context are returned.
 func (r *RequestAccessor) GetAPIGatewayStageVars(req *http.Request) (map[string]string, error) {
 	stageVars := make(map[string]string)
-	if req.Header.Get(APIGwStageVarsHeader) == "" {
+	if req.Header.Get(r.headerName(APIGwStageVarsHeader)) == "" {
+		if stageVars, ok := GetAPIGatewayStageVarsFromContext(req.Context()); ok {
+			return stageVars, nil
+		}
 		return stageVars, errors.New("No stage vars header in request")
 	}
-	err := json.Unmarshal([]byte(req.Header.Get(APIGwStageVarsHeader)), &stageVars)
+	err := r.decodeJSONHeader(req, APIGwStageVarsHeader, &stageVars)
 	if err != nil {
 		log.Println("Erorr while unmarshalling stage variables")
 		log.Println(err)
@@ -83,8 +172,74 @@ func (r *RequestAccessor) GetAPIGatewayStageVars(req *http.Request) (map[string]
 // framework for routing. This is used when API Gateway is configured with
 // base path mappings in custom domain names.
 func (r *RequestAccessor) StripBasePath(basePath string) string {
+	newBasePath := normalizeBasePath(basePath)
+	r.stripBasePaths = nil
+	if newBasePath != "" {
+		r.stripBasePaths = []string{newBasePath}
+	}
+
+	return newBasePath
+}
+
+// StripBasePaths instructs the RequestAccessor object that any of the given
+// base paths should be removed from the request path before sending it to
+// the framework for routing. This is used when the same function is exposed
+// through several base path mappings. When more than one base path matches,
+// the longest one is removed.
+// Returns the normalized base paths, blank strings are ignored.
+func (r *RequestAccessor) StripBasePaths(basePaths ...string) []string {
+	r.stripBasePaths = nil
+	for _, basePath := range basePaths {
+		if newBasePath := normalizeBasePath(basePath); newBasePath != "" {
+			r.stripBasePaths = append(r.stripBasePaths, newBasePath)
+		}
+	}
+	sort.SliceStable(r.stripBasePaths, func(i, j int) bool {
+		return len(r.stripBasePaths[i]) > len(r.stripBasePaths[j])
+	})
+
+	return r.stripBasePaths
+}
+
+// StripStage instructs the RequestAccessor object to remove the stage of the
+// API Gateway request context from the beginning of the request path, when
+// present. This is used when the API is invoked through the default
+// execute-api endpoint, which includes the stage in the path, and the same
+// routes must also work through custom domain names, which do not.
+func (r *RequestAccessor) StripStage(strip bool) {
+	r.stripStage = strip
+}
+
+// stageRelativePath removes the stage from the beginning of the path if
+// StripStage is enabled. The $default stage of HTTP APIs is never part of
+// the path.
+func (r *RequestAccessor) stageRelativePath(path, stage string) string {
+	if !r.stripStage || stage == "" || stage == "$default" {
+		return path
+	}
+	prefix := "/" + stage
+	if path == prefix {
+		return "/"
+	}
+	if strings.HasPrefix(path, prefix+"/") {
+		return strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// SetTrailingSlash instructs the RequestAccessor object to normalize the
+// trailing slash of the request paths, after the base path is stripped and
+// the rewrite rules are applied. This avoids mismatches between the greedy
+// proxy paths of API Gateway and strict routers, which consider /users and
+// /users/ as different routes. The root path is never changed.
+func (r *RequestAccessor) SetTrailingSlash(ts TrailingSlash) {
+	r.trailingSlash = ts
+}
+
+// normalizeBasePath adds the leading slash and removes the trailing slash
+// of a base path. Returns an empty string for blank base paths.
+func normalizeBasePath(basePath string) string {
 	if strings.Trim(basePath, " ") == "" {
-		r.stripBasePath = ""
 		return ""
 	}
 
@@ -97,84 +252,455 @@ func (r *RequestAccessor) StripBasePath(basePath string) string {
 		newBasePath = newBasePath[:len(newBasePath)-1]
 	}
 
-	r.stripBasePath = newBasePath
-
 	return newBasePath
 }
 
 // ProxyEventToHTTPRequest converts an API Gateway proxy event into an
-// http.Request object.
+// http.Request object. The query string is encoded from the multi value
+// query string parameters if present, or from the single value ones. The
+// host of the request is read from the Host header or the domain name of
+// the request context, and the source IP of the caller is set as the
+// RemoteAddr of the request.
 // Returns the populated request with an additional two custom headers for the
 // stage variables and API Gateway context. To access these properties use
 // the GetAPIGatewayStageVars and GetAPIGatewayContext method of the RequestAccessor
-// object.
+// object. The path parameters are stored in the context of the request and
+// can be read with the GetAPIGatewayPathParams method. The API Gateway context
+// and the stage variables are stored in the request context too, see
+// GetAPIGatewayContextFromContext and GetAPIGatewayStageVarsFromContext, along
+// with the resource template, see GetAPIGatewayResource.
 func (r *RequestAccessor) ProxyEventToHTTPRequest(req events.APIGatewayProxyRequest) (*http.Request, error) {
-	decodedBody := []byte(req.Body)
-	if req.IsBase64Encoded {
-		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
-		if err != nil {
-			return nil, err
+	httpRequest, err := http.NewRequest(
+		eventMethod(req.HTTPMethod),
+		r.requestURL(r.stageRelativePath(req.Path, req.RequestContext.Stage)),
+		nil,
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
+		log.Println(err)
+		return nil, err
+	}
+	httpRequest.URL.RawQuery = encodeQuery(queryValues(req.QueryStringParameters, req.MultiValueQueryStringParameters))
+
+	// the multi value headers, when enabled, contain all the headers of the
+	// request, including those with a single value. The console test events
+	// may set them to null, in which case the single value is used.
+	if len(req.MultiValueHeaders) > 0 {
+		for h, values := range req.MultiValueHeaders {
+			if len(values) == 0 && req.Headers[h] != "" {
+				values = []string{req.Headers[h]}
+			}
+			for _, value := range values {
+				httpRequest.Header.Add(h, value)
+			}
+		}
+	} else {
+		for h := range req.Headers {
+			httpRequest.Header.Add(h, req.Headers[h])
 		}
-		decodedBody = base64Body
 	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, testInvokeValue(req.RequestContext.DomainName))
+	r.setForwarded(httpRequest)
+	r.overrideMethod(httpRequest)
+	if err := r.setBody(httpRequest, req.Body, req.IsBase64Encoded); err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, testInvokeValue(req.RequestContext.Identity.SourceIP))
+	httpRequest = setProto(httpRequest, req.RequestContext.Protocol)
+
+	if err := r.addJSONHeader(httpRequest, APIGwContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+	if err := r.addJSONHeader(httpRequest, APIGwStageVarsHeader, stageVariables(req.StageVariables)); err != nil {
+		return nil, err
+	}
+	httpRequest = withValue(httpRequest, apiGwContextContextKey, req.RequestContext)
+	httpRequest = withValue(httpRequest, stageVarsContextKey, stageVariables(req.StageVariables))
+	httpRequest = withValue(httpRequest, resourceContextKey, req.Resource)
+
+	httpRequest = r.withRequestID(httpRequest, req.RequestContext.RequestID)
+	httpRequest = r.withRequestValues(httpRequest, headerNames(req.Headers, req.MultiValueHeaders))
+	return withPathParams(httpRequest, req.PathParameters), nil
+}
+
+// ProxyEventToHTTPRequestWithContext converts any of the events supported by
+// the RequestAccessor object, or a pointer to one, into an http.Request
+// object attached to the given context. When ctx is the context received by
+// the Lambda handler, the handlers can read the invocation details with
+// lambdacontext.FromContext and stop working when the invocation is
+// cancelled, see SetDeadlineMargin. A json.RawMessage payload is converted with the
+// AnyEventToHTTPRequest method.
+// Returns the request and the function releasing the resources of its
+// context, which must be called once the request is served, like the
+// cancel function of context.WithDeadline. Returns an error for unsupported
+// event types.
+func (r *RequestAccessor) ProxyEventToHTTPRequestWithContext(ctx context.Context, event interface{}) (*http.Request, context.CancelFunc, error) {
+	if ctx == nil {
+		return nil, nil, errors.New("No context for request")
+	}
+	if v := reflect.ValueOf(event); v.Kind() == reflect.Ptr && !v.IsNil() {
+		event = v.Elem().Interface()
+	}
+
+	var httpRequest *http.Request
+	var err error
+	switch v := event.(type) {
+	case events.APIGatewayProxyRequest:
+		httpRequest, err = r.ProxyEventToHTTPRequest(v)
+	case events.APIGatewayV2HTTPRequest:
+		httpRequest, err = r.APIGatewayV2HTTPRequestToHTTPRequest(v)
+	case events.ALBTargetGroupRequest:
+		httpRequest, err = r.ALBTargetGroupRequestToHTTPRequest(v)
+	case events.LambdaFunctionURLRequest:
+		httpRequest, err = r.LambdaFunctionURLRequestToHTTPRequest(v)
+	case events.APIGatewayWebsocketProxyRequest:
+		httpRequest, err = r.WebsocketProxyRequestToHTTPRequest(v)
+	case events.APIGatewayCustomAuthorizerRequestTypeRequest:
+		httpRequest, err = r.CustomAuthorizerRequestToHTTPRequest(v)
+	case CloudFrontEvent:
+		httpRequest, err = r.CloudFrontEventToHTTPRequest(v)
+	case VPCLatticeRequest:
+		httpRequest, err = r.VPCLatticeRequestToHTTPRequest(v)
+	case SwitchableAPIGatewayRequest:
+		return r.SwitchableEventToHTTPRequest(ctx, v)
+	case json.RawMessage:
+		httpRequest, _, err = r.AnyEventToHTTPRequest(v)
+	default:
+		return nil, nil, fmt.Errorf("Unsupported event type %T", event)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := r.withDeadline(ctx)
+	return httpRequest.WithContext(withEventValues(ctx, httpRequest.Context())), cancel, nil
+}
+
+// eventMethod returns the uppercase HTTP method of an event. Events without
+// a method, such as hand-crafted test events, are treated as GET requests.
+func eventMethod(method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		return http.MethodGet
+	}
+	return method
+}
+
+// stageVariables returns the stage variables of an event, or an empty map
+// when the event has none, so that the stage variables header always holds
+// a JSON object.
+func stageVariables(vars map[string]string) map[string]string {
+	if vars == nil {
+		return map[string]string{}
+	}
+	return vars
+}
 
-	queryString := ""
-	if len(req.QueryStringParameters) > 0 {
-		queryString = "?"
-		queryCnt := 0
-		for q := range req.QueryStringParameters {
-			if queryCnt > 0 {
-				queryString += "&"
+// StreamRequestBodies instructs the RequestAccessor object to decode the
+// base64 encoded bodies of the events while the handlers read them, instead
+// of decoding them in memory before routing. This avoids holding both the
+// encoded and the decoded copies of large uploads. The length of the body is
+// computed from the encoded data, and a corrupted body is reported by the
+// Read method of the body rather than by the conversion. Bodies converted by
+// the TranscodeRequestBodies option are still decoded in memory.
+func (r *RequestAccessor) StreamRequestBodies(stream bool) {
+	r.streamBodies = stream
+}
+
+// setBody sets the body of the event, decoded from base64 if needed, as the
+// body of the request along with its length. The headers of the request must
+// be set, the charset of the Content-Type decides whether the body is
+// transcoded.
+func (r *RequestAccessor) setBody(httpRequest *http.Request, body string, isBase64Encoded bool) error {
+	if body == "" {
+		httpRequest.Body = http.NoBody
+		httpRequest.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		setContentLength(httpRequest, 0)
+		return nil
+	}
+
+	if r.streamBodies && isBase64Encoded && !r.transcodes(httpRequest.Header.Get(contentTypeHeaderKey)) && !r.decompresses(httpRequest) {
+		httpRequest.Body = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, strings.NewReader(body)))
+		httpRequest.GetBody = nil
+		setContentLength(httpRequest, decodedLen(body))
+		return nil
+	}
+
+	decodedBody, err := decodeBody(body, isBase64Encoded)
+	if err != nil {
+		return err
+	}
+	if decodedBody, err = r.decompressBody(httpRequest, decodedBody); err != nil {
+		return err
+	}
+	httpRequest.Body = ioutil.NopCloser(bytes.NewReader(decodedBody))
+	httpRequest.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(decodedBody)), nil
+	}
+	if decodedBody, err = r.transcodeBody(httpRequest, decodedBody, isBase64Encoded); err != nil {
+		return err
+	}
+	setContentLength(httpRequest, int64(len(decodedBody)))
+	return nil
+}
+
+// decodedLen returns the number of bytes of a padded base64 string once
+// decoded. Line breaks are ignored, like they are by the decoder.
+func decodedLen(body string) int64 {
+	body = strings.TrimRight(body, "\r\n")
+	n := len(body) - strings.Count(body, "\n") - strings.Count(body, "\r")
+	n = n / 4 * 3
+	if strings.HasSuffix(body, "==") {
+		n -= 2
+	} else if strings.HasSuffix(body, "=") {
+		n--
+	}
+	return int64(n)
+}
+
+// decodeBody returns the raw bytes of an event body
+func decodeBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+	return base64.StdEncoding.DecodeString(body)
+}
+
+// queryValues returns the decoded query string parameters of an API Gateway
+// event. The multi value parameters, when enabled, contain all the
+// parameters of the request and take precedence over the single value ones.
+// Parameters without values, such as ?debug, are kept with an empty value.
+func queryValues(single map[string]string, multi map[string][]string) url.Values {
+	query := url.Values{}
+	if len(multi) > 0 {
+		for q, values := range multi {
+			if len(values) == 0 {
+				query[q] = append(query[q], "")
+			}
+			for _, value := range values {
+				query.Add(q, value)
+			}
+		}
+	} else {
+		for q, value := range single {
+			query.Add(q, value)
+		}
+	}
+	return query
+}
+
+// encodeQuery encodes the query string parameters like url.Values.Encode,
+// sorted by name so the query string of the request does not depend on the
+// order of the event maps, except that parameters with an empty value are
+// encoded as flags: ?debug rather than ?debug=.
+func encodeQuery(query url.Values) string {
+	return joinQuery(query, url.QueryEscape)
+}
+
+// joinQuery joins the query string parameters sorted by name, like
+// encodeQuery, escaping the names and values with the given function.
+func joinQuery(query url.Values, escape func(string) string) string {
+	keys := make([]string, 0, len(query))
+	for q := range query {
+		keys = append(keys, q)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, q := range keys {
+		for _, value := range query[q] {
+			if value == "" {
+				parts = append(parts, escape(q))
+				continue
 			}
-			queryString += url.QueryEscape(q) + "=" + url.QueryEscape(req.QueryStringParameters[q])
-			queryCnt++
+			parts = append(parts, escape(q)+"="+escape(value))
 		}
 	}
+	return strings.Join(parts, "&")
+}
 
-	path := req.Path
-	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
-		if strings.HasPrefix(path, r.stripBasePath) {
-			path = strings.Replace(path, r.stripBasePath, "", 1)
+// requestURL removes the base path from the path of an event, applies the
+// rewrite rules and the trailing slash normalization, and prepends the
+// server address to the escaped path.
+func (r *RequestAccessor) requestURL(path string) string {
+	for _, basePath := range r.stripBasePaths {
+		if len(basePath) > 1 && strings.HasPrefix(path, basePath) {
+			path = strings.Replace(path, basePath, "", 1)
+			break
 		}
 	}
 	if !strings.HasPrefix(path, "/") {
 		path = "/" + path
 	}
-	serverAddress := DefaultServerAddress
-	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
-		serverAddress = customAddress
+	path = r.rewritePath(path)
+	switch {
+	case r.trailingSlash == RemoveTrailingSlash && len(path) > 1:
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	case r.trailingSlash == AddTrailingSlash && !strings.HasSuffix(path, "/"):
+		path += "/"
+	}
+	serverAddress, ok := r.customServerAddress()
+	if !ok {
+		serverAddress = DefaultServerAddress
 	}
 
-	path = serverAddress + path
+	return serverAddress + escapePath(path)
+}
 
-	httpRequest, err := http.NewRequest(
-		strings.ToUpper(req.HTTPMethod),
-		path+queryString,
-		bytes.NewReader(decodedBody),
-	)
+// SetServerAddress instructs the RequestAccessor object to prepend the given
+// address to the path of the requests, instead of the address of the
+// GO_API_HOST environment variable or the host of the event. The address
+// should include a protocol: http://my-custom.host.com. An empty address
+// restores the default behavior.
+func (r *RequestAccessor) SetServerAddress(address string) {
+	r.serverAddress = strings.TrimSpace(address)
+}
 
-	if err != nil {
-		fmt.Printf("Could not convert request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
-		log.Println(err)
-		return nil, err
+// customServerAddress returns the server address set with SetServerAddress
+// or, as a fallback, with the GO_API_HOST environment variable.
+// Returns false if neither is configured.
+func (r *RequestAccessor) customServerAddress() (string, bool) {
+	address := r.serverAddress
+	if address == "" {
+		customAddress, ok := os.LookupEnv(CustomHostVariable)
+		if !ok {
+			return "", false
+		}
+		address = customAddress
 	}
+	return strings.TrimSuffix(normalizeServerAddress(address), "/"), true
+}
 
-	for h := range req.Headers {
-		httpRequest.Header.Add(h, req.Headers[h])
+// escapePath returns the escaped form of an event path. Paths that are
+// already percent-encoded are kept as they are, so that the RawPath of the
+// request preserves escapes such as %2F for the routers that inspect it.
+// Other paths, for example containing a literal % or ?, are escaped.
+func escapePath(path string) string {
+	u := url.URL{Path: path}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		u = url.URL{Path: unescaped, RawPath: path}
 	}
+	return u.EscapedPath()
+}
 
-	apiGwContext, err := json.Marshal(req.RequestContext)
+// setHost sets the Host of the request to the ASCII form of the Host header,
+// or of the domain name of the event when the header is missing, so routing
+// on virtual hosts and internationalized domain names does not depend on the
+// form the client sent. Unless a custom server address is configured, the
+// host of the request URL is set to the same value.
+func (r *RequestAccessor) setHost(httpRequest *http.Request, domainName string) {
+	host := httpRequest.Header.Get("Host")
+	if host == "" {
+		host = domainName
+	}
+	if host == "" {
+		return
+	}
+	normalized, err := NormalizeHost(host)
 	if err != nil {
-		log.Println("Could not Marshal API GW context for custom header")
+		log.Printf("Could not normalize host %s: %v\n", host, err)
+		return
+	}
+	if httpRequest.Header.Get("Host") != "" {
+		httpRequest.Header.Set("Host", normalized)
+	}
+	httpRequest.Host = normalized
+	if _, ok := r.customServerAddress(); !ok {
+		httpRequest.URL.Host = normalized
+	}
+}
+
+// setForwarded applies the X-Forwarded-Proto and X-Forwarded-Port headers set
+// by API Gateway and the load balancers to the scheme and the host of the
+// request URL, unless a custom server address is configured. The port is
+// only added to the host when it is not the default port of the scheme and
+// the host has none.
+// Requests forwarded over https also get an empty TLS connection state, so
+// that handlers checking req.TLS consider them secure.
+func (r *RequestAccessor) setForwarded(httpRequest *http.Request) {
+	_, customHost := r.customServerAddress()
+
+	proto := strings.ToLower(strings.TrimSpace(httpRequest.Header.Get("X-Forwarded-Proto")))
+	if proto == "http" || proto == "https" {
+		if !customHost {
+			httpRequest.URL.Scheme = proto
+		}
+		if proto == "https" {
+			serverName, _ := splitHostPort(httpRequest.Host)
+			httpRequest.TLS = &tls.ConnectionState{ServerName: serverName}
+		}
+	}
+
+	port := strings.TrimSpace(httpRequest.Header.Get("X-Forwarded-Port"))
+	if customHost || port == "" || httpRequest.URL.Port() != "" {
+		return
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return
+	}
+	if (httpRequest.URL.Scheme == "https" && port == "443") || (httpRequest.URL.Scheme == "http" && port == "80") {
+		return
+	}
+	httpRequest.URL.Host = joinHostPort(httpRequest.URL.Host, port)
+}
+
+// setContentLength sets the ContentLength of the request and, when the event
+// did not include one, the Content-Length header to the size of the decoded
+// body. Middlewares limiting or binding the body rely on them.
+func setContentLength(httpRequest *http.Request, length int64) {
+	httpRequest.ContentLength = length
+	if length > 0 && httpRequest.Header.Get("Content-Length") == "" {
+		httpRequest.Header.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+}
+
+// setRemoteAddr sets the RemoteAddr of the request to the source IP of the
+// event. When the event does not contain one, the address is read from the
+// last entry of the X-Forwarded-For header, which is appended by the load
+// balancer and cannot be spoofed by the client.
+func setRemoteAddr(httpRequest *http.Request, sourceIP string) {
+	if sourceIP == "" {
+		forwardedFor := strings.Split(strings.Join(httpRequest.Header.Values("X-Forwarded-For"), ","), ",")
+		sourceIP = strings.TrimSpace(forwardedFor[len(forwardedFor)-1])
+	}
+	if sourceIP != "" {
+		httpRequest.RemoteAddr = remoteAddr(sourceIP)
+	}
+}
+
+// remoteAddr returns the host:port form of the given address expected in
+// the RemoteAddr of requests, such as by net.SplitHostPort. The events do
+// not contain the port of the client, 0 is used instead.
+func remoteAddr(ip string) string {
+	if _, _, err := net.SplitHostPort(ip); err == nil {
+		return ip
+	}
+	return net.JoinHostPort(ip, "0")
+}
+
+// RawEventToHTTPRequest converts the raw JSON payload of an API Gateway proxy
+// event into an http.Request object.
+// The original payload bytes are stored in the context of the returned request
+// and can be read with the GetRawEvent method of the RequestAccessor object.
+func (r *RequestAccessor) RawEventToHTTPRequest(payload json.RawMessage) (*http.Request, error) {
+	event := events.APIGatewayProxyRequest{}
+	if err := unmarshalEvent(payload, &event); err != nil {
+		log.Println("Could not unmarshal raw event")
 		return nil, err
 	}
-	stageVars, err := json.Marshal(req.StageVariables)
+
+	httpRequest, err := r.ProxyEventToHTTPRequest(event)
 	if err != nil {
-		log.Println("Could not marshal stage variables for custom header")
 		return nil, err
 	}
-	httpRequest.Header.Add(APIGwContextHeader, string(apiGwContext))
-	httpRequest.Header.Add(APIGwStageVarsHeader, string(stageVars))
 
-	return httpRequest, nil
+	return withRawEvent(httpRequest, []byte(payload)), nil
 }
@@ -1,12 +1,21 @@
 package core_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/base64"
+	"encoding/json"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 
 	. "github.com/onsi/ginkgo"
@@ -57,6 +66,18 @@ var _ = Describe("RequestAccessor tests", func() {
 			Expect(binaryBody).To(Equal(bodyBytes))
 		})
 
+		It("Sets the content length of the decoded body", func() {
+			httpReq, err := accessor.ProxyEventToHTTPRequest(binaryRequest)
+			Expect(err).To(BeNil())
+			Expect(int64(len(binaryBody))).To(Equal(httpReq.ContentLength))
+			Expect("256").To(Equal(httpReq.Header.Get("Content-Length")))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			Expect(int64(0)).To(Equal(httpReq.ContentLength))
+			Expect(httpReq.Header.Get("Content-Length")).To(BeEmpty())
+		})
+
 		qsRequest := getProxyRequest("/hello", "GET")
 		qsRequest.QueryStringParameters = map[string]string{
 			"hello": "1",
@@ -78,6 +99,58 @@ var _ = Describe("RequestAccessor tests", func() {
 			Expect("2").To(Equal(query["world"][0]))
 		})
 
+		It("Encodes special characters in the query string", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.QueryStringParameters = map[string]string{
+				"plus": "1+1",
+				"semi": "a;b",
+				"name": "Zoë & co",
+				"a=b":  "c",
+			}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("a%3Db=c&name=Zo%C3%AB+%26+co&plus=1%2B1&semi=a%3Bb").To(Equal(httpReq.URL.RawQuery))
+
+			query := httpReq.URL.Query()
+			Expect("1+1").To(Equal(query.Get("plus")))
+			Expect("a;b").To(Equal(query.Get("semi")))
+			Expect("Zoë & co").To(Equal(query.Get("name")))
+			Expect("c").To(Equal(query.Get("a=b")))
+		})
+
+		It("Keeps valueless query parameters", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.QueryStringParameters = map[string]string{"debug": "", "flag": ""}
+			req.MultiValueQueryStringParameters = map[string][]string{"debug": {""}, "flag": nil, "id": {"1"}}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("debug&flag&id=1").To(Equal(httpReq.URL.RawQuery))
+
+			query := httpReq.URL.Query()
+			_, hasDebug := query["debug"]
+			_, hasFlag := query["flag"]
+			Expect(hasDebug).To(BeTrue())
+			Expect(hasFlag).To(BeTrue())
+			Expect("").To(Equal(query.Get("debug")))
+
+			albReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod:            "GET",
+				Path:                  "/hello",
+				QueryStringParameters: map[string]string{"debug": ""},
+			})
+			Expect(err).To(BeNil())
+			Expect("debug").To(Equal(albReq.URL.RawQuery))
+		})
+
+		It("Prefers the multi value query string parameters", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.QueryStringParameters = map[string]string{"id": "2"}
+			req.MultiValueQueryStringParameters = map[string][]string{"id": {"1", "2"}}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect([]string{"1", "2"}).To(Equal(httpReq.URL.Query()["id"]))
+		})
+
 		basePathRequest := getProxyRequest("/app1/orders", "GET")
 
 		It("Stips the base path correct", func() {
@@ -93,121 +166,1837 @@ var _ = Describe("RequestAccessor tests", func() {
 		It("Populates context header correctly", func() {
 			httpReq, err := accessor.ProxyEventToHTTPRequest(contextRequest)
 			Expect(err).To(BeNil())
-			Expect(2).To(Equal(len(httpReq.Header)))
+			Expect(3).To(Equal(len(httpReq.Header)))
 			Expect(httpReq.Header.Get(core.APIGwContextHeader)).ToNot(BeNil())
+			Expect("x").To(Equal(httpReq.Header.Get(core.RequestIDHeader)))
 		})
 	})
 
-	Context("StripBasePath tests", func() {
-		accessor := core.RequestAccessor{}
-		It("Adds prefix slash", func() {
-			basePath := accessor.StripBasePath("app1")
-			Expect("/app1").To(Equal(basePath))
+	Context("Multi value headers", func() {
+		It("Prefers the multi value headers when they are set", func() {
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "GET")
+			proxyReq.Headers = map[string]string{"Accept": "application/json", "X-Single": "1"}
+			proxyReq.MultiValueHeaders = map[string][]string{
+				"Accept":   {"text/html", "application/json"},
+				"Cookie":   {"a=1", "b=2"},
+				"X-Single": {"1"},
+			}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect([]string{"text/html", "application/json"}).To(Equal(httpReq.Header["Accept"]))
+			Expect([]string{"1"}).To(Equal(httpReq.Header["X-Single"]))
+			Expect(2).To(Equal(len(httpReq.Cookies())))
 		})
 
-		It("Removes trailing slash", func() {
-			basePath := accessor.StripBasePath("/app1/")
-			Expect("/app1").To(Equal(basePath))
+		It("Falls back to the single value headers", func() {
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "GET")
+			proxyReq.Headers = map[string]string{"Accept": "application/json"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect([]string{"application/json"}).To(Equal(httpReq.Header["Accept"]))
 		})
+	})
 
-		It("Ignores blank strings", func() {
-			basePath := accessor.StripBasePath("  ")
-			Expect("").To(Equal(basePath))
+	Context("Raw event conversion", func() {
+		It("Stores the original payload in the request context", func() {
+			payload := []byte(`{"path":"/hello","httpMethod":"POST","body":"hi"}`)
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.RawEventToHTTPRequest(payload)
+			Expect(err).To(BeNil())
+			Expect("/hello").To(Equal(httpReq.URL.Path))
+			Expect("POST").To(Equal(httpReq.Method))
+
+			rawEvent, err := accessor.GetRawEvent(httpReq)
+			Expect(err).To(BeNil())
+			Expect(payload).To(Equal(rawEvent))
+
+			ctxEvent, ok := core.RawEventFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal(ctxEvent))
+		})
+
+		It("Returns an error for invalid payloads", func() {
+			accessor := core.RequestAccessor{}
+			_, err := accessor.RawEventToHTTPRequest([]byte(`{"path":`))
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Returns an error when the request has no raw event", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			_, err = accessor.GetRawEvent(httpReq)
+			Expect(err).ToNot(BeNil())
 		})
 	})
 
-	Context("Retrieves API Gateway context", func() {
-		It("Returns a correctly unmarshalled object", func() {
-			contextRequest := getProxyRequest("orders", "GET")
-			contextRequest.RequestContext = getRequestContext()
+	Context("ALB event conversion", func() {
+		It("Converts single value headers and query strings", func() {
+			accessor := core.RequestAccessor{}
+			accessor.StripBasePath("app")
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod:            "post",
+				Path:                  "/app/hello",
+				QueryStringParameters: map[string]string{"name": "a%20b"},
+				Headers:               map[string]string{"X-Custom": "value"},
+				Body:                  base64.StdEncoding.EncodeToString([]byte("body")),
+				IsBase64Encoded:       true,
+			})
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+			Expect("/hello").To(Equal(httpReq.URL.Path))
+			Expect("a b").To(Equal(httpReq.URL.Query().Get("name")))
+			Expect("value").To(Equal(httpReq.Header.Get("X-Custom")))
+
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("body").To(Equal(string(body)))
+		})
 
+		It("Stores the load balancer context in a custom header", func() {
 			accessor := core.RequestAccessor{}
-			httpReq, err := accessor.ProxyEventToHTTPRequest(contextRequest)
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod: "GET",
+				Path:       "/hello",
+				RequestContext: events.ALBTargetGroupRequestContext{
+					ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:tg"},
+				},
+			})
 			Expect(err).To(BeNil())
+			Expect(httpReq.Header.Get(core.ALBContextHeader)).ToNot(Equal(""))
 
-			context, err := accessor.GetAPIGatewayContext(httpReq)
+			albContext, err := accessor.GetALBTargetGroupRequestContext(httpReq)
 			Expect(err).To(BeNil())
-			Expect(context).ToNot(BeNil())
-			Expect("x").To(Equal(context.AccountID))
-			Expect("x").To(Equal(context.RequestID))
-			Expect("x").To(Equal(context.APIID))
-			Expect("prod").To(Equal(context.Stage))
+			Expect("arn:aws:elasticloadbalancing:tg").To(Equal(albContext.ELB.TargetGroupArn))
+
+			httpReq.Header.Del(core.ALBContextHeader)
+			_, err = accessor.GetALBTargetGroupRequestContext(httpReq)
+			Expect(err).ToNot(BeNil())
 		})
 
-		It("Populates stage variables correctly", func() {
-			varsRequest := getProxyRequest("orders", "GET")
-			varsRequest.StageVariables = getStageVariables()
+		It("Prefers multi value headers and query strings", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod:                      "GET",
+				Path:                            "/hello",
+				QueryStringParameters:           map[string]string{"id": "3"},
+				MultiValueQueryStringParameters: map[string][]string{"id": {"1", "2"}},
+				Headers:                         map[string]string{"Accept": "text/plain"},
+				MultiValueHeaders:               map[string][]string{"Accept": {"text/html", "application/json"}},
+			})
+			Expect(err).To(BeNil())
+			Expect([]string{"1", "2"}).To(Equal(httpReq.URL.Query()["id"]))
+			Expect([]string{"text/html", "application/json"}).To(Equal(httpReq.Header["Accept"]))
+		})
 
+		It("Sorts the raw query string parameters", func() {
 			accessor := core.RequestAccessor{}
-			httpReq, err := accessor.ProxyEventToHTTPRequest(varsRequest)
+			for i := 0; i < 10; i++ {
+				httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+					HTTPMethod:            "GET",
+					Path:                  "/hello",
+					QueryStringParameters: map[string]string{"z": "1", "b": "a%20b", "m": "", "a": "2"},
+				})
+				Expect(err).To(BeNil())
+				Expect(httpReq.URL.RawQuery).To(Equal("a=2&b=a%20b&m&z=1"))
+			}
+		})
+	})
+
+	Context("HTTP API v2 event conversion", func() {
+		It("Uses the raw path, raw query string and cookies", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RouteKey:       "POST /orders/{id}",
+				RawPath:        "/orders/a%2Fb",
+				RawQueryString: "tag=1&tag=2",
+				Cookies:        []string{"a=1", "b=2"},
+				Headers:        map[string]string{"accept": "text/plain,application/json"},
+				Body:           "order",
+				StageVariables: getStageVariables(),
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					RouteKey: "POST /orders/{id}",
+					Stage:    "$default",
+					HTTP:     events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "post"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+			Expect("/orders/a%2Fb").To(Equal(httpReq.URL.EscapedPath()))
+			Expect([]string{"1", "2"}).To(Equal(httpReq.URL.Query()["tag"]))
+			Expect("a=1; b=2").To(Equal(httpReq.Header.Get("Cookie")))
+			Expect("text/plain,application/json").To(Equal(httpReq.Header.Get("Accept")))
+
+			v2Context, err := accessor.GetAPIGatewayV2Context(httpReq)
 			Expect(err).To(BeNil())
+			Expect("POST /orders/{id}").To(Equal(v2Context.RouteKey))
 
 			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
 			Expect(err).To(BeNil())
-			Expect(2).To(Equal(len(stageVars)))
-			Expect(stageVars["var1"]).ToNot(BeNil())
-			Expect(stageVars["var2"]).ToNot(BeNil())
 			Expect("value1").To(Equal(stageVars["var1"]))
-			Expect("value2").To(Equal(stageVars["var2"]))
+
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("order").To(Equal(string(body)))
 		})
 
-		It("Populates the default hostname correctly", func() {
-			basicRequest := getProxyRequest("orders", "GET")
+		It("Preserves the raw query string byte for byte", func() {
 			accessor := core.RequestAccessor{}
-			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			rawQuery := "z=%7e&a=2&a=1&flag&sig=a+b%2Bc%23d#frag"
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath:        "/hello",
+				RawQueryString: rawQuery,
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
 			Expect(err).To(BeNil())
+			Expect(rawQuery).To(Equal(httpReq.URL.RawQuery))
+			Expect([]string{"2", "1"}).To(Equal(httpReq.URL.Query()["a"]))
+		})
 
-			Expect(core.DefaultServerAddress).To(Equal("https://" + httpReq.Host))
-			Expect(core.DefaultServerAddress).To(Equal("https://" + httpReq.URL.Host))
+		It("Rebuilds the Cookie header from the cookies field", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath: "/hello",
+				Cookies: []string{"session=abc", "theme=dark"},
+				Headers: map[string]string{"cookie": "legacy=1"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(1).To(Equal(len(httpReq.Header["Cookie"])))
+			Expect(3).To(Equal(len(httpReq.Cookies())))
+
+			session, err := httpReq.Cookie("session")
+			Expect(err).To(BeNil())
+			Expect("abc").To(Equal(session.Value))
+			legacy, err := httpReq.Cookie("legacy")
+			Expect(err).To(BeNil())
+			Expect("1").To(Equal(legacy.Value))
 		})
 
-		It("Uses a custom hostname", func() {
-			myCustomHost := "http://my-custom-host.com"
-			os.Setenv(core.CustomHostVariable, myCustomHost)
-			basicRequest := getProxyRequest("orders", "GET")
+		It("Returns an error when the request has no v2 context", func() {
 			accessor := core.RequestAccessor{}
-			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
 			Expect(err).To(BeNil())
+			_, err = accessor.GetAPIGatewayV2Context(httpReq)
+			Expect(err).ToNot(BeNil())
+		})
+	})
 
-			Expect(myCustomHost).To(Equal("http://" + httpReq.Host))
-			Expect(myCustomHost).To(Equal("http://" + httpReq.URL.Host))
-			os.Unsetenv(core.CustomHostVariable)
+	Context("WebSocket event conversion", func() {
+		It("Maps the route key, connection id and event type", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.WebsocketProxyRequestToHTTPRequest(events.APIGatewayWebsocketProxyRequest{
+				Body: "hello",
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					RouteKey:     "$default",
+					EventType:    "MESSAGE",
+					ConnectionID: "conn-1",
+					Stage:        "prod",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+			Expect("/$default").To(Equal(httpReq.URL.Path))
+			Expect("conn-1").To(Equal(httpReq.Header.Get(core.WebsocketConnectionIDHeader)))
+			Expect("MESSAGE").To(Equal(httpReq.Header.Get(core.WebsocketEventTypeHeader)))
+			Expect("$default").To(Equal(httpReq.Header.Get(core.WebsocketRouteKeyHeader)))
+
+			websocketContext, err := accessor.GetAPIGatewayWebsocketContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("prod").To(Equal(websocketContext.Stage))
+
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("hello").To(Equal(string(body)))
 		})
+	})
+
+	Context("CloudFront event conversion", func() {
+		It("Converts the request of the first record", func() {
+			event := core.CloudFrontEvent{}
+			err := json.Unmarshal([]byte(`{"Records":[{"cf":{
+				"config":{"distributionId":"EDFDVBD6EXAMPLE","eventType":"origin-request","requestId":"4TyzHTaYWb1GX1qTfsHhEqV6HUDd_BzoBZnwfnvQc_1oF26ClkoUSEQ=="},
+				"request":{"clientIp":"203.0.113.178","method":"POST","uri":"/form","querystring":"a=1",
+					"headers":{"content-type":[{"key":"Content-Type","value":"text/plain"}]},
+					"body":{"inputTruncated":false,"action":"read-only","encoding":"base64","data":"aGVsbG8="}}}}]}`), &event)
+			Expect(err).To(BeNil())
 
-		It("Strips terminating / from hostname", func() {
-			myCustomHost := "http://my-custom-host.com"
-			os.Setenv(core.CustomHostVariable, myCustomHost+"/")
-			basicRequest := getProxyRequest("orders", "GET")
 			accessor := core.RequestAccessor{}
-			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			httpReq, err := accessor.CloudFrontEventToHTTPRequest(event)
 			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+			Expect("/form").To(Equal(httpReq.URL.Path))
+			Expect("1").To(Equal(httpReq.URL.Query().Get("a")))
+			Expect("text/plain").To(Equal(httpReq.Header.Get("Content-Type")))
 
-			Expect(myCustomHost).To(Equal("http://" + httpReq.Host))
-			Expect(myCustomHost).To(Equal("http://" + httpReq.URL.Host))
-			os.Unsetenv(core.CustomHostVariable)
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("hello").To(Equal(string(body)))
+
+			config, err := accessor.GetCloudFrontConfig(httpReq)
+			Expect(err).To(BeNil())
+			Expect("origin-request").To(Equal(config.EventType))
+		})
+
+		It("Returns an error for events without records", func() {
+			accessor := core.RequestAccessor{}
+			_, err := accessor.CloudFrontEventToHTTPRequest(core.CloudFrontEvent{})
+			Expect(err).ToNot(BeNil())
 		})
 	})
-})
 
-func getProxyRequest(path string, method string) events.APIGatewayProxyRequest {
-	return events.APIGatewayProxyRequest{
-		Path:       path,
-		HTTPMethod: method,
-	}
-}
+	Context("Event type detection", func() {
+		restEvent := []byte(`{"resource":"/{proxy+}","path":"/hello","httpMethod":"GET","requestContext":{"stage":"prod"}}`)
+		v2Event := []byte(`{"version":"2.0","routeKey":"GET /hello","rawPath":"/hello","requestContext":{"domainName":"id.execute-api.us-east-1.amazonaws.com","http":{"method":"GET"}}}`)
+		albEvent := []byte(`{"httpMethod":"GET","path":"/hello","requestContext":{"elb":{"targetGroupArn":"arn"}}}`)
+		urlEvent := []byte(`{"version":"2.0","rawPath":"/hello","requestContext":{"domainName":"abc.lambda-url.us-east-1.on.aws","http":{"method":"GET"}}}`)
 
-func getRequestContext() events.APIGatewayProxyRequestContext {
-	return events.APIGatewayProxyRequestContext{
-		AccountID: "x",
-		RequestID: "x",
-		APIID:     "x",
-		Stage:     "prod",
-	}
-}
+		It("Detects the supported events", func() {
+			Expect(core.APIGatewayProxyEvent).To(Equal(core.DetectEventType(restEvent)))
+			Expect(core.APIGatewayV2HTTPEvent).To(Equal(core.DetectEventType(v2Event)))
+			Expect(core.ALBTargetGroupEvent).To(Equal(core.DetectEventType(albEvent)))
+			Expect(core.LambdaFunctionURLEvent).To(Equal(core.DetectEventType(urlEvent)))
+			Expect(core.UnknownEvent).To(Equal(core.DetectEventType([]byte(`{"Records":[]}`))))
+			Expect(core.UnknownEvent).To(Equal(core.DetectEventType([]byte(`[`))))
+		})
 
-func getStageVariables() map[string]string {
-	return map[string]string{
-		"var1": "value1",
-		"var2": "value2",
-	}
+		It("Converts the event and stores its type", func() {
+			accessor := core.RequestAccessor{}
+			for _, payload := range [][]byte{restEvent, v2Event, albEvent, urlEvent} {
+				httpReq, eventType, err := accessor.AnyEventToHTTPRequest(payload)
+				Expect(err).To(BeNil())
+				Expect("/hello").To(Equal(httpReq.URL.Path))
+				Expect("GET").To(Equal(httpReq.Method))
+
+				ctxType, ok := core.EventTypeFromContext(httpReq.Context())
+				Expect(ok).To(BeTrue())
+				Expect(eventType).To(Equal(ctxType))
+				rawEvent, err := accessor.GetRawEvent(httpReq)
+				Expect(err).To(BeNil())
+				Expect(payload).To(Equal(rawEvent))
+			}
+
+			_, _, err := accessor.AnyEventToHTTPRequest([]byte(`{}`))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Switchable request conversion", func() {
+		It("Unmarshals both payload formats", func() {
+			v1 := core.SwitchableAPIGatewayRequest{}
+			Expect(json.Unmarshal([]byte(`{"path":"/v1","httpMethod":"GET"}`), &v1)).To(BeNil())
+			Expect(v1.Version1()).ToNot(BeNil())
+			Expect(v1.Version2()).To(BeNil())
+
+			v2 := core.SwitchableAPIGatewayRequest{}
+			Expect(json.Unmarshal([]byte(`{"version":"2.0","rawPath":"/v2","requestContext":{"http":{"method":"GET"}}}`), &v2)).To(BeNil())
+			Expect(v2.Version1()).To(BeNil())
+			Expect("/v2").To(Equal(v2.Version2().RawPath))
+
+			marshalled, err := json.Marshal(v1)
+			Expect(err).To(BeNil())
+			Expect(string(marshalled)).To(ContainSubstring(`"path":"/v1"`))
+
+			invalid := core.SwitchableAPIGatewayRequest{}
+			Expect(json.Unmarshal([]byte(`{"Records":[]}`), &invalid)).ToNot(BeNil())
+		})
+
+		It("Converts the held event with the given context", func() {
+			accessor := core.RequestAccessor{}
+			req := core.NewSwitchableAPIGatewayRequestV2(&events.APIGatewayV2HTTPRequest{
+				RawPath:        "/v2",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"}},
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			httpReq, _, err := accessor.SwitchableEventToHTTPRequest(ctx, *req)
+			Expect(err).To(BeNil())
+			Expect("/v2").To(Equal(httpReq.URL.Path))
+			cancel()
+			Expect(httpReq.Context().Err()).ToNot(BeNil())
+
+			_, _, err = accessor.SwitchableEventToHTTPRequest(ctx, core.SwitchableAPIGatewayRequest{})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Host normalization", func() {
+		It("Punycode encodes internationalized Host headers", func() {
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "GET")
+			proxyReq.Headers = map[string]string{"Host": "Bücher.example:8443"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect("xn--bcher-kva.example:8443").To(Equal(httpReq.Host))
+			Expect("xn--bcher-kva.example:8443").To(Equal(httpReq.Header.Get("Host")))
+		})
+
+		It("Uses the Host header or the domain name as the request host", func() {
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "GET")
+			proxyReq.Headers = map[string]string{"Host": "api.example.com"}
+			proxyReq.RequestContext.DomainName = "abc123.execute-api.us-east-1.amazonaws.com"
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect("api.example.com").To(Equal(httpReq.Host))
+			Expect("api.example.com").To(Equal(httpReq.URL.Host))
+
+			proxyReq.Headers = nil
+			httpReq, err = accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect("abc123.execute-api.us-east-1.amazonaws.com").To(Equal(httpReq.Host))
+			Expect("abc123.execute-api.us-east-1.amazonaws.com").To(Equal(httpReq.URL.Host))
+			Expect(httpReq.Header.Get("Host")).To(BeEmpty())
+		})
+
+		It("Keeps the URL host of the custom server address", func() {
+			os.Setenv(core.CustomHostVariable, "https://custom.example")
+			defer os.Unsetenv(core.CustomHostVariable)
+
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "GET")
+			proxyReq.Headers = map[string]string{"Host": "api.example.com"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect("api.example.com").To(Equal(httpReq.Host))
+			Expect("custom.example").To(Equal(httpReq.URL.Host))
+		})
+
+		It("Punycode encodes the custom server address", func() {
+			os.Setenv(core.CustomHostVariable, "https://bücher.example")
+			defer os.Unsetenv(core.CustomHostVariable)
+
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			Expect("xn--bcher-kva.example").To(Equal(httpReq.URL.Host))
+		})
+
+		It("Converts hosts between the ASCII and Unicode forms", func() {
+			ascii, err := core.NormalizeHost("BÜCHER.example")
+			Expect(err).To(BeNil())
+			Expect("xn--bcher-kva.example").To(Equal(ascii))
+
+			unicode, err := core.UnicodeHost(ascii + ":443")
+			Expect(err).To(BeNil())
+			Expect("bücher.example:443").To(Equal(unicode))
+
+			ip, err := core.NormalizeHost("[::1]:8080")
+			Expect(err).To(BeNil())
+			Expect("[::1]:8080").To(Equal(ip))
+		})
+	})
+
+	Context("Charset aware body decoding", func() {
+		It("Decodes ISO-8859-1 request bodies", func() {
+			accessor := core.RequestAccessor{}
+			proxyReq := getProxyRequest("/hello", "POST")
+			proxyReq.Headers = map[string]string{"Content-Type": "text/plain; charset=ISO-8859-1"}
+			proxyReq.Body = base64.StdEncoding.EncodeToString([]byte{'c', 'a', 'f', 0xe9})
+			proxyReq.IsBase64Encoded = true
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+
+			body, err := core.ReadBodyString(httpReq)
+			Expect(err).To(BeNil())
+			Expect("caf\u00e9").To(Equal(body))
+
+			raw, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect([]byte{'c', 'a', 'f', 0xe9}).To(Equal(raw))
+		})
+
+		It("Round trips UTF-16 bodies", func() {
+			encoded, err := core.EncodeBody("application/json; charset=utf-16le", "{}")
+			Expect(err).To(BeNil())
+			Expect([]byte{'{', 0, '}', 0}).To(Equal(encoded))
+
+			decoded, err := core.DecodeBody("application/json; charset=utf-16le", encoded)
+			Expect(err).To(BeNil())
+			Expect("{}").To(Equal(decoded))
+		})
+
+		It("Transcodes request bodies to UTF-8 when enabled", func() {
+			accessor := core.RequestAccessor{}
+			accessor.TranscodeRequestBodies(true)
+			proxyReq := getProxyRequest("/hello", "POST")
+			proxyReq.Headers = map[string]string{"Content-Type": "text/plain; charset=ISO-8859-1", "Content-Length": "4"}
+			proxyReq.Body = base64.StdEncoding.EncodeToString([]byte{'c', 'a', 'f', 0xe9})
+			proxyReq.IsBase64Encoded = true
+			httpReq, err := accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			Expect("text/plain; charset=utf-8").To(Equal(httpReq.Header.Get("Content-Type")))
+			Expect("5").To(Equal(httpReq.Header.Get("Content-Length")))
+			Expect(int64(5)).To(Equal(httpReq.ContentLength))
+
+			raw, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("caf\u00e9").To(Equal(string(raw)))
+
+			proxyReq.Body = "caf\u00e9"
+			proxyReq.IsBase64Encoded = false
+			httpReq, err = accessor.ProxyEventToHTTPRequest(proxyReq)
+			Expect(err).To(BeNil())
+			raw, err = ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect("caf\u00e9").To(Equal(string(raw)))
+			Expect("text/plain; charset=utf-8").To(Equal(httpReq.Header.Get("Content-Type")))
+		})
+
+		It("Returns an error for unknown charsets", func() {
+			_, err := core.DecodeBody("text/plain; charset=x-unknown", []byte("hi"))
+			Expect(err).ToNot(BeNil())
+			Expect("").To(Equal(core.Charset("text/plain")))
+		})
+	})
+
+	Context("StripBasePath tests", func() {
+		accessor := core.RequestAccessor{}
+		It("Adds prefix slash", func() {
+			basePath := accessor.StripBasePath("app1")
+			Expect("/app1").To(Equal(basePath))
+		})
+
+		It("Removes trailing slash", func() {
+			basePath := accessor.StripBasePath("/app1/")
+			Expect("/app1").To(Equal(basePath))
+		})
+
+		It("Ignores blank strings", func() {
+			basePath := accessor.StripBasePath("  ")
+			Expect("").To(Equal(basePath))
+		})
+
+		It("Strips the longest of multiple base paths", func() {
+			accessor := core.RequestAccessor{}
+			basePaths := accessor.StripBasePaths("v1", "/v1/admin/", " ", "shop")
+			Expect([]string{"/v1/admin", "/shop", "/v1"}).To(Equal(basePaths))
+
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/v1/admin/users", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/shop/orders", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/orders").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/other/orders", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/other/orders").To(Equal(httpReq.URL.Path))
+		})
+	})
+
+	Context("Stage stripping", func() {
+		It("Removes the stage from the path when enabled", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/prod/orders", "GET")
+			req.RequestContext.Stage = "prod"
+
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("/prod/orders").To(Equal(httpReq.URL.Path))
+
+			accessor.StripStage(true)
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("/orders").To(Equal(httpReq.URL.Path))
+
+			req.Path = "/production/orders"
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("/production/orders").To(Equal(httpReq.URL.Path))
+
+			v2Req, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath: "/prod",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					Stage: "prod",
+					HTTP:  events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("/").To(Equal(v2Req.URL.Path))
+		})
+	})
+
+	Context("Encoded paths", func() {
+		accessor := core.RequestAccessor{}
+
+		It("Preserves encoded slashes in the raw path", func() {
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath: "/files/a%2Fb.txt",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("/files/a/b.txt").To(Equal(httpReq.URL.Path))
+			Expect("/files/a%2Fb.txt").To(Equal(httpReq.URL.RawPath))
+			Expect("/files/a%2Fb.txt").To(Equal(httpReq.URL.EscapedPath()))
+		})
+
+		It("Escapes decoded paths", func() {
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/discount/100%", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/discount/100%").To(Equal(httpReq.URL.Path))
+			Expect("/discount/100%25").To(Equal(httpReq.URL.EscapedPath()))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/what?/a b", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/what?/a b").To(Equal(httpReq.URL.Path))
+			Expect(httpReq.URL.RawQuery).To(BeEmpty())
+		})
+	})
+
+	Context("Trailing slash normalization", func() {
+		It("Keeps the trailing slashes by default", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/users/", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users/").To(Equal(httpReq.URL.Path))
+		})
+
+		It("Removes trailing slashes", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SetTrailingSlash(core.RemoveTrailingSlash)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/users//", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/").To(Equal(httpReq.URL.Path))
+		})
+
+		It("Adds trailing slashes", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SetTrailingSlash(core.AddTrailingSlash)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/users", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users/").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/users/", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users/").To(Equal(httpReq.URL.Path))
+		})
+	})
+
+	Context("Path rewrites", func() {
+		It("Applies the first matching rule after stripping the base path", func() {
+			accessor := core.RequestAccessor{}
+			accessor.StripBasePath("api")
+			accessor.RewritePrefix("/v1/items", "/items")
+			Expect(accessor.RewriteRegexp(`^/users/(\d+)/profile$`, "/profiles/$1")).To(BeNil())
+			accessor.RewritePrefix("/users", "/members")
+
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/api/v1/items/42", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/items/42").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/api/users/7/profile", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/profiles/7").To(Equal(httpReq.URL.Path))
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/api/users/7", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/members/7").To(Equal(httpReq.URL.Path))
+
+			accessor.ClearRewrites()
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/api/users/7", "GET"))
+			Expect(err).To(BeNil())
+			Expect("/users/7").To(Equal(httpReq.URL.Path))
+		})
+
+		It("Refuses invalid regular expressions", func() {
+			accessor := core.RequestAccessor{}
+			Expect(accessor.RewriteRegexp("(", "/")).ToNot(BeNil())
+		})
+	})
+
+	Context("Path parameters", func() {
+		It("Stores the path parameters in the request context", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/orders/42", "GET")
+			req.PathParameters = map[string]string{"id": "42"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+
+			pathParams, err := accessor.GetAPIGatewayPathParams(httpReq)
+			Expect(err).To(BeNil())
+			Expect(map[string]string{"id": "42"}).To(Equal(pathParams))
+
+			httpReq, _, err = accessor.SwitchableEventToHTTPRequest(context.Background(), *core.NewSwitchableAPIGatewayRequestV2(&events.APIGatewayV2HTTPRequest{
+				RawPath:        "/orders/7",
+				PathParameters: map[string]string{"id": "7"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			}))
+			Expect(err).To(BeNil())
+			pathParams, ok := core.PathParamsFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("7").To(Equal(pathParams["id"]))
+		})
+
+		It("Returns an empty map for requests without parameters", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/orders", "GET"))
+			Expect(err).To(BeNil())
+			pathParams, err := accessor.GetAPIGatewayPathParams(httpReq)
+			Expect(err).To(BeNil())
+			Expect(pathParams).To(BeEmpty())
+		})
+	})
+
+	Context("Retrieves API Gateway context", func() {
+		It("Returns a correctly unmarshalled object", func() {
+			contextRequest := getProxyRequest("orders", "GET")
+			contextRequest.RequestContext = getRequestContext()
+
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(contextRequest)
+			Expect(err).To(BeNil())
+
+			context, err := accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect(context).ToNot(BeNil())
+			Expect("x").To(Equal(context.AccountID))
+			Expect("x").To(Equal(context.RequestID))
+			Expect("x").To(Equal(context.APIID))
+			Expect("prod").To(Equal(context.Stage))
+		})
+
+		It("Populates stage variables correctly", func() {
+			varsRequest := getProxyRequest("orders", "GET")
+			varsRequest.StageVariables = getStageVariables()
+
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(varsRequest)
+			Expect(err).To(BeNil())
+
+			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect(2).To(Equal(len(stageVars)))
+			Expect(stageVars["var1"]).ToNot(BeNil())
+			Expect(stageVars["var2"]).ToNot(BeNil())
+			Expect("value1").To(Equal(stageVars["var1"]))
+			Expect("value2").To(Equal(stageVars["var2"]))
+		})
+
+		It("Populates the default hostname correctly", func() {
+			basicRequest := getProxyRequest("orders", "GET")
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			Expect(err).To(BeNil())
+
+			Expect(core.DefaultServerAddress).To(Equal("https://" + httpReq.Host))
+			Expect(core.DefaultServerAddress).To(Equal("https://" + httpReq.URL.Host))
+		})
+
+		It("Uses a custom hostname", func() {
+			myCustomHost := "http://my-custom-host.com"
+			os.Setenv(core.CustomHostVariable, myCustomHost)
+			basicRequest := getProxyRequest("orders", "GET")
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			Expect(err).To(BeNil())
+
+			Expect(myCustomHost).To(Equal("http://" + httpReq.Host))
+			Expect(myCustomHost).To(Equal("http://" + httpReq.URL.Host))
+			os.Unsetenv(core.CustomHostVariable)
+		})
+
+		It("Uses the server address of the accessor", func() {
+			os.Setenv(core.CustomHostVariable, "http://my-custom-host.com")
+			defer os.Unsetenv(core.CustomHostVariable)
+
+			accessor := core.RequestAccessor{}
+			accessor.SetServerAddress("https://api.example.com/")
+			req := getProxyRequest("orders", "GET")
+			req.Headers = map[string]string{"Host": "other.example.com", "X-Forwarded-Port": "8443"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("https://api.example.com/orders").To(Equal(httpReq.URL.String()))
+			Expect("other.example.com").To(Equal(httpReq.Host))
+
+			accessor.SetServerAddress("")
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("orders", "GET"))
+			Expect(err).To(BeNil())
+			Expect("http://my-custom-host.com/orders").To(Equal(httpReq.URL.String()))
+		})
+
+		It("Strips terminating / from hostname", func() {
+			myCustomHost := "http://my-custom-host.com"
+			os.Setenv(core.CustomHostVariable, myCustomHost+"/")
+			basicRequest := getProxyRequest("orders", "GET")
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(basicRequest)
+			Expect(err).To(BeNil())
+
+			Expect(myCustomHost).To(Equal("http://" + httpReq.Host))
+			Expect(myCustomHost).To(Equal("http://" + httpReq.URL.Host))
+			os.Unsetenv(core.CustomHostVariable)
+		})
+	})
+
+	Context("Custom authorizer events", func() {
+		It("Converts REQUEST authorizer events", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.CustomAuthorizerRequestToHTTPRequest(events.APIGatewayCustomAuthorizerRequestTypeRequest{
+				Type:                  "REQUEST",
+				MethodArn:             "arn:aws:execute-api:us-east-1:123456789012:abc/prod/GET/orders",
+				Path:                  "/orders",
+				HTTPMethod:            "get",
+				Headers:               map[string]string{"Authorization": "Bearer token"},
+				QueryStringParameters: map[string]string{"q": "a b"},
+				RequestContext: events.APIGatewayCustomAuthorizerRequestTypeRequestContext{
+					Stage: "prod",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("GET").To(Equal(httpReq.Method))
+			Expect("/orders").To(Equal(httpReq.URL.Path))
+			Expect("a b").To(Equal(httpReq.URL.Query().Get("q")))
+			Expect("Bearer token").To(Equal(httpReq.Header.Get("Authorization")))
+			Expect("arn:aws:execute-api:us-east-1:123456789012:abc/prod/GET/orders").To(Equal(httpReq.Header.Get(core.AuthorizerMethodArnHeader)))
+			Expect(httpReq.Body).ToNot(BeNil())
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect(0).To(Equal(len(body)))
+
+			context, err := accessor.GetCustomAuthorizerContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("prod").To(Equal(context.Stage))
+		})
+	})
+
+	Context("Remote address", func() {
+		accessor := core.RequestAccessor{}
+
+		It("Uses the source IP of API Gateway events", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.Headers = map[string]string{"X-Forwarded-For": "10.0.0.1, 198.51.100.1"}
+			req.RequestContext.Identity.SourceIP = "203.0.113.5"
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("203.0.113.5:0").To(Equal(httpReq.RemoteAddr))
+
+			v2Req, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath: "/hello",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", SourceIP: "2001:db8::1"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("[2001:db8::1]:0").To(Equal(v2Req.RemoteAddr))
+		})
+
+		It("Uses the last X-Forwarded-For entry of ALB events", func() {
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod: "GET",
+				Path:       "/hello",
+				Headers:    map[string]string{"X-Forwarded-For": "10.0.0.1, 198.51.100.7"},
+			})
+			Expect(err).To(BeNil())
+			Expect("198.51.100.7:0").To(Equal(httpReq.RemoteAddr))
+		})
+	})
+
+	Context("Forwarded headers", func() {
+		accessor := core.RequestAccessor{}
+
+		It("Uses X-Forwarded-Proto as the URL scheme", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.Headers = map[string]string{"Host": "api.example.com", "X-Forwarded-Proto": "http"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("http").To(Equal(httpReq.URL.Scheme))
+			Expect(httpReq.TLS).To(BeNil())
+
+			albReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{
+				HTTPMethod: "GET",
+				Path:       "/hello",
+				Headers:    map[string]string{"Host": "api.example.com", "X-Forwarded-Proto": "https"},
+			})
+			Expect(err).To(BeNil())
+			Expect("https").To(Equal(albReq.URL.Scheme))
+			Expect(albReq.TLS).ToNot(BeNil())
+			Expect("api.example.com").To(Equal(albReq.TLS.ServerName))
+		})
+
+		It("Adds non standard X-Forwarded-Port values to the URL host", func() {
+			req := getProxyRequest("/hello", "GET")
+			req.Headers = map[string]string{"Host": "api.example.com", "X-Forwarded-Proto": "https", "X-Forwarded-Port": "8443"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("api.example.com:8443").To(Equal(httpReq.URL.Host))
+			Expect("https://api.example.com:8443/hello").To(Equal(httpReq.URL.String()))
+
+			req.Headers["X-Forwarded-Port"] = "443"
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("api.example.com").To(Equal(httpReq.URL.Host))
+
+			req.Headers["Host"] = "api.example.com:9000"
+			req.Headers["X-Forwarded-Port"] = "8443"
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("api.example.com:9000").To(Equal(httpReq.URL.Host))
+		})
+
+		It("Keeps the scheme of the custom server address", func() {
+			os.Setenv(core.CustomHostVariable, "https://custom.example")
+			defer os.Unsetenv(core.CustomHostVariable)
+
+			req := getProxyRequest("/hello", "GET")
+			req.Headers = map[string]string{"X-Forwarded-Proto": "http"}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("https").To(Equal(httpReq.URL.Scheme))
+		})
+	})
+
+	Context("List headers", func() {
+		v2Request := events.APIGatewayV2HTTPRequest{
+			RawPath: "/hello",
+			Headers: map[string]string{
+				"accept":          "text/html, application/json;q=0.9",
+				"if-none-match":   `"a,b", "c"`,
+				"user-agent":      "Mozilla/5.0 (X11, Linux)",
+				"x-forwarded-for": "10.0.0.1, 198.51.100.7",
+			},
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+			},
+		}
+
+		It("Does not split headers by default", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(v2Request)
+			Expect(err).To(BeNil())
+			Expect([]string{"text/html, application/json;q=0.9"}).To(Equal(httpReq.Header.Values("Accept")))
+		})
+
+		It("Splits the default list headers", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SplitListHeaders()
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(v2Request)
+			Expect(err).To(BeNil())
+			Expect([]string{"text/html", "application/json;q=0.9"}).To(Equal(httpReq.Header.Values("Accept")))
+			Expect([]string{`"a,b"`, `"c"`}).To(Equal(httpReq.Header.Values("If-None-Match")))
+			Expect([]string{"Mozilla/5.0 (X11, Linux)"}).To(Equal(httpReq.Header.Values("User-Agent")))
+		})
+
+		It("Splits the given headers", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SplitListHeaders("x-forwarded-for")
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(v2Request)
+			Expect(err).To(BeNil())
+			Expect([]string{"10.0.0.1", "198.51.100.7"}).To(Equal(httpReq.Header.Values("X-Forwarded-For")))
+			Expect([]string{"text/html, application/json;q=0.9"}).To(Equal(httpReq.Header.Values("Accept")))
+		})
+	})
+
+	Context("Custom header prefix", func() {
+		It("Renames the custom headers", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SetHeaderPrefix("X-Lambda-")
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext = getRequestContext()
+			req.StageVariables = getStageVariables()
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header.Get(core.APIGwContextHeader)).To(BeEmpty())
+			Expect(httpReq.Header.Get("X-Lambda-ApiGw-Context")).ToNot(BeEmpty())
+			Expect(httpReq.Header.Get("X-Lambda-ApiGw-StageVars")).ToNot(BeEmpty())
+
+			context, err := accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("prod").To(Equal(context.Stage))
+			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect("value1").To(Equal(stageVars["var1"]))
+		})
+	})
+
+	Context("Skipped context headers", func() {
+		It("Does not add the context and stage variables headers", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SkipContextHeaders(true)
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext = getRequestContext()
+			req.StageVariables = getStageVariables()
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header).ToNot(HaveKey(core.APIGwContextHeader))
+			Expect(httpReq.Header).ToNot(HaveKey(core.APIGwStageVarsHeader))
+
+			context, err := accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("prod").To(Equal(context.Stage))
+
+			wsReq, err := accessor.WebsocketProxyRequestToHTTPRequest(events.APIGatewayWebsocketProxyRequest{
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{RouteKey: "$connect", ConnectionID: "abc="},
+			})
+			Expect(err).To(BeNil())
+			Expect(wsReq.Header).ToNot(HaveKey(core.WebsocketContextHeader))
+			Expect("abc=").To(Equal(wsReq.Header.Get(core.WebsocketConnectionIDHeader)))
+		})
+	})
+
+	Context("Hop-by-hop headers", func() {
+		req := getProxyRequest("/hello", "GET")
+		req.Headers = map[string]string{
+			"Connection":        "keep-alive, X-Session",
+			"Keep-Alive":        "timeout=5",
+			"Transfer-Encoding": "chunked",
+			"X-Session":         "abc",
+			"X-Custom":          "value",
+		}
+
+		It("Keeps the headers by default", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("timeout=5").To(Equal(httpReq.Header.Get("Keep-Alive")))
+		})
+
+		It("Strips the hop-by-hop headers when enabled", func() {
+			accessor := core.RequestAccessor{}
+			accessor.StripHopByHopHeaders(true)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header).ToNot(HaveKey("Connection"))
+			Expect(httpReq.Header).ToNot(HaveKey("Keep-Alive"))
+			Expect(httpReq.Header).ToNot(HaveKey("Transfer-Encoding"))
+			Expect(httpReq.Header).ToNot(HaveKey("X-Session"))
+			Expect("value").To(Equal(httpReq.Header.Get("X-Custom")))
+		})
+	})
+
+	Context("Header casing", func() {
+		req := getProxyRequest("/hello", "GET")
+		req.Headers = map[string]string{"x-amz-content-sha256": "abc", "X-API-KEY": "key"}
+
+		It("Does not record the header names by default", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			_, err = accessor.GetOriginalHeaderNames(httpReq)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Records the original header names when enabled", func() {
+			accessor := core.RequestAccessor{}
+			accessor.PreserveHeaderCase(true)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("abc").To(Equal(httpReq.Header.Get("X-Amz-Content-Sha256")))
+
+			names, err := accessor.GetOriginalHeaderNames(httpReq)
+			Expect(err).To(BeNil())
+			Expect("x-amz-content-sha256").To(Equal(names["X-Amz-Content-Sha256"]))
+			Expect("X-API-KEY").To(Equal(names["X-Api-Key"]))
+
+			httpReq, _, err = accessor.SwitchableEventToHTTPRequest(context.Background(), *core.NewSwitchableAPIGatewayRequestV1(&req))
+			Expect(err).To(BeNil())
+			names, ok := core.HeaderNamesFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("X-API-KEY").To(Equal(names["X-Api-Key"]))
+		})
+	})
+
+	Context("Empty events", func() {
+		accessor := core.RequestAccessor{}
+		accessor.StripBasePath("app")
+		accessor.SetTrailingSlash(core.RemoveTrailingSlash)
+
+		It("Converts empty proxy events to GET /", func() {
+			httpReq, err := accessor.ProxyEventToHTTPRequest(events.APIGatewayProxyRequest{})
+			Expect(err).To(BeNil())
+			Expect("GET").To(Equal(httpReq.Method))
+			Expect("/").To(Equal(httpReq.URL.Path))
+			Expect(httpReq.URL.RawQuery).To(BeEmpty())
+
+			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect(stageVars).To(BeEmpty())
+			Expect("{}").To(Equal(httpReq.Header.Get(core.APIGwStageVarsHeader)))
+		})
+
+		It("Converts ALB health checks without headers", func() {
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect("/").To(Equal(httpReq.URL.Path))
+			Expect(httpReq.RemoteAddr).To(BeEmpty())
+			Expect("aws-serverless-go-api.com").To(Equal(httpReq.Host))
+		})
+
+		It("Converts the other empty events", func() {
+			v2Req, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{})
+			Expect(err).To(BeNil())
+			Expect("GET /").To(Equal(v2Req.Method + " " + v2Req.URL.Path))
+
+			urlReq, err := accessor.LambdaFunctionURLRequestToHTTPRequest(events.LambdaFunctionURLRequest{})
+			Expect(err).To(BeNil())
+			Expect("GET /").To(Equal(urlReq.Method + " " + urlReq.URL.Path))
+
+			latticeReq, err := accessor.VPCLatticeRequestToHTTPRequest(core.VPCLatticeRequest{})
+			Expect(err).To(BeNil())
+			Expect("GET /").To(Equal(latticeReq.Method + " " + latticeReq.URL.Path))
+
+			authorizerReq, err := accessor.CustomAuthorizerRequestToHTTPRequest(events.APIGatewayCustomAuthorizerRequestTypeRequest{})
+			Expect(err).To(BeNil())
+			Expect("GET /").To(Equal(authorizerReq.Method + " " + authorizerReq.URL.Path))
+
+			_, err = accessor.CloudFrontEventToHTTPRequest(core.CloudFrontEvent{})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Decoded context cache", func() {
+		It("Decodes the context headers again when they change", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext = getRequestContext()
+			req.StageVariables = getStageVariables()
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+
+			for i := 0; i < 2; i++ {
+				context, err := accessor.GetAPIGatewayContext(httpReq)
+				Expect(err).To(BeNil())
+				Expect("prod").To(Equal(context.Stage))
+				stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+				Expect(err).To(BeNil())
+				Expect("value1").To(Equal(stageVars["var1"]))
+			}
+
+			httpReq.Header.Set(core.APIGwContextHeader, `{"stage":"dev"}`)
+			context, err := accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("dev").To(Equal(context.Stage))
+
+			httpReq.Header.Set(core.APIGwContextHeader, "{")
+			_, err = accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Decodes the headers of requests created by other means", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := http.NewRequest("GET", "/hello", nil)
+			Expect(err).To(BeNil())
+			httpReq.Header.Set(core.APIGwStageVarsHeader, `{"var1":"value1"}`)
+
+			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect("value1").To(Equal(stageVars["var1"]))
+		})
+	})
+
+	Context("Typed context values", func() {
+		It("Keeps the context when the headers are removed", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext = getRequestContext()
+			req.StageVariables = getStageVariables()
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			httpReq.Header.Del(core.APIGwContextHeader)
+			httpReq.Header.Del(core.APIGwStageVarsHeader)
+
+			apiGwContext, ok := core.GetAPIGatewayContextFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("prod").To(Equal(apiGwContext.Stage))
+			stageVars, ok := core.GetAPIGatewayStageVarsFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("value1").To(Equal(stageVars["var1"]))
+
+			apiGwContext, err = accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect("x").To(Equal(apiGwContext.RequestID))
+			stageVars, err = accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect("value2").To(Equal(stageVars["var2"]))
+		})
+
+		It("Stores the v2 context even without the context headers", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SkipContextHeaders(true)
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath:        "/orders",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{RouteKey: "GET /orders"},
+			})
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header).ToNot(HaveKey(core.APIGwV2ContextHeader))
+
+			v2Context, ok := core.GetAPIGatewayV2ContextFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("GET /orders").To(Equal(v2Context.RouteKey))
+			v2Context, err = accessor.GetAPIGatewayV2Context(httpReq)
+			Expect(err).To(BeNil())
+			Expect("GET /orders").To(Equal(v2Context.RouteKey))
+
+			_, ok = core.GetAPIGatewayContextFromContext(httpReq.Context())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("Conversion with context", func() {
+		accessor := core.RequestAccessor{}
+
+		It("Attaches the Lambda context to the request", func() {
+			lc := &lambdacontext.LambdaContext{AwsRequestID: "abc"}
+			ctx, cancel := context.WithCancel(lambdacontext.NewContext(context.Background(), lc))
+			req := getProxyRequest("/orders/1", "GET")
+			req.PathParameters = map[string]string{"id": "1"}
+			httpReq, _, err := accessor.ProxyEventToHTTPRequestWithContext(ctx, &req)
+			Expect(err).To(BeNil())
+			Expect("/orders/1").To(Equal(httpReq.URL.Path))
+
+			fromContext, ok := lambdacontext.FromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("abc").To(Equal(fromContext.AwsRequestID))
+			pathParams, err := accessor.GetAPIGatewayPathParams(httpReq)
+			Expect(err).To(BeNil())
+			Expect("1").To(Equal(pathParams["id"]))
+
+			cancel()
+			Expect(httpReq.Context().Err()).To(Equal(context.Canceled))
+		})
+
+		It("Converts the other events", func() {
+			httpReq, _, err := accessor.ProxyEventToHTTPRequestWithContext(context.Background(), events.ALBTargetGroupRequest{HTTPMethod: "POST", Path: "/alb"})
+			Expect(err).To(BeNil())
+			Expect("POST /alb").To(Equal(httpReq.Method + " " + httpReq.URL.Path))
+
+			httpReq, _, err = accessor.ProxyEventToHTTPRequestWithContext(context.Background(), json.RawMessage(`{"version":"2.0","rawPath":"/v2","requestContext":{"http":{"method":"GET"}}}`))
+			Expect(err).To(BeNil())
+			Expect("/v2").To(Equal(httpReq.URL.Path))
+			eventType, ok := core.EventTypeFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect(core.APIGatewayV2HTTPEvent).To(Equal(eventType))
+		})
+
+		It("Rejects unsupported events", func() {
+			_, _, err := accessor.ProxyEventToHTTPRequestWithContext(context.Background(), "event")
+			Expect(err).ToNot(BeNil())
+			_, _, err = accessor.ProxyEventToHTTPRequestWithContext(nil, getProxyRequest("/", "GET"))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Deadline margin", func() {
+		It("Cancels the request context before the Lambda deadline", func() {
+			deadline := time.Now().Add(time.Hour)
+			ctx, cancel := context.WithDeadline(context.Background(), deadline)
+			defer cancel()
+
+			accessor := core.RequestAccessor{}
+			httpReq, _, err := accessor.ProxyEventToHTTPRequestWithContext(ctx, getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			reqDeadline, ok := httpReq.Context().Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(Equal(reqDeadline))
+
+			accessor.SetDeadlineMargin(time.Second)
+			httpReq, release, err := accessor.SwitchableEventToHTTPRequest(ctx, *core.NewSwitchableAPIGatewayRequestV1(&events.APIGatewayProxyRequest{Path: "/hello"}))
+			Expect(err).To(BeNil())
+			reqDeadline, ok = httpReq.Context().Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline.Add(-time.Second)).To(Equal(reqDeadline))
+			release()
+			Expect(httpReq.Context().Err()).To(Equal(context.Canceled))
+			Expect(ctx.Err()).To(BeNil())
+
+			httpReq, _, err = accessor.ProxyEventToHTTPRequestWithContext(context.Background(), getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			_, ok = httpReq.Context().Deadline()
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("Streamed bodies", func() {
+		accessor := core.RequestAccessor{}
+		accessor.StreamRequestBodies(true)
+
+		It("Decodes the base64 body while it is read", func() {
+			for _, body := range []string{"a", "ab", "abc", "binary body"} {
+				req := getProxyRequest("/upload", "POST")
+				req.Body = base64.StdEncoding.EncodeToString([]byte(body))
+				req.IsBase64Encoded = true
+				httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+				Expect(err).To(BeNil())
+				Expect(httpReq.GetBody).To(BeNil())
+				Expect(int64(len(body))).To(Equal(httpReq.ContentLength))
+
+				decoded, err := ioutil.ReadAll(httpReq.Body)
+				Expect(err).To(BeNil())
+				Expect(body).To(Equal(string(decoded)))
+			}
+		})
+
+		It("Reports corrupted bodies when they are read", func() {
+			req := getProxyRequest("/upload", "POST")
+			req.Body = "not base64!"
+			req.IsBase64Encoded = true
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+
+			_, err = ioutil.ReadAll(httpReq.Body)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Does not stream text bodies", func() {
+			req := getProxyRequest("/upload", "POST")
+			req.Body = "text"
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.GetBody).ToNot(BeNil())
+			Expect(int64(4)).To(Equal(httpReq.ContentLength))
+		})
+	})
+
+	Context("Multipart uploads", func() {
+		contentType := "multipart/form-data; boundary=----WebKitFormBoundary7MA4YWxkTrZu0gW"
+
+		It("Preserves the boundary and the bytes of the files", func() {
+			for _, stream := range []bool{false, true} {
+				accessor := core.RequestAccessor{}
+				accessor.StreamRequestBodies(stream)
+				accessor.TranscodeRequestBodies(true)
+				req := getProxyRequest("/upload", "POST")
+				req.Headers = map[string]string{"Content-Type": contentType + "; charset=ISO-8859-1"}
+				req.Body = base64.StdEncoding.EncodeToString(getMultipartBody())
+				req.IsBase64Encoded = true
+				httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+				Expect(err).To(BeNil())
+				Expect(contentType + "; charset=ISO-8859-1").To(Equal(httpReq.Header.Get("Content-Type")))
+
+				parts, err := core.ValidateMultipartBody(httpReq)
+				Expect(err).To(BeNil())
+				Expect(2).To(Equal(parts))
+
+				Expect(httpReq.ParseMultipartForm(1 << 20)).To(BeNil())
+				Expect("holiday").To(Equal(httpReq.FormValue("title")))
+				file, header, err := httpReq.FormFile("photo")
+				Expect(err).To(BeNil())
+				Expect("photo.png").To(Equal(header.Filename))
+				content, err := ioutil.ReadAll(file)
+				Expect(err).To(BeNil())
+				Expect(getPNGHeader()).To(Equal(content))
+			}
+		})
+
+		It("Preserves quoted boundaries of HTTP API events", func() {
+			accessor := core.RequestAccessor{}
+			quoted := `multipart/form-data; boundary="----WebKitFormBoundary7MA4YWxkTrZu0gW"`
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath:         "/upload",
+				Headers:         map[string]string{"content-type": quoted},
+				Body:            base64.StdEncoding.EncodeToString(getMultipartBody()),
+				IsBase64Encoded: true,
+				RequestContext:  events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "POST"}},
+			})
+			Expect(err).To(BeNil())
+			Expect(quoted).To(Equal(httpReq.Header.Get("Content-Type")))
+
+			parts, err := core.ValidateMultipartBody(httpReq)
+			Expect(err).To(BeNil())
+			Expect(2).To(Equal(parts))
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect(getMultipartBody()).To(Equal(body))
+		})
+
+		It("Detects broken uploads", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/upload", "POST")
+			req.Headers = map[string]string{"Content-Type": "multipart/form-data; boundary=other"}
+			req.Body = string(getMultipartBody())
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			_, err = core.ValidateMultipartBody(httpReq)
+			Expect(err).ToNot(BeNil())
+
+			req.Headers = map[string]string{"Content-Type": "multipart/form-data"}
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			_, err = core.ValidateMultipartBody(httpReq)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Warm-up events", func() {
+		It("Recognizes the common keep-warm payloads", func() {
+			Expect(core.IsWarmupEvent([]byte(`{"source":"serverless-plugin-warmup"}`))).To(BeTrue())
+			Expect(core.IsWarmupEvent([]byte(`{"source":"aws.events","detail-type":"Scheduled Event","detail":{}}`))).To(BeTrue())
+			Expect(core.IsWarmupEvent([]byte(`{"warmer":true,"concurrency":3}`))).To(BeTrue())
+			Expect(core.IsWarmupEvent([]byte(`{"source":"aws.events","detail-type":"EC2 Instance State-change Notification"}`))).To(BeFalse())
+			Expect(core.IsWarmupEvent([]byte(`{"path":"/","httpMethod":"GET"}`))).To(BeFalse())
+			Expect(core.IsWarmupEvent([]byte(`not json`))).To(BeFalse())
+		})
+
+		It("Uses the configured matcher", func() {
+			accessor := core.RequestAccessor{}
+			Expect(accessor.IsWarmup([]byte(`{"source":"serverless-plugin-warmup"}`))).To(BeFalse())
+			accessor.SetWarmupMatcher(func(payload json.RawMessage) bool {
+				return string(payload) == "ping"
+			})
+			Expect(accessor.IsWarmup([]byte("ping"))).To(BeTrue())
+			Expect(accessor.IsWarmup([]byte(`{"source":"serverless-plugin-warmup"}`))).To(BeFalse())
+		})
+	})
+
+	Context("Cold start", func() {
+		It("Only flags the first converted request", func() {
+			accessor := core.RequestAccessor{}
+			accessor.AddColdStartHeader(true)
+			_, err := accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+
+			httpReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{Path: "/hello"})
+			Expect(err).To(BeNil())
+			coldStart, ok := core.ColdStartFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect(coldStart).To(BeFalse())
+			Expect("false").To(Equal(httpReq.Header.Get(core.ColdStartHeader)))
+
+			httpReq, err = (&core.RequestAccessor{}).ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header).ToNot(HaveKey(core.ColdStartHeader))
+			_, ok = core.ColdStartFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+
+			_, ok = core.ColdStartFromContext(context.Background())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("Request ID", func() {
+		It("Sets the request ID header and context value", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext.RequestID = "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("c6af9ac6-7b61-11e6-9a41-93e8deadbeef").To(Equal(httpReq.Header.Get("X-Request-Id")))
+			requestID, ok := core.RequestIDFromContext(httpReq.Context())
+			Expect(ok).To(BeTrue())
+			Expect("c6af9ac6-7b61-11e6-9a41-93e8deadbeef").To(Equal(requestID))
+
+			v2Req, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				Headers:        map[string]string{"x-request-id": "client"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{RequestID: "v2-id"},
+			})
+			Expect(err).To(BeNil())
+			Expect("client").To(Equal(v2Req.Header.Get(core.RequestIDHeader)))
+			requestID, _ = core.RequestIDFromContext(v2Req.Context())
+			Expect("v2-id").To(Equal(requestID))
+
+			accessor.SkipRequestIDHeader(true)
+			urlReq, err := accessor.LambdaFunctionURLRequestToHTTPRequest(events.LambdaFunctionURLRequest{
+				RequestContext: events.LambdaFunctionURLRequestContext{RequestID: "url-id"},
+			})
+			Expect(err).To(BeNil())
+			Expect(urlReq.Header).ToNot(HaveKey(core.RequestIDHeader))
+			requestID, _ = core.RequestIDFromContext(urlReq.Context())
+			Expect("url-id").To(Equal(requestID))
+		})
+	})
+
+	Context("Reverse conversion", func() {
+		It("Converts an http.Request into an equivalent proxy event", func() {
+			httpReq, err := http.NewRequest("POST", "https://api.example.com/orders?id=1&id=2&sort=asc", strings.NewReader(`{"name":"order"}`))
+			Expect(err).To(BeNil())
+			httpReq.Header.Add("Accept", "application/json")
+			httpReq.Header.Add("X-Tag", "a")
+			httpReq.Header.Add("X-Tag", "b")
+			httpReq.RemoteAddr = "203.0.113.7:41234"
+
+			event, err := core.HTTPRequestToProxyEvent(httpReq)
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(event.HTTPMethod))
+			Expect("/orders").To(Equal(event.Path))
+			Expect("asc").To(Equal(event.QueryStringParameters["sort"]))
+			Expect([]string{"1", "2"}).To(Equal(event.MultiValueQueryStringParameters["id"]))
+			Expect("b").To(Equal(event.Headers["X-Tag"]))
+			Expect("api.example.com").To(Equal(event.Headers["Host"]))
+			Expect("203.0.113.7").To(Equal(event.RequestContext.Identity.SourceIP))
+			Expect(event.IsBase64Encoded).To(BeFalse())
+			Expect(`{"name":"order"}`).To(Equal(event.Body))
+
+			body, err := ioutil.ReadAll(httpReq.Body)
+			Expect(err).To(BeNil())
+			Expect(`{"name":"order"}`).To(Equal(string(body)))
+
+			accessor := core.RequestAccessor{}
+			converted, err := accessor.ProxyEventToHTTPRequest(event)
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(converted.Method))
+			Expect("/orders").To(Equal(converted.URL.Path))
+			Expect("api.example.com").To(Equal(converted.Host))
+			Expect(httpReq.URL.Query()).To(Equal(converted.URL.Query()))
+			Expect([]string{"a", "b"}).To(Equal(converted.Header["X-Tag"]))
+			Expect("203.0.113.7:0").To(Equal(converted.RemoteAddr))
+			body, err = ioutil.ReadAll(converted.Body)
+			Expect(err).To(BeNil())
+			Expect(`{"name":"order"}`).To(Equal(string(body)))
+		})
+
+		It("Base64 encodes binary bodies", func() {
+			httpReq, err := http.NewRequest("PUT", "/image", bytes.NewReader(getPNGHeader()))
+			Expect(err).To(BeNil())
+
+			event, err := core.HTTPRequestToProxyEvent(httpReq)
+			Expect(err).To(BeNil())
+			Expect(event.IsBase64Encoded).To(BeTrue())
+			Expect(base64.StdEncoding.EncodeToString(getPNGHeader())).To(Equal(event.Body))
+
+			event, err = core.HTTPRequestToProxyEvent(httptest.NewRequest("GET", "/", nil))
+			Expect(err).To(BeNil())
+			Expect(event.Body).To(BeEmpty())
+			Expect(event.IsBase64Encoded).To(BeFalse())
+		})
+	})
+
+	Context("Method override", func() {
+		req := getProxyRequest("/orders", "POST")
+		req.Headers = map[string]string{"X-HTTP-Method-Override": "PATCH"}
+
+		It("Only overrides the method when enabled", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+
+			accessor.AllowMethodOverride(true)
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("PATCH").To(Equal(httpReq.Method))
+		})
+
+		It("Ignores other methods and invalid values", func() {
+			accessor := core.RequestAccessor{}
+			accessor.AllowMethodOverride(true)
+			getReq := getProxyRequest("/orders", "GET")
+			getReq.Headers = req.Headers
+			httpReq, err := accessor.ProxyEventToHTTPRequest(getReq)
+			Expect(err).To(BeNil())
+			Expect("GET").To(Equal(httpReq.Method))
+
+			invalid := getProxyRequest("/orders", "POST")
+			invalid.Headers = map[string]string{"X-HTTP-Method-Override": "DELETE /admin"}
+			httpReq, err = accessor.ProxyEventToHTTPRequest(invalid)
+			Expect(err).To(BeNil())
+			Expect("POST").To(Equal(httpReq.Method))
+		})
+	})
+
+	Context("Unsupported headers", func() {
+		req := getProxyRequest("/upload", "PUT")
+		req.Headers = map[string]string{
+			"Expect":           "100-continue",
+			"Proxy-Connection": "keep-alive",
+			"X-Custom":         "value",
+		}
+
+		It("Removes the default unsupported headers", func() {
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header).ToNot(HaveKey("Expect"))
+			Expect(httpReq.Header).ToNot(HaveKey("Proxy-Connection"))
+			Expect("value").To(Equal(httpReq.Header.Get("X-Custom")))
+		})
+
+		It("Uses the configured list", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SetUnsupportedHeaders("x-custom")
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("100-continue").To(Equal(httpReq.Header.Get("Expect")))
+			Expect(httpReq.Header).ToNot(HaveKey("X-Custom"))
+
+			accessor.SetUnsupportedHeaders()
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("100-continue").To(Equal(httpReq.Header.Get("Expect")))
+			Expect("keep-alive").To(Equal(httpReq.Header.Get("Proxy-Connection")))
+		})
+
+		It("Drops the custom headers sent by the client", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SetUnsupportedHeaders()
+			forged := getProxyRequest("/", "GET")
+			forged.RequestContext.DomainName = "a.example.com"
+			forged.Headers = map[string]string{
+				"x-golambdaproxy-apigw-context": `{"domainName":"b.example.com"}`,
+				"X-GoLambdaProxy-Tenant":        "b",
+			}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(forged)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header.Get("X-GoLambdaProxy-Tenant")).To(BeEmpty())
+			Expect(httpReq.Header[http.CanonicalHeaderKey(core.APIGwContextHeader)]).To(HaveLen(1))
+			context, err := accessor.GetAPIGatewayContext(httpReq)
+			Expect(err).To(BeNil())
+			Expect(context.DomainName).To(Equal("a.example.com"))
+		})
+	})
+
+	Context("Compressed request bodies", func() {
+		gzipped := func(body string) string {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(body))
+			zw.Close()
+			return base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+
+		It("Decompresses gzip bodies when enabled", func() {
+			req := getProxyRequest("/orders", "POST")
+			req.Headers = map[string]string{"Content-Encoding": "gzip", "Content-Length": "42"}
+			req.Body = gzipped(`{"name":"order"}`)
+			req.IsBase64Encoded = true
+
+			accessor := core.RequestAccessor{}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect("gzip").To(Equal(httpReq.Header.Get("Content-Encoding")))
+
+			for _, stream := range []bool{false, true} {
+				accessor.DecompressRequestBodies(true)
+				accessor.StreamRequestBodies(stream)
+				httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+				Expect(err).To(BeNil())
+				Expect(httpReq.Header).ToNot(HaveKey("Content-Encoding"))
+				Expect(int64(16)).To(Equal(httpReq.ContentLength))
+				Expect("16").To(Equal(httpReq.Header.Get("Content-Length")))
+				body, err := ioutil.ReadAll(httpReq.Body)
+				Expect(err).To(BeNil())
+				Expect(`{"name":"order"}`).To(Equal(string(body)))
+			}
+		})
+
+		It("Stops decompressing past the body limit", func() {
+			req := getProxyRequest("/orders", "POST")
+			req.Headers = map[string]string{"Content-Encoding": "gzip"}
+			req.Body = gzipped(strings.Repeat("a", 1<<20))
+			req.IsBase64Encoded = true
+
+			accessor := core.RequestAccessor{}
+			accessor.DecompressRequestBodies(true)
+			accessor.SetMaxRequestBodyBytes(1024)
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(int64(1025)).To(Equal(httpReq.ContentLength))
+
+			req.Body = base64.StdEncoding.EncodeToString([]byte("not gzip"))
+			_, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Rejects decompression bombs without a body limit", func() {
+			req := getProxyRequest("/orders", "POST")
+			req.Headers = map[string]string{"Content-Encoding": "gzip"}
+			req.Body = gzipped(strings.Repeat("a", core.DefaultMaxDecompressedBodyBytes+1))
+			req.IsBase64Encoded = true
+
+			accessor := core.RequestAccessor{}
+			accessor.DecompressRequestBodies(true)
+			_, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Range headers", func() {
+		It("Passes the ranges through untouched", func() {
+			accessor := core.RequestAccessor{}
+			accessor.SplitListHeaders()
+			req := getProxyRequest("/file.bin", "GET")
+			req.Headers = map[string]string{"Range": "bytes=0-1, 4-5", "If-Range": `"abc"`}
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect([]string{"bytes=0-1, 4-5"}).To(Equal(httpReq.Header["Range"]))
+			Expect(`"abc"`).To(Equal(httpReq.Header.Get("If-Range")))
+		})
+	})
+
+	Context("Resource template", func() {
+		It("Stores the resource template of the events", func() {
+			accessor := core.RequestAccessor{}
+			req := getProxyRequest("/users/42", "GET")
+			req.Resource = "/users/{id}"
+			httpReq, err := accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			resource, err := accessor.GetAPIGatewayResource(httpReq)
+			Expect(err).To(BeNil())
+			Expect("/users/{id}").To(Equal(resource))
+
+			v2Req, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{
+				RawPath:        "/users/42",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{RouteKey: "GET /users/{id}"},
+			})
+			Expect(err).To(BeNil())
+			resource, ok := core.ResourceFromContext(v2Req.Context())
+			Expect(ok).To(BeTrue())
+			Expect("/users/{id}").To(Equal(resource))
+
+			v2Req, err = accessor.APIGatewayV2HTTPRequestToHTTPRequest(events.APIGatewayV2HTTPRequest{RouteKey: "$default"})
+			Expect(err).To(BeNil())
+			resource, _ = core.ResourceFromContext(v2Req.Context())
+			Expect("$default").To(Equal(resource))
+
+			albReq, err := accessor.ALBTargetGroupRequestToHTTPRequest(events.ALBTargetGroupRequest{Path: "/users/42"})
+			Expect(err).To(BeNil())
+			_, err = accessor.GetAPIGatewayResource(albReq)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Lenient event unmarshaling", func() {
+		It("Accepts arrays and scalars in the header and query maps", func() {
+			accessor := core.RequestAccessor{}
+			payload := []byte(`{"resource":"/{proxy+}","path":"/hello","httpMethod":"GET",` +
+				`"headers":{"X-Tag":["a","b"],"X-Count":3},` +
+				`"queryStringParameters":{"page":["1","2"],"debug":true},` +
+				`"multiValueQueryStringParameters":{"debug":"true"},` +
+				`"requestContext":{"stage":"prod"}}`)
+
+			httpReq, err := accessor.RawEventToHTTPRequest(payload)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Header["X-Tag"]).To(Equal([]string{"a", "b"}))
+			Expect(httpReq.Header.Get("X-Count")).To(Equal("3"))
+			Expect(httpReq.URL.Query()["debug"]).To(Equal([]string{"true"}))
+
+			anyReq, eventType, err := accessor.AnyEventToHTTPRequest(payload)
+			Expect(err).To(BeNil())
+			Expect(eventType).To(Equal(core.APIGatewayProxyEvent))
+			Expect(anyReq.Header["X-Tag"]).To(Equal([]string{"a", "b"}))
+
+			v2 := core.SwitchableAPIGatewayRequest{}
+			Expect(json.Unmarshal([]byte(`{"version":"2.0","rawPath":"/v2","cookies":"a=1","headers":{"X-Tag":["a"]},"requestContext":{"http":{"method":"GET"}}}`), &v2)).To(BeNil())
+			Expect(v2.Version2().Cookies).To(Equal([]string{"a=1"}))
+			Expect(v2.Version2().Headers["X-Tag"]).To(Equal("a"))
+
+			_, err = accessor.RawEventToHTTPRequest([]byte(`{"path":["/a"]}`))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Console test events", func() {
+		It("Converts the events of the API Gateway console", func() {
+			accessor := core.RequestAccessor{}
+			payload := []byte(`{"resource":"/pets","path":"/pets","headers":{"Accept":"text/plain"},` +
+				`"multiValueHeaders":{"Accept":null},"queryStringParameters":null,"multiValueQueryStringParameters":null,` +
+				`"pathParameters":null,"stageVariables":null,"body":null,"isBase64Encoded":null,` +
+				`"requestContext":{"stage":"test-invoke-stage","domainName":"testPrefix.testDomainName",` +
+				`"identity":{"sourceIp":"test-invoke-source-ip"},"authorizer":null}}`)
+
+			httpReq, eventType, err := accessor.AnyEventToHTTPRequest(payload)
+			Expect(err).To(BeNil())
+			Expect(eventType).To(Equal(core.APIGatewayProxyEvent))
+			Expect(httpReq.Method).To(Equal(http.MethodGet))
+			Expect(httpReq.URL.String()).To(Equal(core.DefaultServerAddress + "/pets"))
+			Expect(httpReq.Header.Get("Accept")).To(Equal("text/plain"))
+			Expect(httpReq.RemoteAddr).To(Equal(""))
+			Expect(httpReq.Body).To(Equal(http.NoBody))
+
+			stageVars, err := accessor.GetAPIGatewayStageVars(httpReq)
+			Expect(err).To(BeNil())
+			Expect(stageVars).To(BeEmpty())
+			params, err := accessor.GetAPIGatewayPathParams(httpReq)
+			Expect(err).To(BeNil())
+			Expect(params).To(BeEmpty())
+
+			httpReq, err = accessor.RawEventToHTTPRequest([]byte(`{"path":"/pets","requestContext":null}`))
+			Expect(err).To(BeNil())
+			Expect(httpReq.URL.Path).To(Equal("/pets"))
+		})
+	})
+
+	Context("Request protocol", func() {
+		It("Sets the protocol version of the event", func() {
+			accessor := core.RequestAccessor{}
+			v2Req := events.APIGatewayV2HTTPRequest{RawPath: "/hello"}
+			v2Req.RequestContext.HTTP.Method = "GET"
+			v2Req.RequestContext.HTTP.Protocol = "HTTP/2.0"
+
+			httpReq, err := accessor.APIGatewayV2HTTPRequestToHTTPRequest(v2Req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.Proto).To(Equal("HTTP/2.0"))
+			Expect(httpReq.ProtoMajor).To(Equal(2))
+			Expect(httpReq.ProtoMinor).To(Equal(0))
+			protocol, err := accessor.GetRequestProtocol(httpReq)
+			Expect(err).To(BeNil())
+			Expect(protocol).To(Equal("HTTP/2.0"))
+
+			req := getProxyRequest("/hello", "GET")
+			req.RequestContext.Protocol = "HTTP/1.0"
+			httpReq, err = accessor.ProxyEventToHTTPRequest(req)
+			Expect(err).To(BeNil())
+			Expect(httpReq.ProtoAtLeast(1, 1)).To(BeFalse())
+
+			httpReq, err = accessor.ProxyEventToHTTPRequest(getProxyRequest("/hello", "GET"))
+			Expect(err).To(BeNil())
+			Expect(httpReq.Proto).To(Equal("HTTP/1.1"))
+			_, err = accessor.GetRequestProtocol(httpReq)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
+
+func getProxyRequest(path string, method string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		Path:       path,
+		HTTPMethod: method,
+	}
+}
+
+func getRequestContext() events.APIGatewayProxyRequestContext {
+	return events.APIGatewayProxyRequestContext{
+		AccountID: "x",
+		RequestID: "x",
+		APIID:     "x",
+		Stage:     "prod",
+	}
+}
+
+func getStageVariables() map[string]string {
+	return map[string]string{
+		"var1": "value1",
+		"var2": "value2",
+	}
+}
+
+// getPNGHeader returns the signature and the IHDR chunk of a PNG file, which
+// include the bytes most often mangled by text conversions.
+func getPNGHeader() []byte {
+	return []byte{
+		0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a,
+		0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+		0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+		0x89, 0xff, 0xfe, 0x00, 0x80,
+	}
+}
+
+// getMultipartBody returns a form upload as sent by browsers, with a text
+// field and a PNG file.
+func getMultipartBody() []byte {
+	body := "------WebKitFormBoundary7MA4YWxkTrZu0gW\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n" +
+		"\r\n" +
+		"holiday\r\n" +
+		"------WebKitFormBoundary7MA4YWxkTrZu0gW\r\n" +
+		"Content-Disposition: form-data; name=\"photo\"; filename=\"photo.png\"\r\n" +
+		"Content-Type: image/png\r\n" +
+		"\r\n" +
+		string(getPNGHeader()) + "\r\n" +
+		"------WebKitFormBoundary7MA4YWxkTrZu0gW--\r\n"
+	return []byte(body)
 }
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader is the header set on the converted requests to the request
+// ID of the event, such as the requestId of the API Gateway request context,
+// so that the logging and tracing middlewares of the framework can correlate
+// their entries with the access logs of API Gateway. A header sent by the
+// client is not replaced.
+const RequestIDHeader = "X-Request-Id"
+
+// SkipRequestIDHeader instructs the RequestAccessor object not to set the
+// RequestIDHeader on the converted requests. The request ID is still stored
+// in the request context, see RequestIDFromContext.
+func (r *RequestAccessor) SkipRequestIDHeader(skip bool) {
+	r.skipRequestIDHeader = skip
+}
+
+// RequestIDFromContext returns the request ID of the event stored in the
+// given context by the conversion of API Gateway, Function URL and CloudFront
+// events.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey).(string)
+	return requestID, ok
+}
+
+func (r *RequestAccessor) withRequestID(req *http.Request, requestID string) *http.Request {
+	if requestID == "" {
+		return req
+	}
+	if !r.skipRequestIDHeader && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	return req.WithContext(context.WithValue(req.Context(), requestIDContextKey, requestID))
+}
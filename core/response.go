@@ -3,10 +3,18 @@
 package core
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -14,6 +22,31 @@ import (
 
 const defaultStatusCode = -1
 const contentTypeHeaderKey = "Content-Type"
+const contentMD5HeaderKey = "Content-MD5"
+const etagHeaderKey = "ETag"
+
+// ErrHijackNotSupported is returned by the Hijack method of the
+// ProxyResponseWriter. Lambda invocations are not backed by a network
+// connection the handler could take over, for example to upgrade it to a
+// WebSocket.
+var ErrHijackNotSupported = errors.New("Hijacking the connection is not supported by the proxy response writer")
+
+// IntegrityHeader selects the header the ProxyResponseWriter computes over
+// the buffered response body when generating the proxy response. Clients
+// can use the header to verify the payload after API Gateway decodes it.
+type IntegrityHeader int
+
+const (
+	// NoIntegrityHeader does not add any integrity header to the response.
+	// This is the default.
+	NoIntegrityHeader IntegrityHeader = iota
+	// ContentMD5Header sets the Content-MD5 header to the base64 encoded
+	// MD5 digest of the response body
+	ContentMD5Header
+	// ETagHeader sets a strong ETag header to the hex encoded SHA-256
+	// digest of the response body
+	ETagHeader
+)
 
 // ProxyResponseWriter implements http.ResponseWriter and adds the method
 // necessary to return an events.APIGatewayProxyResponse object
@@ -21,6 +54,51 @@ type ProxyResponseWriter struct {
 	headers http.Header
 	body    bytes.Buffer
 	status  int
+	options ResponseOptions
+	request *http.Request
+	closed  chan bool
+
+	final           *finalBody
+	overflowHandled bool
+	pooled          bool
+}
+
+// ResponseOptions holds the settings applied to the ProxyResponseWriter
+// objects created by the framework adapters. The adapters embed this
+// struct so the options can be set directly on the adapter instance.
+type ResponseOptions struct {
+	integrityHeader     IntegrityHeader
+	compression         *compressionOptions
+	binaryContentTypes  []string
+	stripHopByHop       bool
+	contentTypeDefault  ContentTypeDefault
+	defaultContentType  string
+	maxPayloadBytes     int
+	overflowHandler     OverflowHandler
+	conditionalRequests bool
+	poolWriters         bool
+	writeObservers      []WriteObserver
+}
+
+// SetIntegrityHeader instructs the ResponseOptions object to add the given
+// integrity header to all the responses generated by the writers it creates.
+func (o *ResponseOptions) SetIntegrityHeader(h IntegrityHeader) {
+	o.integrityHeader = h
+}
+
+// NewProxyResponseWriter returns a new ProxyResponseWriter object
+// configured with the current options for the given request. The request
+// is used to negotiate the response encoding.
+func (o *ResponseOptions) NewProxyResponseWriter(req *http.Request) *ProxyResponseWriter {
+	var w *ProxyResponseWriter
+	if o.poolWriters {
+		w = acquireProxyResponseWriter()
+	} else {
+		w = NewProxyResponseWriter()
+	}
+	w.options = *o
+	w.request = req
+	return w
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
@@ -34,6 +112,37 @@ func NewProxyResponseWriter() *ProxyResponseWriter {
 
 }
 
+// SetIntegrityHeader sets the integrity header the writer adds to the
+// proxy response. Headers explicitly set by the handler are never replaced.
+func (r *ProxyResponseWriter) SetIntegrityHeader(h IntegrityHeader) {
+	r.options.integrityHeader = h
+}
+
+// Reset discards the status code, the headers and the body written to the
+// response, as well as the response finalized by the Get methods and the
+// state of the overflow handler, so that a new response can be written, for
+// example when an invocation is retried. The options and the request of the
+// writer are kept.
+func (r *ProxyResponseWriter) Reset() {
+	r.headers = make(http.Header)
+	r.body.Reset()
+	r.status = defaultStatusCode
+	r.final = nil
+	r.overflowHandled = false
+}
+
+// Status returns the status code written to the response, or -1 if no status
+// was written.
+func (r *ProxyResponseWriter) Status() int {
+	return r.status
+}
+
+// Body returns the body written to the response, before any compression and
+// encoding.
+func (r *ProxyResponseWriter) Body() []byte {
+	return r.body.Bytes()
+}
+
 // Header implementation from the http.ResponseWriter interface.
 func (r *ProxyResponseWriter) Header() http.Header {
 	return r.headers
@@ -50,51 +159,231 @@ func (r *ProxyResponseWriter) Write(body []byte) (int, error) {
 	// if the content type header is not set when we write the body we try to
 	// detect one and set it by default. If the content type cannot be detected
 	// it is automatically set to "application/octet-stream" by the
-	// DetectContentType method
-	if r.Header().Get(contentTypeHeaderKey) == "" {
-		r.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
-	}
+	// DetectContentType method. See SetContentTypeDefault for the other
+	// options.
+	r.setDefaultContentType(body)
 
+	if err := r.observeWrite(body); err != nil {
+		return 0, err
+	}
 	return (&r.body).Write(body)
 }
 
+// ReadFrom implementation from the io.ReaderFrom interface, used by io.Copy.
+// The data is read directly into the buffer of the response body, which is
+// grown upfront when the size of the reader is known. The status code and the
+// content type are set like with the Write method. With write observers, the
+// data is passed to the Write method in chunks instead.
+func (r *ProxyResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if len(r.options.writeObservers) > 0 {
+		return r.copyObserved(src)
+	}
+	if sized, ok := src.(interface{ Len() int }); ok {
+		r.body.Grow(sized.Len())
+	}
+
+	// the first bytes are passed to the Write method, which detects the
+	// content type of the body when the handler did not set one
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(src, sniff)
+	if n > 0 {
+		if _, werr := r.Write(sniff[:n]); werr != nil {
+			return 0, werr
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return int64(n), nil
+	}
+	if err != nil {
+		return int64(n), err
+	}
+
+	rest, err := (&r.body).ReadFrom(src)
+	return int64(n) + rest, err
+}
+
 // WriteHeader sets a status code for the response. This method is used
-// for error responses.
+// for error responses. Informational statuses, such as 100 Continue or 103
+// Early Hints, cannot be sent before the response of a Lambda invocation and
+// are ignored; the headers set for them, such as the Link headers of early
+// hints, are kept in the final response like with the net/http package.
 func (r *ProxyResponseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		return
+	}
 	r.status = status
 }
 
+// Hijack implementation from the http.Hijacker interface. Always returns
+// ErrHijackNotSupported, so that the frameworks asserting the interface can
+// report the error instead of panicking.
+func (r *ProxyResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, ErrHijackNotSupported
+}
+
+// CloseNotify implementation from the http.CloseNotifier interface. The
+// returned channel receives a single value when the context of the request
+// passed to NewProxyResponseWriter is done, for example when the Lambda
+// invocation is about to time out, see SetDeadlineMargin. The channel never
+// receives a value for writers without a request or for requests whose
+// context cannot be cancelled.
+func (r *ProxyResponseWriter) CloseNotify() <-chan bool {
+	if r.closed != nil {
+		return r.closed
+	}
+	r.closed = make(chan bool, 1)
+	if r.request == nil || r.request.Context().Done() == nil {
+		return r.closed
+	}
+	go func(ctx context.Context, closed chan<- bool) {
+		<-ctx.Done()
+		closed <- true
+	}(r.request.Context(), r.closed)
+	return r.closed
+}
+
+// Flush implementation from the http.Flusher interface. The response is
+// returned to Lambda as a whole once the handler completes, so Flush does not
+// send anything and only sets the status of the response to 200 OK if no
+// status code was set, like the writers of the net/http package.
+func (r *ProxyResponseWriter) Flush() {
+	if r.status == defaultStatusCode {
+		r.status = http.StatusOK
+	}
+}
+
 // GetProxyResponse converts the data passed to the response writer into
 // an events.APIGatewayProxyResponse object.
+// Headers are returned both as single and multi value headers, so that the
+// repeated headers such as Set-Cookie are not collapsed into their first
+// value. API Gateway combines the two maps.
 // Returns a populated proxy response object. If the reponse is invalid, for example
-// has no headers or an invalid status code returns an error.
+// has no headers or an invalid status code returns an error. Responses
+// larger than the Lambda payload limit are passed to the overflow handler,
+// see SetResponseOverflowHandler. The response is finalized by the first call
+// of a Get method, later calls return the same response until Reset is
+// called.
 func (r *ProxyResponseWriter) GetProxyResponse() (events.APIGatewayProxyResponse, error) {
 	if r.status == defaultStatusCode {
 		return events.APIGatewayProxyResponse{}, errors.New("Status code not set on response")
 	}
 
-	var output string
-	isBase64 := false
-
-	bb := (&r.body).Bytes()
-
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
-		output = base64.StdEncoding.EncodeToString(bb)
-		isBase64 = true
+	output, isBase64, err := r.encodeBody(false)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
 	}
 
 	proxyHeaders := make(map[string]string)
+	multiValueHeaders := make(map[string][]string)
 
 	for h := range r.headers {
 		proxyHeaders[h] = r.headers.Get(h)
+		multiValueHeaders[h] = r.headers[h]
+	}
+
+	resp := events.APIGatewayProxyResponse{
+		StatusCode:        r.status,
+		Headers:           proxyHeaders,
+		MultiValueHeaders: multiValueHeaders,
+		Body:              output,
+		IsBase64Encoded:   isBase64,
+	}
+	retry, err := r.checkPayloadSize(resp, len(output))
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+	if retry {
+		return r.GetProxyResponse()
+	}
+	return resp, nil
+}
+
+// encodeBody returns the body of the response as a string, finalizing the
+// response on the first call. Bodies that are not valid UTF-8 text are base64
+// encoded, as well as all bodies when forceBase64 is true, the bodies of the
+// binary types set with SetBinaryContentTypes and the partial responses to
+// range requests of binary content.
+func (r *ProxyResponseWriter) encodeBody(forceBase64 bool) (string, bool, error) {
+	if r.final == nil {
+		if err := r.finalize(); err != nil {
+			return "", false, err
+		}
+	}
+	if r.final.empty {
+		return "", false, nil
+	}
+
+	// bodies in a charset other than UTF-8 are always base64 encoded, even
+	// when the bytes happen to be valid UTF-8, so that API Gateway returns
+	// them to the client unchanged
+	bb := r.final.body
+	if r.options.binaryContentTypes != nil && r.isBinary() {
+		forceBase64 = true
+	}
+	if !forceBase64 && !r.isBinaryRange() && utf8.Valid(bb) && !r.final.compressed && isUTF8Charset(Charset(r.headers.Get(contentTypeHeaderKey))) {
+		return string(bb), false, nil
+	}
+	return base64.StdEncoding.EncodeToString(bb), true, nil
+}
+
+// finalBody is the body of a finalized response.
+type finalBody struct {
+	body       []byte
+	compressed bool
+	empty      bool
+}
+
+// finalize applies the response encoding and the integrity header to the
+// buffered body. The trailers are folded into the headers and the hop-by-hop
+// headers are removed first if the option is enabled. The body of the
+// responses to HEAD requests is always empty, its length is returned in the
+// Content-Length header, and so is the body of the 304 responses to the
+// conditional requests, see HandleConditionalRequests.
+// The headers are only changed once, so that all the responses generated by
+// the Get methods are the same until Reset is called.
+func (r *ProxyResponseWriter) finalize() error {
+	foldTrailers(r.headers)
+	if r.options.stripHopByHop {
+		removeHopByHopHeaders(r.headers)
+	}
+
+	bb := (&r.body).Bytes()
+	bb, compressed, err := r.compress(bb)
+	if err != nil {
+		return err
+	}
+	r.addIntegrityHeader(bb)
+	r.final = &finalBody{body: bb, compressed: compressed}
+	if r.notModified(bb) {
+		r.final.empty = true
+		return nil
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode:      r.status,
-		Headers:         proxyHeaders,
-		Body:            output,
-		IsBase64Encoded: isBase64,
-	}, nil
+	// HEAD responses describe the body of the matching GET response without
+	// carrying it, some frameworks write it anyway
+	if r.request != nil && r.request.Method == http.MethodHead {
+		if len(bb) > 0 && r.headers.Get("Content-Length") == "" {
+			r.headers.Set("Content-Length", strconv.Itoa(len(bb)))
+		}
+		r.final.empty = true
+	}
+	return nil
+}
+
+// addIntegrityHeader computes the configured integrity header over the raw
+// body bytes, before any base64 encoding, so that the value matches the
+// payload received by the client.
+func (r *ProxyResponseWriter) addIntegrityHeader(body []byte) {
+	switch r.options.integrityHeader {
+	case ContentMD5Header:
+		if r.headers.Get(contentMD5HeaderKey) == "" {
+			digest := md5.Sum(body)
+			r.headers.Set(contentMD5HeaderKey, base64.StdEncoding.EncodeToString(digest[:]))
+		}
+	case ETagHeader:
+		if r.headers.Get(etagHeaderKey) == "" {
+			digest := sha256.Sum256(body)
+			r.headers.Set(etagHeaderKey, "\""+hex.EncodeToString(digest[:])+"\"")
+		}
+	}
 }
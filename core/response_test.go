@@ -1,11 +1,24 @@
 package core
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"math/rand"
 	"net/http"
+	"os"
 	"strings"
 
+	"github.com/klauspost/compress/zstd"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -92,6 +105,20 @@ var _ = Describe("ResponseWriter tests", func() {
 			Expect(true).To(Equal(strings.HasPrefix(proxyResp.Headers["Content-Type"], "text/html;")))
 			Expect(htmlBodyContent).To(Equal(proxyResp.Body))
 		})
+
+		It("Applies the configured default content type", func() {
+			options := ResponseOptions{}
+			options.SetContentTypeDefault(FixedContentType, "application/json")
+			resp := options.NewProxyResponseWriter(nil)
+			resp.Write([]byte(htmlBodyContent))
+			Expect("application/json").To(Equal(resp.Header().Get("Content-Type")))
+
+			options.SetContentTypeDefault(NoContentType, "")
+			resp = options.NewProxyResponseWriter(nil)
+			resp.Write([]byte(htmlBodyContent))
+			Expect(resp.Header()).ToNot(HaveKey("Content-Type"))
+			Expect(http.StatusOK).To(Equal(resp.status))
+		})
 	})
 
 	Context("Export API Gateway proxy response", func() {
@@ -119,6 +146,18 @@ var _ = Describe("ResponseWriter tests", func() {
 			Expect(proxyResponse.IsBase64Encoded).To(BeFalse())
 		})
 
+		It("Returns the repeated headers as multi value headers", func() {
+			cookieResponse := NewProxyResponseWriter()
+			cookieResponse.Header().Add("Set-Cookie", "a=1")
+			cookieResponse.Header().Add("Set-Cookie", "b=2")
+			cookieResponse.WriteHeader(http.StatusNoContent)
+
+			proxyResponse, err := cookieResponse.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect("a=1").To(Equal(proxyResponse.Headers["Set-Cookie"]))
+			Expect([]string{"a=1", "b=2"}).To(Equal(proxyResponse.MultiValueHeaders["Set-Cookie"]))
+		})
+
 		binaryResponse := NewProxyResponseWriter()
 		binaryResponse.Header().Add("Content-Type", "application/octet-stream")
 		binaryBody := make([]byte, 256)
@@ -143,4 +182,849 @@ var _ = Describe("ResponseWriter tests", func() {
 			Expect(http.StatusAccepted).To(Equal(proxyResponse.StatusCode))
 		})
 	})
+
+	Context("Export ALB target group response", func() {
+		It("Refuses responses with default status code", func() {
+			_, err := NewProxyResponseWriter().GetALBTargetGroupResponse()
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Writes status description and headers", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Add("Content-Type", "text/plain")
+			resp.Header().Add("X-Multi", "1")
+			resp.Header().Add("X-Multi", "2")
+			resp.WriteHeader(http.StatusNotFound)
+			resp.Write([]byte("missing"))
+
+			albResp, err := resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusNotFound).To(Equal(albResp.StatusCode))
+			Expect("404 Not Found").To(Equal(albResp.StatusDescription))
+			Expect("missing").To(Equal(albResp.Body))
+			Expect(albResp.IsBase64Encoded).To(BeFalse())
+			Expect("1").To(Equal(albResp.Headers["X-Multi"]))
+			Expect([]string{"1", "2"}).To(Equal(albResp.MultiValueHeaders["X-Multi"]))
+		})
+
+		It("Base64 encodes binary content types", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "image/png")
+			resp.Write([]byte("not really a png"))
+
+			albResp, err := resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(albResp.IsBase64Encoded).To(BeTrue())
+			Expect(base64.StdEncoding.EncodeToString([]byte("not really a png"))).To(Equal(albResp.Body))
+		})
+
+		It("Base64 encodes bodies with a content encoding", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "application/json")
+			resp.Header().Set("Content-Encoding", "gzip")
+			resp.Write([]byte("{}"))
+
+			albResp, err := resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(albResp.IsBase64Encoded).To(BeTrue())
+		})
+
+		It("Does not treat textual image types as binary", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "image/svg+xml")
+			resp.Write([]byte("<svg/>"))
+
+			albResp, err := resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(albResp.IsBase64Encoded).To(BeFalse())
+			Expect("<svg/>").To(Equal(albResp.Body))
+		})
+
+		It("Uses the configured binary content types", func() {
+			opts := ResponseOptions{}
+			opts.SetBinaryContentTypes("application/x-custom")
+			req, _ := http.NewRequest("GET", "/file", nil)
+
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Type", "application/x-custom")
+			resp.Write([]byte("data"))
+			albResp, err := resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(albResp.IsBase64Encoded).To(BeTrue())
+
+			resp = opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Type", "image/png")
+			resp.Write([]byte("data"))
+			albResp, err = resp.GetALBTargetGroupResponse()
+			Expect(err).To(BeNil())
+			Expect(albResp.IsBase64Encoded).To(BeFalse())
+		})
+
+		It("Applies the binary content types to all the response formats", func() {
+			opts := ResponseOptions{}
+			opts.SetBinaryContentTypes("application/vnd.*", "image/*")
+
+			resp := opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "application/vnd.api+protobuf")
+			resp.Write([]byte("data"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeTrue())
+			Expect(base64.StdEncoding.EncodeToString([]byte("data"))).To(Equal(proxyResp.Body))
+
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "application/pdf")
+			resp.Write([]byte("data"))
+			v2Resp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(v2Resp.IsBase64Encoded).To(BeFalse())
+
+			resp.SetBinaryContentTypes("*/*")
+			v2Resp, err = resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(v2Resp.IsBase64Encoded).To(BeTrue())
+
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Write([]byte("data"))
+			proxyResp, err = resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeFalse())
+		})
+	})
+
+	Context("Export HTTP API v2 response", func() {
+		It("Joins headers and returns cookies separately", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Add("Content-Type", "text/plain")
+			resp.Header().Add("Cache-Control", "no-cache")
+			resp.Header().Add("Cache-Control", "no-store")
+			resp.Header().Add("Set-Cookie", "a=1")
+			resp.Header().Add("Set-Cookie", "b=2")
+			resp.Write([]byte("hello"))
+
+			v2Resp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(v2Resp.StatusCode))
+			Expect("hello").To(Equal(v2Resp.Body))
+			Expect("no-cache,no-store").To(Equal(v2Resp.Headers["Cache-Control"]))
+			Expect(v2Resp.Headers).ToNot(HaveKey("Set-Cookie"))
+			Expect([]string{"a=1", "b=2"}).To(Equal(v2Resp.Cookies))
+		})
+	})
+
+	Context("Export VPC Lattice response", func() {
+		It("Joins headers and keeps only the last cookie", func() {
+			var logged bytes.Buffer
+			log.SetOutput(&logged)
+			defer log.SetOutput(os.Stderr)
+
+			resp := NewProxyResponseWriter()
+			resp.Header().Add("Vary", "Accept")
+			resp.Header().Add("Vary", "Origin")
+			resp.Header().Add("Set-Cookie", "a=1; Expires=Wed, 21 Oct 2026 07:28:00 GMT")
+			resp.Header().Add("Set-Cookie", "b=2; Expires=Thu, 22 Oct 2026 07:28:00 GMT")
+			resp.Write([]byte("hello"))
+
+			latticeResp, err := resp.GetVPCLatticeResponse()
+			Expect(err).To(BeNil())
+			Expect("Accept,Origin").To(Equal(latticeResp.Headers["Vary"]))
+			Expect("b=2; Expires=Thu, 22 Oct 2026 07:28:00 GMT").To(Equal(latticeResp.Headers["Set-Cookie"]))
+			Expect(latticeResp.Headers["Set-Cookie"]).ToNot(ContainSubstring("a=1"))
+			Expect(logged.String()).To(ContainSubstring("discarding 1 Set-Cookie headers"))
+		})
+	})
+
+	Context("Charset aware encoding", func() {
+		It("Base64 encodes bodies declared in a non UTF-8 charset", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "text/plain; charset=utf-16le")
+			resp.Write([]byte{'h', 0, 'i', 0})
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeTrue())
+			Expect(base64.StdEncoding.EncodeToString([]byte{'h', 0, 'i', 0})).To(Equal(proxyResp.Body))
+		})
+
+		It("Does not encode UTF-8 bodies", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+			resp.Write([]byte("hi"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeFalse())
+			Expect("hi").To(Equal(proxyResp.Body))
+		})
+	})
+
+	Context("Integrity headers", func() {
+		It("Does not add integrity headers by default", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Add("Content-Type", "text/plain")
+			resp.Write([]byte("hello"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(1).To(Equal(len(proxyResp.Headers)))
+		})
+
+		It("Adds a Content-MD5 header computed over the raw body", func() {
+			binaryBody := make([]byte, 256)
+			_, err := rand.Read(binaryBody)
+			Expect(err).To(BeNil())
+
+			opts := ResponseOptions{}
+			opts.SetIntegrityHeader(ContentMD5Header)
+			resp := opts.NewProxyResponseWriter(nil)
+			resp.Header().Add("Content-Type", "application/octet-stream")
+			resp.Write(binaryBody)
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeTrue())
+			digest := md5.Sum(binaryBody)
+			Expect(base64.StdEncoding.EncodeToString(digest[:])).To(Equal(proxyResp.Headers["Content-Md5"]))
+		})
+
+		It("Adds a strong ETag header", func() {
+			resp := NewProxyResponseWriter()
+			resp.SetIntegrityHeader(ETagHeader)
+			resp.Header().Add("Content-Type", "text/plain")
+			resp.Write([]byte("hello"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			digest := sha256.Sum256([]byte("hello"))
+			Expect("\"" + hex.EncodeToString(digest[:]) + "\"").To(Equal(proxyResp.Headers["Etag"]))
+		})
+
+		It("Does not replace an ETag set by the handler", func() {
+			resp := NewProxyResponseWriter()
+			resp.SetIntegrityHeader(ETagHeader)
+			resp.Header().Set("ETag", "\"v1\"")
+			resp.Write([]byte("hello"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect("\"v1\"").To(Equal(proxyResp.Headers["Etag"]))
+		})
+	})
+
+	Context("Response compression", func() {
+		largeBody := strings.Repeat("{\"message\":\"hello\"}", 100)
+
+		newWriter := func(acceptEncoding string) *ProxyResponseWriter {
+			req, _ := http.NewRequest("GET", "/hello", nil)
+			if acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+			}
+			opts := ResponseOptions{}
+			opts.EnableCompression(0)
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Type", "application/json")
+			return resp
+		}
+
+		It("Compresses with zstd when the client accepts it", func() {
+			resp := newWriter("gzip, zstd")
+			resp.Write([]byte(largeBody))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeTrue())
+			Expect("zstd").To(Equal(proxyResp.Headers["Content-Encoding"]))
+			Expect("Accept-Encoding").To(Equal(proxyResp.Headers["Vary"]))
+
+			compressed, err := base64.StdEncoding.DecodeString(proxyResp.Body)
+			Expect(err).To(BeNil())
+			decoder, err := zstd.NewReader(nil)
+			Expect(err).To(BeNil())
+			decoded, err := decoder.DecodeAll(compressed, nil)
+			Expect(err).To(BeNil())
+			Expect(largeBody).To(Equal(string(decoded)))
+		})
+
+		It("Compresses with gzip when the client does not accept zstd", func() {
+			resp := newWriter("gzip, deflate, br")
+			resp.Write([]byte(largeBody))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.IsBase64Encoded).To(BeTrue())
+			Expect("gzip").To(Equal(proxyResp.Headers["Content-Encoding"]))
+
+			compressed, err := base64.StdEncoding.DecodeString(proxyResp.Body)
+			Expect(err).To(BeNil())
+			reader, err := gzip.NewReader(bytes.NewReader(compressed))
+			Expect(err).To(BeNil())
+			decoded, err := ioutil.ReadAll(reader)
+			Expect(err).To(BeNil())
+			Expect(largeBody).To(Equal(string(decoded)))
+		})
+
+		It("Does not compress when the client accepts no supported encoding", func() {
+			for _, acceptEncoding := range []string{"", "br", "zstd;q=0, gzip;q=0"} {
+				resp := newWriter(acceptEncoding)
+				resp.Write([]byte(largeBody))
+
+				proxyResp, err := resp.GetProxyResponse()
+				Expect(err).To(BeNil())
+				Expect(proxyResp.IsBase64Encoded).To(BeFalse())
+				Expect(largeBody).To(Equal(proxyResp.Body))
+				Expect(proxyResp.Headers).ToNot(HaveKey("Content-Encoding"))
+			}
+		})
+
+		It("Does not compress small or binary bodies", func() {
+			resp := newWriter("zstd")
+			resp.Write([]byte("{}"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.Headers).ToNot(HaveKey("Content-Encoding"))
+
+			resp = newWriter("*")
+			resp.Header().Set("Content-Type", "image/png")
+			resp.Write([]byte(largeBody))
+			proxyResp, err = resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.Headers).ToNot(HaveKey("Content-Encoding"))
+		})
+
+		It("Computes integrity headers over the compressed body", func() {
+			resp := newWriter("zstd")
+			resp.SetIntegrityHeader(ContentMD5Header)
+			resp.Write([]byte(largeBody))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			compressed, err := base64.StdEncoding.DecodeString(proxyResp.Body)
+			Expect(err).To(BeNil())
+			digest := md5.Sum(compressed)
+			Expect(base64.StdEncoding.EncodeToString(digest[:])).To(Equal(proxyResp.Headers["Content-Md5"]))
+		})
+	})
+
+	Context("Hop-by-hop headers", func() {
+		It("Strips the hop-by-hop response headers when enabled", func() {
+			opts := ResponseOptions{}
+			opts.StripHopByHopResponseHeaders(true)
+			req, _ := http.NewRequest("GET", "/hello", nil)
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Connection", "close")
+			resp.Header().Set("Transfer-Encoding", "chunked")
+			resp.Header().Set("Upgrade", "h2c")
+			resp.Header().Set("X-Custom", "value")
+			resp.Write([]byte("hello"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.Headers).ToNot(HaveKey("Connection"))
+			Expect(proxyResp.Headers).ToNot(HaveKey("Transfer-Encoding"))
+			Expect(proxyResp.Headers).ToNot(HaveKey("Upgrade"))
+			Expect("value").To(Equal(proxyResp.Headers["X-Custom"]))
+		})
+	})
+
+	Context("HEAD requests", func() {
+		It("Drops the body and keeps its length", func() {
+			opts := ResponseOptions{}
+			opts.SetIntegrityHeader(ETagHeader)
+			req, _ := http.NewRequest("HEAD", "/hello", nil)
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Write([]byte("hello"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.Body).To(BeEmpty())
+			Expect(proxyResp.IsBase64Encoded).To(BeFalse())
+			Expect("5").To(Equal(proxyResp.Headers["Content-Length"]))
+			Expect("text/plain; charset=utf-8").To(Equal(proxyResp.Headers["Content-Type"]))
+			Expect(proxyResp.Headers["Etag"]).ToNot(BeEmpty())
+		})
+
+		It("Keeps the headers set by the handler", func() {
+			req, _ := http.NewRequest("HEAD", "/hello", nil)
+			resp := (&ResponseOptions{}).NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Length", "42")
+			resp.WriteHeader(http.StatusOK)
+
+			proxyResp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResp.Body).To(BeEmpty())
+			Expect("42").To(Equal(proxyResp.Headers["Content-Length"]))
+		})
+	})
+
+	Context("Flushing the response", func() {
+		It("Implements http.Flusher", func() {
+			response := NewProxyResponseWriter()
+			var w http.ResponseWriter = response
+			flusher, ok := w.(http.Flusher)
+			Expect(ok).To(BeTrue())
+
+			flusher.Flush()
+			Expect(response.status).To(Equal(http.StatusOK))
+			fmt.Fprint(w, "data: 1\n\n")
+			flusher.Flush()
+
+			proxyResponse, err := response.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResponse.Body).To(Equal("data: 1\n\n"))
+		})
+	})
+
+	Context("Hijacking the connection", func() {
+		It("Implements http.Hijacker with an error", func() {
+			var w http.ResponseWriter = NewProxyResponseWriter()
+			hijacker, ok := w.(http.Hijacker)
+			Expect(ok).To(BeTrue())
+
+			conn, rw, err := hijacker.Hijack()
+			Expect(err).To(Equal(ErrHijackNotSupported))
+			Expect(conn).To(BeNil())
+			Expect(rw).To(BeNil())
+		})
+	})
+
+	Context("Close notifications", func() {
+		It("Notifies the handler when the request context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			req, err := http.NewRequest(http.MethodGet, "/events", nil)
+			Expect(err).To(BeNil())
+			options := ResponseOptions{}
+			var w http.ResponseWriter = options.NewProxyResponseWriter(req.WithContext(ctx))
+			notifier, ok := w.(http.CloseNotifier)
+			Expect(ok).To(BeTrue())
+
+			closed := notifier.CloseNotify()
+			Consistently(closed).ShouldNot(Receive())
+			cancel()
+			Eventually(closed).Should(Receive(BeTrue()))
+
+			Consistently(NewProxyResponseWriter().CloseNotify()).ShouldNot(Receive())
+		})
+	})
+
+	Context("Copying the response body", func() {
+		It("Implements io.ReaderFrom", func() {
+			response := NewProxyResponseWriter()
+			var w http.ResponseWriter = response
+			_, ok := w.(io.ReaderFrom)
+			Expect(ok).To(BeTrue())
+
+			body := "<html><body>" + strings.Repeat("a", 2048) + "</body></html>"
+			written, err := io.Copy(w, strings.NewReader(body))
+			Expect(err).To(BeNil())
+			Expect(written).To(Equal(int64(len(body))))
+			Expect(response.status).To(Equal(http.StatusOK))
+			Expect(response.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+			Expect(response.body.String()).To(Equal(body))
+
+			empty := NewProxyResponseWriter()
+			written, err = empty.ReadFrom(strings.NewReader(""))
+			Expect(err).To(BeNil())
+			Expect(written).To(Equal(int64(0)))
+			Expect(empty.status).To(Equal(defaultStatusCode))
+		})
+	})
+
+	Context("Response trailers", func() {
+		It("Folds the trailers into the headers", func() {
+			response := NewProxyResponseWriter()
+			response.Header().Set("Trailer", "Grpc-Status")
+			response.Header().Set("Content-Type", "application/grpc-web+proto")
+			response.WriteHeader(http.StatusOK)
+			_, err := response.Write([]byte("message"))
+			Expect(err).To(BeNil())
+			response.Header().Set("Grpc-Status", "0")
+			response.Header().Set(http.TrailerPrefix+"grpc-message", "OK")
+
+			proxyResponse, err := response.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(proxyResponse.Headers["Grpc-Status"]).To(Equal("0"))
+			Expect(proxyResponse.Headers["Grpc-Message"]).To(Equal("OK"))
+			Expect(proxyResponse.Headers).ToNot(HaveKey("Trailer"))
+			Expect(proxyResponse.Headers).ToNot(HaveKey(http.TrailerPrefix + "grpc-message"))
+		})
+	})
+
+	Context("Oversized responses", func() {
+		It("Rejects responses larger than the payload limit", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Write([]byte(strings.Repeat("a", MaxResponsePayloadBytes)))
+
+			_, err := resp.GetProxyResponse()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("exceeds the limit of 6291456 bytes"))
+		})
+
+		It("Calls the overflow handler", func() {
+			opts := ResponseOptions{}
+			var overflowSize int
+			opts.SetResponseOverflowHandler(1024, func(w *ProxyResponseWriter, size int) error {
+				overflowSize = size
+				body := w.Body()[:100]
+				w.Reset()
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("X-Truncated", "true")
+				w.WriteHeader(http.StatusPartialContent)
+				_, err := w.Write(body)
+				return err
+			})
+
+			resp := opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Header().Set("X-Large", "true")
+			resp.Write([]byte(strings.Repeat("a", 2048)))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(overflowSize).To(BeNumerically(">", 2048))
+			Expect(proxyResp.StatusCode).To(Equal(http.StatusPartialContent))
+			Expect(proxyResp.Body).To(Equal(strings.Repeat("a", 100)))
+			Expect(proxyResp.Headers["X-Truncated"]).To(Equal("true"))
+			Expect(proxyResp.Headers).ToNot(HaveKey("X-Large"))
+
+			opts.SetResponseOverflowHandler(1024, OverflowStatus(http.StatusBadGateway))
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Write([]byte(strings.Repeat("a", 2048)))
+			v2Resp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(v2Resp.StatusCode).To(Equal(http.StatusBadGateway))
+			Expect(v2Resp.Body).To(Equal(""))
+
+			resp.Reset()
+			resp.Write([]byte(strings.Repeat("b", 2048)))
+			v2Resp, err = resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(v2Resp.StatusCode).To(Equal(http.StatusBadGateway))
+
+			opts.SetResponseOverflowHandler(1024, func(w *ProxyResponseWriter, size int) error {
+				return nil
+			})
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Write([]byte(strings.Repeat("a", 2048)))
+			_, err = resp.GetALBTargetGroupResponse()
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Applies the CloudFront limit to Lambda@Edge responses", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Write([]byte(strings.Repeat("a", MaxCloudFrontPayloadBytes)))
+			_, err := resp.GetCloudFrontResponse()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("exceeds the limit of 1048576 bytes"))
+
+			opts := ResponseOptions{}
+			opts.SetResponseOverflowHandler(0, OverflowStatus(http.StatusBadGateway))
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Write([]byte(strings.Repeat("a", MaxCloudFrontPayloadBytes)))
+			cfResp, err := resp.GetCloudFrontResponse()
+			Expect(err).To(BeNil())
+			Expect("502").To(Equal(cfResp.Status))
+			Expect("").To(Equal(cfResp.Body))
+		})
+
+		It("Calls the overflow handler for VPC Lattice responses", func() {
+			opts := ResponseOptions{}
+			opts.SetResponseOverflowHandler(1024, OverflowStatus(http.StatusBadGateway))
+			resp := opts.NewProxyResponseWriter(nil)
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Write([]byte(strings.Repeat("a", 2048)))
+			latticeResp, err := resp.GetVPCLatticeResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusBadGateway).To(Equal(latticeResp.StatusCode))
+			Expect("").To(Equal(latticeResp.Body))
+
+			opts.SetResponseOverflowHandler(1024, nil)
+			resp = opts.NewProxyResponseWriter(nil)
+			resp.Write([]byte(strings.Repeat("a", 2048)))
+			_, err = resp.GetVPCLatticeResponse()
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Conditional requests", func() {
+		newWriter := func(method string, ifNoneMatch string) *ProxyResponseWriter {
+			req, _ := http.NewRequest(method, "/report", nil)
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			opts := ResponseOptions{}
+			opts.HandleConditionalRequests(true)
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Type", "text/plain")
+			resp.Header().Set("Cache-Control", "max-age=60")
+			return resp
+		}
+
+		It("Returns 304 responses when the ETag matches", func() {
+			resp := newWriter("GET", "")
+			resp.Write([]byte("report"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(proxyResp.StatusCode))
+			etag := proxyResp.Headers["Etag"]
+			Expect(etag).To(HavePrefix("\""))
+
+			resp = newWriter("GET", "\"other\", W/"+etag)
+			resp.Write([]byte("report"))
+			proxyResp, err = resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusNotModified).To(Equal(proxyResp.StatusCode))
+			Expect("").To(Equal(proxyResp.Body))
+			Expect(etag).To(Equal(proxyResp.Headers["Etag"]))
+			Expect("max-age=60").To(Equal(proxyResp.Headers["Cache-Control"]))
+		})
+
+		It("Uses the ETag set by the handler", func() {
+			resp := newWriter("HEAD", "\"v1\"")
+			resp.Header().Set("ETag", "\"v1\"")
+			resp.Write([]byte("report"))
+			v2Resp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusNotModified).To(Equal(v2Resp.StatusCode))
+
+			resp = newWriter("GET", "\"v1\"")
+			resp.Header().Set("ETag", "\"v2\"")
+			resp.Write([]byte("report"))
+			v2Resp, err = resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(v2Resp.StatusCode))
+			Expect("report").To(Equal(v2Resp.Body))
+		})
+
+		It("Ignores other methods and statuses", func() {
+			resp := newWriter("POST", "*")
+			resp.Write([]byte("report"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(proxyResp.StatusCode))
+			Expect(proxyResp.Headers).ToNot(HaveKey("Etag"))
+
+			resp = newWriter("GET", "*")
+			resp.WriteHeader(http.StatusNotFound)
+			resp.Write([]byte("missing"))
+			proxyResp, err = resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusNotFound).To(Equal(proxyResp.StatusCode))
+		})
+	})
+
+	Context("Pooled writers", func() {
+		It("Reuses released writers without their state", func() {
+			opts := ResponseOptions{}
+			opts.PoolResponseWriters(true)
+			req, _ := http.NewRequest("GET", "/pooled", nil)
+
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("X-First", "1")
+			resp.WriteHeader(http.StatusCreated)
+			resp.Write([]byte("first"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			resp.Release()
+			resp.Release()
+			Expect("first").To(Equal(proxyResp.Body))
+			Expect("1").To(Equal(proxyResp.Headers["X-First"]))
+
+			for i := 0; i < 10; i++ {
+				resp = opts.NewProxyResponseWriter(req)
+				Expect(defaultStatusCode).To(Equal(resp.status))
+				Expect(resp.Header()).To(BeEmpty())
+				Expect(resp.Body()).To(BeEmpty())
+				Expect(resp.request).To(Equal(req))
+				resp.Release()
+			}
+
+			unpooled := NewProxyResponseWriter()
+			unpooled.Write([]byte("data"))
+			unpooled.Release()
+			Expect("data").To(Equal(string(unpooled.Body())))
+		})
+	})
+
+	Context("Write observers", func() {
+		It("Passes each chunk to the observers", func() {
+			opts := ResponseOptions{}
+			var chunks []string
+			opts.AddWriteObserver(func(w *ProxyResponseWriter, chunk []byte) error {
+				Expect(http.StatusOK).To(Equal(w.Status()))
+				chunks = append(chunks, string(chunk))
+				return nil
+			})
+			limit := errors.New("Too large")
+			written := 0
+			opts.AddWriteObserver(func(w *ProxyResponseWriter, chunk []byte) error {
+				if written+len(chunk) > 8 {
+					return limit
+				}
+				written += len(chunk)
+				return nil
+			})
+
+			resp := opts.NewProxyResponseWriter(nil)
+			fmt.Fprint(resp, "abc")
+			fmt.Fprint(resp, "def")
+			n, err := fmt.Fprint(resp, "ghi")
+			Expect(err).To(Equal(limit))
+			Expect(0).To(Equal(n))
+			Expect([]string{"abc", "def", "ghi"}).To(Equal(chunks))
+			Expect("abcdef").To(Equal(string(resp.Body())))
+
+			chunks = nil
+			written = -1024
+			resp = opts.NewProxyResponseWriter(nil)
+			_, err = io.Copy(resp, strings.NewReader(strings.Repeat("a", 1024)))
+			Expect(err).To(BeNil())
+			Expect(len(chunks)).To(BeNumerically(">=", 1))
+			Expect(1024).To(Equal(len(strings.Join(chunks, ""))))
+		})
+	})
+
+	Context("Finalized responses", func() {
+		It("Returns the same response when called twice", func() {
+			req, _ := http.NewRequest("GET", "/hello", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			opts := ResponseOptions{}
+			opts.EnableCompression(0)
+			opts.SetIntegrityHeader(ETagHeader)
+			resp := opts.NewProxyResponseWriter(req)
+			resp.Header().Set("Content-Type", "application/json")
+			resp.Header().Set("Trailer", "X-Checksum")
+			resp.Write([]byte(strings.Repeat("{\"message\":\"hello\"}", 100)))
+			resp.Header().Set("X-Checksum", "abc")
+
+			first, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			second, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(first).To(Equal(second))
+			Expect("gzip").To(Equal(second.Headers["Content-Encoding"]))
+			Expect([]string{"Accept-Encoding"}).To(Equal(second.MultiValueHeaders["Vary"]))
+
+			v2Resp, err := resp.GetAPIGatewayV2HTTPResponse()
+			Expect(err).To(BeNil())
+			Expect(first.Body).To(Equal(v2Resp.Body))
+		})
+
+		It("Discards the response on Reset", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Set("X-Attempt", "1")
+			resp.WriteHeader(http.StatusServiceUnavailable)
+			resp.Write([]byte("retry"))
+			_, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+
+			resp.Reset()
+			Expect(defaultStatusCode).To(Equal(resp.Status()))
+			_, err = resp.GetProxyResponse()
+			Expect(err).ToNot(BeNil())
+
+			resp.Write([]byte("done"))
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(proxyResp.StatusCode))
+			Expect("done").To(Equal(proxyResp.Body))
+			Expect(proxyResp.Headers).ToNot(HaveKey("X-Attempt"))
+		})
+	})
+
+	Context("Informational statuses", func() {
+		It("Ignores early hints", func() {
+			resp := NewProxyResponseWriter()
+			resp.Header().Add("Link", "</style.css>; rel=preload; as=style")
+			resp.WriteHeader(http.StatusEarlyHints)
+			Expect(defaultStatusCode).To(Equal(resp.Status()))
+			resp.Write([]byte("<html></html>"))
+
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusOK).To(Equal(proxyResp.StatusCode))
+			Expect("</style.css>; rel=preload; as=style").To(Equal(proxyResp.Headers["Link"]))
+		})
+
+		It("Ignores 100 Continue before the final status", func() {
+			resp := NewProxyResponseWriter()
+			resp.WriteHeader(http.StatusContinue)
+			_, err := resp.GetProxyResponse()
+			Expect(err).ToNot(BeNil())
+
+			resp.WriteHeader(http.StatusCreated)
+			resp.WriteHeader(http.StatusContinue)
+			proxyResp, err := resp.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect(http.StatusCreated).To(Equal(proxyResp.StatusCode))
+		})
+	})
+
+	Context("Recording responses", func() {
+		It("Passes the writer interfaces through", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			req, err := http.NewRequest(http.MethodGet, "/events", nil)
+			Expect(err).To(BeNil())
+			options := ResponseOptions{}
+			response := options.NewProxyResponseWriter(req.WithContext(ctx))
+			var w http.ResponseWriter = NewResponseRecorder(response)
+
+			_, ok := w.(http.Flusher)
+			Expect(ok).To(BeTrue())
+			_, ok = w.(io.ReaderFrom)
+			Expect(ok).To(BeTrue())
+			_, _, err = w.(http.Hijacker).Hijack()
+			Expect(err).To(Equal(ErrHijackNotSupported))
+
+			closed := w.(http.CloseNotifier).CloseNotify()
+			cancel()
+			Eventually(closed).Should(Receive(BeTrue()))
+
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusCreated)
+			written, err := io.Copy(w, strings.NewReader("<html></html>"))
+			Expect(err).To(BeNil())
+			Expect(int64(13)).To(Equal(written))
+
+			rec := w.(*ResponseRecorder)
+			Expect(http.StatusCreated).To(Equal(rec.StatusCode()))
+			Expect(int64(13)).To(Equal(rec.Written()))
+			Expect(http.ResponseWriter(response)).To(Equal(rec.Unwrap()))
+
+			proxyResp, err := response.GetProxyResponse()
+			Expect(err).To(BeNil())
+			Expect("<html></html>").To(Equal(proxyResp.Body))
+			Expect("text/html; charset=utf-8").To(Equal(proxyResp.Headers["Content-Type"]))
+		})
+
+		It("Copies the body", func() {
+			var body bytes.Buffer
+			rec := NewResponseRecorder(NewProxyResponseWriter())
+			rec.CopyBodyTo(&body)
+			io.Copy(rec, strings.NewReader("hello"))
+			fmt.Fprint(rec, " world")
+			Expect(http.StatusOK).To(Equal(rec.StatusCode()))
+			Expect("hello world").To(Equal(body.String()))
+		})
+
+		It("Applies the content type default without a writer", func() {
+			rec := NewResponseRecorder(nil)
+			rec.Write([]byte("<html></html>"))
+			Expect("text/html; charset=utf-8").To(Equal(rec.Header().Get("Content-Type")))
+
+			rec = NewResponseRecorder(nil)
+			rec.SetContentTypeDefault(FixedContentType, "application/json")
+			rec.Write([]byte("<html></html>"))
+			Expect("application/json").To(Equal(rec.Header().Get("Content-Type")))
+
+			rec = NewResponseRecorder(nil)
+			rec.SetContentTypeDefault(NoContentType, "")
+			rec.Write([]byte("<html></html>"))
+			Expect("").To(Equal(rec.Header().Get("Content-Type")))
+			Consistently(rec.CloseNotify()).ShouldNot(Receive())
+		})
+	})
 })
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rewriteRule replaces the path of the requests matching either a prefix or
+// a regular expression.
+type rewriteRule struct {
+	prefix      string
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// apply returns the rewritten path and true if the rule matches the path.
+func (rule rewriteRule) apply(path string) (string, bool) {
+	if rule.pattern != nil {
+		if !rule.pattern.MatchString(path) {
+			return path, false
+		}
+		return rule.pattern.ReplaceAllString(path, rule.replacement), true
+	}
+	if !strings.HasPrefix(path, rule.prefix) {
+		return path, false
+	}
+	return rule.replacement + strings.TrimPrefix(path, rule.prefix), true
+}
+
+// RewritePrefix instructs the RequestAccessor object to replace the given
+// prefix of the request path with the replacement before sending the request
+// to the framework for routing. For example RewritePrefix("/v1/items",
+// "/items") routes /v1/items/42 to /items/42.
+// Rules are evaluated in the order they were added, after the base path is
+// stripped, and only the first matching rule is applied.
+func (r *RequestAccessor) RewritePrefix(prefix, replacement string) {
+	r.rewriteRules = append(r.rewriteRules, rewriteRule{prefix: prefix, replacement: replacement})
+}
+
+// RewriteRegexp instructs the RequestAccessor object to rewrite the request
+// paths matching the given regular expression, with the replacement syntax
+// of regexp.Regexp.ReplaceAllString: RewriteRegexp(`^/users/(\d+)/profile$`,
+// "/profiles/$1") routes /users/42/profile to /profiles/42.
+// Rules are evaluated in the order they were added, after the base path is
+// stripped, and only the first matching rule is applied.
+// Returns an error if the expression cannot be compiled.
+func (r *RequestAccessor) RewriteRegexp(expr, replacement string) error {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	r.rewriteRules = append(r.rewriteRules, rewriteRule{pattern: pattern, replacement: replacement})
+	return nil
+}
+
+// ClearRewrites removes all the rewrite rules of the RequestAccessor object.
+func (r *RequestAccessor) ClearRewrites() {
+	r.rewriteRules = nil
+}
+
+// rewritePath applies the first matching rewrite rule to the path.
+func (r *RequestAccessor) rewritePath(path string) string {
+	for _, rule := range r.rewriteRules {
+		if rewritten, ok := rule.apply(path); ok {
+			return rewritten
+		}
+	}
+	return path
+}
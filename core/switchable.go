@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SwitchableAPIGatewayRequest holds either a REST API proxy event or an
+// HTTP API event using the 2.0 payload format. The payload format is
+// detected when the request is unmarshalled, so the adapters can expose a
+// single ProxyWithContext method for both versions.
+type SwitchableAPIGatewayRequest struct {
+	v interface{} // nil, *events.APIGatewayProxyRequest or *events.APIGatewayV2HTTPRequest
+}
+
+// NewSwitchableAPIGatewayRequestV1 creates a new SwitchableAPIGatewayRequest
+// from a REST API proxy event.
+func NewSwitchableAPIGatewayRequestV1(v *events.APIGatewayProxyRequest) *SwitchableAPIGatewayRequest {
+	return &SwitchableAPIGatewayRequest{v: v}
+}
+
+// NewSwitchableAPIGatewayRequestV2 creates a new SwitchableAPIGatewayRequest
+// from an HTTP API event.
+func NewSwitchableAPIGatewayRequestV2(v *events.APIGatewayV2HTTPRequest) *SwitchableAPIGatewayRequest {
+	return &SwitchableAPIGatewayRequest{v: v}
+}
+
+// Version1 returns the REST API proxy event, or nil if the request holds an
+// HTTP API event.
+func (s *SwitchableAPIGatewayRequest) Version1() *events.APIGatewayProxyRequest {
+	v, _ := s.v.(*events.APIGatewayProxyRequest)
+	return v
+}
+
+// Version2 returns the HTTP API event, or nil if the request holds a REST
+// API proxy event.
+func (s *SwitchableAPIGatewayRequest) Version2() *events.APIGatewayV2HTTPRequest {
+	v, _ := s.v.(*events.APIGatewayV2HTTPRequest)
+	return v
+}
+
+// MarshalJSON marshals the event held by the request.
+func (s SwitchableAPIGatewayRequest) MarshalJSON() ([]byte, error) {
+	if s.v == nil {
+		return nil, errors.New("No event in switchable request")
+	}
+	return json.Marshal(s.v)
+}
+
+// UnmarshalJSON detects the payload format of the event and unmarshals it
+// into the matching event type.
+func (s *SwitchableAPIGatewayRequest) UnmarshalJSON(b []byte) error {
+	switch DetectEventType(b) {
+	case APIGatewayProxyEvent:
+		v := &events.APIGatewayProxyRequest{}
+		if err := unmarshalEvent(b, v); err != nil {
+			return err
+		}
+		s.v = v
+	case APIGatewayV2HTTPEvent:
+		v := &events.APIGatewayV2HTTPRequest{}
+		if err := unmarshalEvent(b, v); err != nil {
+			return err
+		}
+		s.v = v
+	default:
+		return errors.New("Event is not an API Gateway proxy event")
+	}
+	return nil
+}
+
+// SwitchableAPIGatewayResponse holds the response matching the payload
+// format of a SwitchableAPIGatewayRequest.
+type SwitchableAPIGatewayResponse struct {
+	v interface{} // nil, *events.APIGatewayProxyResponse or *events.APIGatewayV2HTTPResponse
+}
+
+// NewSwitchableAPIGatewayResponseV1 creates a new SwitchableAPIGatewayResponse
+// from a REST API proxy response.
+func NewSwitchableAPIGatewayResponseV1(v *events.APIGatewayProxyResponse) *SwitchableAPIGatewayResponse {
+	return &SwitchableAPIGatewayResponse{v: v}
+}
+
+// NewSwitchableAPIGatewayResponseV2 creates a new SwitchableAPIGatewayResponse
+// from an HTTP API response.
+func NewSwitchableAPIGatewayResponseV2(v *events.APIGatewayV2HTTPResponse) *SwitchableAPIGatewayResponse {
+	return &SwitchableAPIGatewayResponse{v: v}
+}
+
+// Version1 returns the REST API proxy response, or nil if the response
+// holds an HTTP API response.
+func (s *SwitchableAPIGatewayResponse) Version1() *events.APIGatewayProxyResponse {
+	v, _ := s.v.(*events.APIGatewayProxyResponse)
+	return v
+}
+
+// Version2 returns the HTTP API response, or nil if the response holds a
+// REST API proxy response.
+func (s *SwitchableAPIGatewayResponse) Version2() *events.APIGatewayV2HTTPResponse {
+	v, _ := s.v.(*events.APIGatewayV2HTTPResponse)
+	return v
+}
+
+// MarshalJSON marshals the response held by the object.
+func (s SwitchableAPIGatewayResponse) MarshalJSON() ([]byte, error) {
+	if s.v == nil {
+		return nil, errors.New("No response in switchable response")
+	}
+	return json.Marshal(s.v)
+}
+
+// UnmarshalJSON unmarshals a response. As the two response formats can not
+// be told apart, responses with cookies or without multi value headers are
+// unmarshalled as HTTP API responses.
+func (s *SwitchableAPIGatewayResponse) UnmarshalJSON(b []byte) error {
+	shape := struct {
+		MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+		Cookies           []string            `json:"cookies"`
+	}{}
+	if err := json.Unmarshal(b, &shape); err != nil {
+		return err
+	}
+	if shape.Cookies == nil && shape.MultiValueHeaders != nil {
+		v := &events.APIGatewayProxyResponse{}
+		if err := json.Unmarshal(b, v); err != nil {
+			return err
+		}
+		s.v = v
+		return nil
+	}
+	v := &events.APIGatewayV2HTTPResponse{}
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	s.v = v
+	return nil
+}
+
+// SwitchableEventToHTTPRequest converts the event held by the switchable
+// request into an http.Request object using the given context. The values
+// stored by the conversion, such as the path parameters, are added to the
+// context.
+// Returns the request and the function releasing the resources of its
+// context, which must be called once the request is served.
+func (r *RequestAccessor) SwitchableEventToHTTPRequest(ctx context.Context, req SwitchableAPIGatewayRequest) (*http.Request, context.CancelFunc, error) {
+	var httpRequest *http.Request
+	var err error
+	switch v := req.v.(type) {
+	case *events.APIGatewayProxyRequest:
+		httpRequest, err = r.ProxyEventToHTTPRequest(*v)
+	case *events.APIGatewayV2HTTPRequest:
+		httpRequest, err = r.APIGatewayV2HTTPRequestToHTTPRequest(*v)
+	default:
+		return nil, nil, errors.New("No event in switchable request")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := r.withDeadline(ctx)
+	return httpRequest.WithContext(withEventValues(ctx, httpRequest.Context())), cancel, nil
+}
+
+// GetSwitchableResponse converts the data passed to the response writer
+// into a response matching the payload format of the given request.
+func (r *ProxyResponseWriter) GetSwitchableResponse(req SwitchableAPIGatewayRequest) (*SwitchableAPIGatewayResponse, error) {
+	if req.Version2() != nil {
+		resp, err := r.GetAPIGatewayV2HTTPResponse()
+		if err != nil {
+			return nil, err
+		}
+		return NewSwitchableAPIGatewayResponseV2(&resp), nil
+	}
+	resp, err := r.GetProxyResponse()
+	if err != nil {
+		return nil, err
+	}
+	return NewSwitchableAPIGatewayResponseV1(&resp), nil
+}
+
+// GatewayTimeoutSwitchable returns the dafault Gateway Timeout (504)
+// response matching the payload format of the given request.
+func GatewayTimeoutSwitchable(req SwitchableAPIGatewayRequest) *SwitchableAPIGatewayResponse {
+	if req.Version2() != nil {
+		resp := GatewayTimeoutV2()
+		return NewSwitchableAPIGatewayResponseV2(&resp)
+	}
+	resp := GatewayTimeout()
+	return NewSwitchableAPIGatewayResponseV1(&resp)
+}
@@ -0,0 +1,18 @@
+package core
+
+// The API Gateway console fills the request context of the events sent by
+// the "Test" button with placeholders rather than real values.
+const (
+	testInvokeSourceIP   = "test-invoke-source-ip"
+	testInvokeDomainName = "testPrefix.testDomainName"
+)
+
+// testInvokeValue returns an empty string for the placeholders of the
+// console test events, so that the requests get the default host and no
+// remote address instead of invalid ones.
+func testInvokeValue(value string) string {
+	if value == testInvokeSourceIP || value == testInvokeDomainName {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+)
+
+// foldTrailers turns the trailers of the response into regular headers.
+// Lambda responses are returned as a whole, without a chunked body the
+// trailers could follow, so the values of the trailers declared in the
+// Trailer header and set after the body was written are kept as headers,
+// and the trailers set with the http.TrailerPrefix prefix are renamed. The
+// Trailer header is removed since the response no longer has trailers.
+func foldTrailers(headers http.Header) {
+	for h, values := range headers {
+		if !strings.HasPrefix(h, http.TrailerPrefix) {
+			continue
+		}
+		delete(headers, h)
+		if name := strings.TrimPrefix(h, http.TrailerPrefix); name != "" {
+			headers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	headers.Del("Trailer")
+}
@@ -12,9 +12,48 @@ func GatewayTimeout() events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{StatusCode: http.StatusGatewayTimeout}
 }
 
+// ALBGatewayTimeout returns a dafault Gateway Timeout (504) response for
+// Application Load Balancer target groups
+func ALBGatewayTimeout() events.ALBTargetGroupResponse {
+	return events.ALBTargetGroupResponse{
+		StatusCode:        http.StatusGatewayTimeout,
+		StatusDescription: "504 Gateway Timeout",
+	}
+}
+
 // NewLoggedError generates a new error and logs it to stdout
 func NewLoggedError(format string, a ...interface{}) error {
 	err := fmt.Errorf(format, a...)
 	fmt.Println(err.Error())
 	return err
 }
+
+// GatewayTimeoutV2 returns a dafault Gateway Timeout (504) response for
+// API Gateway HTTP APIs
+func GatewayTimeoutV2() events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusGatewayTimeout}
+}
+
+// GatewayTimeoutFunctionURL returns a dafault Gateway Timeout (504) response
+// for Lambda Function URLs
+func GatewayTimeoutFunctionURL() events.LambdaFunctionURLResponse {
+	return events.LambdaFunctionURLResponse{StatusCode: http.StatusGatewayTimeout}
+}
+
+// GatewayTimeoutCloudFront returns a dafault Gateway Timeout (504) response
+// for Lambda@Edge functions
+func GatewayTimeoutCloudFront() CloudFrontResponse {
+	return CloudFrontResponse{
+		Status:            "504",
+		StatusDescription: "Gateway Timeout",
+	}
+}
+
+// GatewayTimeoutVPCLattice returns a dafault Gateway Timeout (504) response
+// for VPC Lattice targets
+func GatewayTimeoutVPCLattice() VPCLatticeResponse {
+	return VPCLatticeResponse{
+		StatusCode:        http.StatusGatewayTimeout,
+		StatusDescription: "504 Gateway Timeout",
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WarmupMatcher returns true if the raw JSON payload of an invocation is a
+// keep-warm ping rather than a request.
+type WarmupMatcher func(payload json.RawMessage) bool
+
+// warmupShape contains the fields of the common keep-warm payloads
+type warmupShape struct {
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Warmer     bool   `json:"warmer"`
+}
+
+// IsWarmupEvent is the built-in WarmupMatcher. It recognizes the payloads of
+// serverless-plugin-warmup, of lambda-warmer and the scheduled events of
+// CloudWatch and EventBridge rules.
+func IsWarmupEvent(payload json.RawMessage) bool {
+	shape := warmupShape{}
+	if err := json.Unmarshal(payload, &shape); err != nil {
+		return false
+	}
+	switch {
+	case shape.Source == "serverless-plugin-warmup":
+		return true
+	case shape.Source == "aws.events" && shape.DetailType == "Scheduled Event":
+		return true
+	}
+	return shape.Warmer
+}
+
+// SetWarmupMatcher instructs the RequestAccessor object to recognize the
+// keep-warm pings with the given matcher. The ProxyRaw and ProxyAny methods
+// of the adapters answer them with WarmupResponse without converting or
+// routing them. Use IsWarmupEvent for the built-in recognition of the common
+// payloads, a nil matcher disables the short circuit, which is the default.
+func (r *RequestAccessor) SetWarmupMatcher(matcher WarmupMatcher) {
+	r.warmupMatcher = matcher
+}
+
+// IsWarmup returns true if the warm-up matcher of the RequestAccessor object
+// recognizes the payload as a keep-warm ping.
+func (r *RequestAccessor) IsWarmup(payload json.RawMessage) bool {
+	return r.warmupMatcher != nil && r.warmupMatcher(payload)
+}
+
+// WarmupResponse returns the empty 200 response sent back to keep-warm pings.
+func WarmupResponse() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// WebsocketContextHeader is the custom header key used to store the
+// API Gateway WebSocket request context. To access the Context properties
+// use the GetAPIGatewayWebsocketContext method of the RequestAccessor object.
+const WebsocketContextHeader = "X-GoLambdaProxy-ApiGw-Websocket-Context"
+
+// WebsocketConnectionIDHeader is the custom header key containing the id of
+// the WebSocket connection, used to post messages back to the client.
+const WebsocketConnectionIDHeader = "X-GoLambdaProxy-Websocket-Connection-Id"
+
+// WebsocketEventTypeHeader is the custom header key containing the type of
+// the WebSocket event: CONNECT, MESSAGE or DISCONNECT.
+const WebsocketEventTypeHeader = "X-GoLambdaProxy-Websocket-Event-Type"
+
+// WebsocketRouteKeyHeader is the custom header key containing the route
+// selected by API Gateway, for example $connect or $default.
+const WebsocketRouteKeyHeader = "X-GoLambdaProxy-Websocket-Route-Key"
+
+// GetAPIGatewayWebsocketContext extracts the API Gateway WebSocket request
+// context from a request's custom header.
+// Returns a populated events.APIGatewayWebsocketProxyRequestContext object
+// from the request.
+func (r *RequestAccessor) GetAPIGatewayWebsocketContext(req *http.Request) (events.APIGatewayWebsocketProxyRequestContext, error) {
+	if req.Header.Get(r.headerName(WebsocketContextHeader)) == "" {
+		return events.APIGatewayWebsocketProxyRequestContext{}, errors.New("No websocket context header in request")
+	}
+	context := events.APIGatewayWebsocketProxyRequestContext{}
+	err := r.decodeJSONHeader(req, WebsocketContextHeader, &context)
+	if err != nil {
+		log.Println("Erorr while unmarshalling websocket context")
+		log.Println(err)
+		return events.APIGatewayWebsocketProxyRequestContext{}, err
+	}
+	return context, nil
+}
+
+// WebsocketProxyRequestToHTTPRequest converts an API Gateway WebSocket event
+// into an http.Request object, so that WebSocket routes can be dispatched
+// through an HTTP router.
+// The route key selected by API Gateway becomes the path of the request,
+// $connect is routed as GET /$connect and a message to the sendmessage route
+// as POST /sendmessage. The connection id, the event type and the route key
+// are added to the request as custom headers, along with the stage variables
+// and the full request context. To access them use the
+// GetAPIGatewayStageVars and GetAPIGatewayWebsocketContext method of the
+// RequestAccessor object.
+func (r *RequestAccessor) WebsocketProxyRequestToHTTPRequest(req events.APIGatewayWebsocketProxyRequest) (*http.Request, error) {
+	method := req.HTTPMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	queryString := ""
+	if len(req.QueryStringParameters) > 0 {
+		query := url.Values{}
+		for q, value := range req.QueryStringParameters {
+			query.Set(q, value)
+		}
+		queryString = "?" + encodeQuery(query)
+	}
+
+	path := r.requestURL("/"+url.PathEscape(req.RequestContext.RouteKey)) + queryString
+	httpRequest, err := http.NewRequest(strings.ToUpper(method), path, nil)
+	if err != nil {
+		fmt.Printf("Could not convert websocket request %s to http.Request\n", req.RequestContext.RouteKey)
+		log.Println(err)
+		return nil, err
+	}
+
+	for h := range req.Headers {
+		httpRequest.Header.Add(h, req.Headers[h])
+	}
+	if r.stripHopByHop {
+		removeHopByHopHeaders(httpRequest.Header)
+	}
+	r.removeUnsupportedHeaders(httpRequest.Header)
+	r.splitListHeaders(httpRequest.Header)
+	r.setHost(httpRequest, req.RequestContext.DomainName)
+	r.setForwarded(httpRequest)
+	if err := r.setBody(httpRequest, req.Body, req.IsBase64Encoded); err != nil {
+		return nil, err
+	}
+	setRemoteAddr(httpRequest, req.RequestContext.Identity.SourceIP)
+
+	if err := r.addJSONHeader(httpRequest, WebsocketContextHeader, req.RequestContext); err != nil {
+		return nil, err
+	}
+	if err := r.addJSONHeader(httpRequest, APIGwStageVarsHeader, stageVariables(req.StageVariables)); err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Add(r.headerName(WebsocketConnectionIDHeader), req.RequestContext.ConnectionID)
+	httpRequest.Header.Add(r.headerName(WebsocketEventTypeHeader), req.RequestContext.EventType)
+	httpRequest.Header.Add(r.headerName(WebsocketRouteKeyHeader), req.RequestContext.RouteKey)
+
+	httpRequest = r.withRequestID(httpRequest, req.RequestContext.RequestID)
+	return r.withRequestValues(httpRequest, headerNames(req.Headers, req.MultiValueHeaders)), nil
+}
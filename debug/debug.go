@@ -0,0 +1,153 @@
+// Package debug exposes the net/http/pprof profiles and the expvar variables
+// of a running function through API Gateway. The endpoints are disabled
+// until at least one gate is configured: a secret that must be sent in a
+// request header, a stage variable that must be set to "true", or both.
+//
+//	router := debug.New()
+//	router.SetSecret(secrets.NewClient().Secret("my-api/debug-token"))
+//	adapter := httpadapter.New(router.Handler(mux))
+//
+// Profiles are then available under /debug/pprof/ and the variables under
+// /debug/vars. API Gateway limits invocations to 29 seconds, CPU profiles
+// and traces must be requested with a shorter seconds parameter.
+package debug
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+)
+
+// DefaultPrefix is the path under which the debug endpoints are served
+const DefaultPrefix = "/debug"
+
+// DefaultHeader is the request header that must contain the secret
+const DefaultHeader = "X-Debug-Token"
+
+// invocations counts the requests received by the Router handlers since
+// the container started. It is published as the "invocations" variable.
+var invocations = expvar.NewInt("invocations")
+
+// Router serves the debug endpoints to authorized requests and passes all
+// the other requests to the wrapped handler.
+type Router struct {
+	core.RequestAccessor
+
+	prefix        string
+	header        string
+	secret        secrets.Source
+	stageVariable string
+}
+
+// New creates a new Router with the default prefix and header. The
+// endpoints stay disabled until SetSecret or SetStageVariable is called.
+func New() *Router {
+	return &Router{
+		prefix: DefaultPrefix,
+		header: DefaultHeader,
+	}
+}
+
+// SetPrefix sets the path under which the debug endpoints are served.
+func (r *Router) SetPrefix(prefix string) {
+	r.prefix = "/" + strings.Trim(prefix, "/")
+}
+
+// SetHeader sets the name of the request header that must contain the
+// secret.
+func (r *Router) SetHeader(name string) {
+	r.header = name
+}
+
+// SetSecret gates the debug endpoints behind the value of the given source.
+// Requests must send the value in the header set with SetHeader.
+func (r *Router) SetSecret(source secrets.Source) {
+	r.secret = source
+}
+
+// SetStageVariable gates the debug endpoints behind the given API Gateway
+// stage variable. The endpoints are only served when the variable of the
+// stage that received the request is set to "true".
+func (r *Router) SetStageVariable(name string) {
+	r.stageVariable = name
+}
+
+// Handler returns an http.Handler that serves the debug endpoints to the
+// requests that pass all the configured gates. Other requests, including
+// unauthorized requests to the debug endpoints, are sent to next so that
+// the endpoints cannot be discovered.
+func (r *Router) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		invocations.Add(1)
+
+		if req.URL.Path != r.prefix && !strings.HasPrefix(req.URL.Path, r.prefix+"/") {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if !r.authorized(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		r.serveDebug(w, req)
+	})
+}
+
+func (r *Router) authorized(req *http.Request) bool {
+	if r.secret == nil && r.stageVariable == "" {
+		return false
+	}
+
+	if r.secret != nil {
+		expected, err := r.secret.Value()
+		if err != nil {
+			log.Println("Could not load debug secret")
+			log.Println(err)
+			return false
+		}
+		token := req.Header.Get(r.header)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return false
+		}
+	}
+
+	if r.stageVariable != "" {
+		stageVars, err := r.GetAPIGatewayStageVars(req)
+		if err != nil || stageVars[r.stageVariable] != "true" {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Router) serveDebug(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, r.prefix)
+	if path == "/vars" {
+		expvar.Handler().ServeHTTP(w, req)
+		return
+	}
+	if path != "/pprof" && !strings.HasPrefix(path, "/pprof/") {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch name := strings.TrimPrefix(strings.TrimPrefix(path, "/pprof"), "/"); name {
+	case "":
+		pprof.Index(w, req)
+	case "cmdline":
+		pprof.Cmdline(w, req)
+	case "profile":
+		pprof.Profile(w, req)
+	case "symbol":
+		pprof.Symbol(w, req)
+	case "trace":
+		pprof.Trace(w, req)
+	default:
+		pprof.Handler(name).ServeHTTP(w, req)
+	}
+}
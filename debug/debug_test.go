@@ -0,0 +1,97 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/debug"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug router tests", func() {
+	app := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("app"))
+	})
+
+	Context("Gating the endpoints", func() {
+		It("Is disabled unless a gate is configured", func() {
+			adapter := httpadapter.New(debug.New().Handler(app))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/debug/vars", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("app"))
+		})
+
+		It("Requires the secret header", func() {
+			router := debug.New()
+			router.SetSecret(secrets.StaticSource("s3cret"))
+			adapter := httpadapter.New(router.Handler(app))
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/debug/vars",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{debug.DefaultHeader: "wrong"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("app"))
+
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/debug/vars",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{debug.DefaultHeader: "s3cret"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			vars := map[string]interface{}{}
+			Expect(json.Unmarshal([]byte(resp.Body), &vars)).To(BeNil())
+			Expect(vars).To(HaveKey("invocations"))
+			Expect(vars).To(HaveKey("memstats"))
+		})
+
+		It("Requires the stage variable", func() {
+			router := debug.New()
+			router.SetStageVariable("debug")
+			router.SetPrefix("/_internal/")
+			adapter := httpadapter.New(router.Handler(app))
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:           "/_internal/pprof/goroutine",
+				HTTPMethod:     "GET",
+				StageVariables: map[string]string{"debug": "false"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("app"))
+
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:                  "/_internal/pprof/goroutine",
+				HTTPMethod:            "GET",
+				QueryStringParameters: map[string]string{"debug": "1"},
+				StageVariables:        map[string]string{"debug": "true"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(ContainSubstring("goroutine profile"))
+		})
+	})
+
+	Context("Serving requests", func() {
+		It("Passes other paths to the application", func() {
+			router := debug.New()
+			router.SetSecret(secrets.StaticSource("s3cret"))
+			adapter := httpadapter.New(router.Handler(app))
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/debugging",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{debug.DefaultHeader: "s3cret"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("app"))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+// Package eventbridge dispatches EventBridge and CloudWatch Events through
+// an HTTP handler, so scheduled and bus-delivered events can be handled by
+// the same framework routes as the API. Each event becomes a POST request
+// with the detail of the event as the JSON body:
+//
+//	adapter := eventbridge.New(router)
+//	adapter.Map("Scheduled Event", "/jobs/cleanup")
+//	lambda.Start(adapter.Handle)
+//
+// Events with an unmapped detail-type are posted to /events/{detail-type}.
+// Handle returns an error when the handler answers with a status code of
+// 400 or above, so that EventBridge retries the invocation.
+package eventbridge
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultPrefix is the prefix of the path of the events whose detail-type
+// is not mapped
+const DefaultPrefix = "/events"
+
+// SourceHeader is the custom header key containing the source of the event
+const SourceHeader = "X-GoLambdaProxy-Event-Source"
+
+// DetailTypeHeader is the custom header key containing the detail-type of
+// the event
+const DetailTypeHeader = "X-GoLambdaProxy-Event-Detail-Type"
+
+// IDHeader is the custom header key containing the id of the event
+const IDHeader = "X-GoLambdaProxy-Event-Id"
+
+// TimeHeader is the custom header key containing the time of the event in
+// the RFC 3339 format
+const TimeHeader = "X-GoLambdaProxy-Event-Time"
+
+type contextKey struct{}
+
+// EventFromContext returns the event that generated the request.
+func EventFromContext(ctx context.Context) (events.CloudWatchEvent, bool) {
+	event, ok := ctx.Value(contextKey{}).(events.CloudWatchEvent)
+	return event, ok
+}
+
+// Adapter converts events into requests for the wrapped handler.
+type Adapter struct {
+	handler http.Handler
+	prefix  string
+	paths   map[string]string
+}
+
+// New creates a new Adapter that sends the events to the given handler.
+func New(handler http.Handler) *Adapter {
+	return &Adapter{
+		handler: handler,
+		prefix:  DefaultPrefix,
+		paths:   make(map[string]string),
+	}
+}
+
+// Map sets the path of the requests generated for the events with the
+// given detail-type.
+func (a *Adapter) Map(detailType string, path string) {
+	a.paths[detailType] = path
+}
+
+// SetPrefix sets the prefix of the path of the events whose detail-type is
+// not mapped.
+func (a *Adapter) SetPrefix(prefix string) {
+	a.prefix = prefix
+}
+
+// Handle dispatches the event through the handler. Returns an error if
+// the request is answered with a status code of 400 or above.
+func (a *Adapter) Handle(event events.CloudWatchEvent) error {
+	req, err := a.EventToHTTPRequest(event)
+	if err != nil {
+		return core.NewLoggedError("Could not convert event to request: %v", err)
+	}
+
+	w := core.NewProxyResponseWriter()
+	a.handler.ServeHTTP(http.ResponseWriter(w), req)
+	resp, err := w.GetProxyResponse()
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		return core.NewLoggedError("Handler returned %d for event %s: %s", resp.StatusCode, event.ID, resp.Body)
+	}
+	return nil
+}
+
+// EventToHTTPRequest converts an event into a POST http.Request object.
+// The event is stored in the context of the request and can be read with
+// EventFromContext.
+func (a *Adapter) EventToHTTPRequest(event events.CloudWatchEvent) (*http.Request, error) {
+	path, ok := a.paths[event.DetailType]
+	if !ok {
+		path = a.prefix + "/" + url.PathEscape(event.DetailType)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, core.DefaultServerAddress+path, bytes.NewReader(event.Detail))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SourceHeader, event.Source)
+	req.Header.Set(DetailTypeHeader, event.DetailType)
+	req.Header.Set(IDHeader, event.ID)
+	req.Header.Set(TimeHeader, event.Time.Format(time.RFC3339))
+
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, event)), nil
+}
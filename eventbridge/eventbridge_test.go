@@ -0,0 +1,60 @@
+package eventbridge_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/eventbridge"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventBridge adapter tests", func() {
+	var received string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		event, _ := eventbridge.EventFromContext(req.Context())
+		received = req.Method + " " + req.URL.EscapedPath() + " " + string(body) + " " + req.Header.Get(eventbridge.SourceHeader) + " " + event.ID
+		if req.URL.Path == "/jobs/broken" {
+			http.Error(w, "broken", http.StatusInternalServerError)
+		}
+	})
+
+	Context("Dispatching events", func() {
+		It("Posts the detail to the mapped path", func() {
+			adapter := eventbridge.New(mux)
+			adapter.Map("Scheduled Event", "/jobs/cleanup")
+			err := adapter.Handle(events.CloudWatchEvent{
+				ID:         "1",
+				DetailType: "Scheduled Event",
+				Source:     "aws.events",
+				Detail:     json.RawMessage(`{}`),
+			})
+			Expect(err).To(BeNil())
+			Expect(received).To(Equal("POST /jobs/cleanup {} aws.events 1"))
+		})
+
+		It("Derives the path from the detail-type", func() {
+			adapter := eventbridge.New(mux)
+			err := adapter.Handle(events.CloudWatchEvent{
+				ID:         "2",
+				DetailType: "Order Created",
+				Source:     "shop",
+				Detail:     json.RawMessage(`{"id":1}`),
+			})
+			Expect(err).To(BeNil())
+			Expect(received).To(Equal(`POST /events/Order%20Created {"id":1} shop 2`))
+		})
+
+		It("Returns an error when the handler fails", func() {
+			adapter := eventbridge.New(mux)
+			adapter.SetPrefix("/jobs")
+			err := adapter.Handle(events.CloudWatchEvent{ID: "3", DetailType: "broken"})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package featureflags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultAppConfigEndpoint is the local endpoint of the AWS AppConfig
+// Lambda extension.
+const DefaultAppConfigEndpoint = "http://localhost:2772"
+
+// DefaultAppConfigTTL is how long the AppConfigProvider caches the flags
+// retrieved from the extension.
+const DefaultAppConfigTTL = 30 * time.Second
+
+// AppConfigProvider loads flags from an AWS AppConfig feature flags
+// configuration profile through the AppConfig Lambda extension. The
+// extension must be added to the function as a layer. The flags are
+// cached in the container for the configured TTL.
+type AppConfigProvider struct {
+	endpoint    string
+	application string
+	environment string
+	profile     string
+	ttl         time.Duration
+	client      *http.Client
+
+	mu      sync.Mutex
+	flags   Flags
+	expires time.Time
+}
+
+// NewAppConfigProvider creates a new AppConfigProvider for the given
+// application, environment and configuration profile.
+func NewAppConfigProvider(application string, environment string, profile string) *AppConfigProvider {
+	return &AppConfigProvider{
+		endpoint:    DefaultAppConfigEndpoint,
+		application: application,
+		environment: environment,
+		profile:     profile,
+		ttl:         DefaultAppConfigTTL,
+		client:      &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// SetEndpoint overrides the address of the AppConfig extension.
+func (p *AppConfigProvider) SetEndpoint(endpoint string) {
+	p.endpoint = endpoint
+}
+
+// SetTTL sets how long the flags are cached.
+func (p *AppConfigProvider) SetTTL(ttl time.Duration) {
+	p.ttl = ttl
+}
+
+// Flags implementation from the Provider interface. When the extension
+// cannot be reached the last known flags are returned and kept for another
+// TTL, so that the requests do not wait for the extension while it is down.
+func (p *AppConfigProvider) Flags(req *http.Request) (Flags, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.flags != nil && time.Now().Before(p.expires) {
+		return p.flags, nil
+	}
+
+	flags, err := p.fetch()
+	if err != nil {
+		if p.flags != nil {
+			p.expires = time.Now().Add(p.ttl)
+			return p.flags, nil
+		}
+		return Flags{}, err
+	}
+	p.flags = flags
+	p.expires = time.Now().Add(p.ttl)
+	return flags, nil
+}
+
+// fetch retrieves the configuration from the extension. AppConfig feature
+// flags are returned as an object keyed by flag name: {"name": {"enabled": true}}
+func (p *AppConfigProvider) fetch() (Flags, error) {
+	url := fmt.Sprintf("%s/applications/%s/environments/%s/configurations/%s",
+		p.endpoint, p.application, p.environment, p.profile)
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AppConfig extension returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	config := map[string]struct {
+		Enabled bool `json:"enabled"`
+	}{}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+
+	flags := Flags{}
+	for name, flag := range config {
+		flags[name] = flag.Enabled
+	}
+	return flags, nil
+}
@@ -0,0 +1,125 @@
+// Package featureflags evaluates feature flags for each request and stores
+// the results in the request context, so that handlers served by any of the
+// adapters read flags the same way. Flags are loaded from a pluggable
+// Provider; implementations backed by API Gateway stage variables and by
+// the AWS AppConfig Lambda extension are included.
+package featureflags
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultStageVariablePrefix is the prefix of the stage variables read by
+// the StageVariableProvider.
+const DefaultStageVariablePrefix = "flag_"
+
+type contextKey struct{}
+
+// Flags is the set of feature flags evaluated for a request.
+type Flags map[string]bool
+
+// Enabled returns true if the flag exists and is enabled.
+func (f Flags) Enabled(name string) bool {
+	return f[name]
+}
+
+// FromContext returns the flags stored in the context by the Middleware.
+// When the context has no flags an empty set is returned.
+func FromContext(ctx context.Context) Flags {
+	if flags, ok := ctx.Value(contextKey{}).(Flags); ok {
+		return flags
+	}
+	return Flags{}
+}
+
+// Enabled is a shortcut for FromContext(ctx).Enabled(name).
+func Enabled(ctx context.Context, name string) bool {
+	return FromContext(ctx).Enabled(name)
+}
+
+// Provider evaluates the feature flags for a request.
+type Provider interface {
+	Flags(req *http.Request) (Flags, error)
+}
+
+// ProviderFunc is an adapter that allows the use of ordinary functions as
+// flag providers.
+type ProviderFunc func(req *http.Request) (Flags, error)
+
+// Flags calls f(req).
+func (f ProviderFunc) Flags(req *http.Request) (Flags, error) {
+	return f(req)
+}
+
+// Middleware evaluates the feature flags of each request before passing it
+// to the wrapped handler.
+type Middleware struct {
+	provider Provider
+}
+
+// New creates a new Middleware that uses the given provider.
+func New(provider Provider) *Middleware {
+	return &Middleware{provider: provider}
+}
+
+// Handler returns an http.Handler that stores the flags in the request
+// context and calls the next handler. Provider errors are logged and the
+// request is served with an empty set of flags, disabling all features.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flags, err := m.provider.Flags(req)
+		if err != nil {
+			log.Println("Could not evaluate feature flags")
+			log.Println(err)
+			flags = Flags{}
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextKey{}, flags)))
+	})
+}
+
+// StageVariableProvider reads flags from the API Gateway stage variables
+// whose name starts with the configured prefix. The value of the variable
+// is parsed with strconv.ParseBool, for example "flag_newCheckout=true"
+// enables the newCheckout flag.
+type StageVariableProvider struct {
+	core.RequestAccessor
+
+	prefix string
+}
+
+// NewStageVariableProvider creates a new StageVariableProvider that reads
+// the stage variables starting with the given prefix. An empty prefix
+// defaults to DefaultStageVariablePrefix.
+func NewStageVariableProvider(prefix string) *StageVariableProvider {
+	if prefix == "" {
+		prefix = DefaultStageVariablePrefix
+	}
+	return &StageVariableProvider{prefix: prefix}
+}
+
+// Flags implementation from the Provider interface.
+func (p *StageVariableProvider) Flags(req *http.Request) (Flags, error) {
+	flags := Flags{}
+	stageVars, err := p.GetAPIGatewayStageVars(req)
+	if err != nil {
+		// no stage variables in the request, all flags are disabled
+		return flags, nil
+	}
+	for name, value := range stageVars {
+		if !strings.HasPrefix(name, p.prefix) {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			continue
+		}
+		flags[strings.TrimPrefix(name, p.prefix)] = enabled
+	}
+	return flags, nil
+}
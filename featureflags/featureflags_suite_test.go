@@ -0,0 +1,13 @@
+package featureflags_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Feature Flags Suite")
+}
@@ -0,0 +1,123 @@
+package featureflags_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/featureflags"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Feature flags tests", func() {
+	showFlag := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%t", featureflags.Enabled(req.Context(), "newCheckout"))
+	})
+
+	Context("Stage variable provider", func() {
+		It("Reads prefixed stage variables", func() {
+			adapter := httpadapter.New(featureflags.New(featureflags.NewStageVariableProvider("")).Handler(showFlag))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				StageVariables: map[string]string{
+					"flag_newCheckout": "true",
+					"newCheckout":      "false",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("true"))
+		})
+
+		It("Disables flags without stage variables", func() {
+			adapter := httpadapter.New(featureflags.New(featureflags.NewStageVariableProvider("ff_")).Handler(showFlag))
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/", HTTPMethod: "GET"})
+			Expect(resp.Body).To(Equal("false"))
+		})
+	})
+
+	Context("Provider errors", func() {
+		It("Serves the request with all flags disabled", func() {
+			failing := featureflags.ProviderFunc(func(req *http.Request) (featureflags.Flags, error) {
+				return nil, errors.New("unavailable")
+			})
+			adapter := httpadapter.New(featureflags.New(failing).Handler(showFlag))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("false"))
+		})
+	})
+
+	Context("AppConfig provider", func() {
+		It("Loads and caches flags from the extension", func() {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				Expect(req.URL.Path).To(Equal("/applications/app/environments/prod/configurations/flags"))
+				w.Write([]byte(`{"newCheckout":{"enabled":true},"darkMode":{"enabled":false}}`))
+			}))
+			defer server.Close()
+
+			provider := featureflags.NewAppConfigProvider("app", "prod", "flags")
+			provider.SetEndpoint(server.URL)
+			provider.SetTTL(time.Minute)
+
+			flags, err := provider.Flags(nil)
+			Expect(err).To(BeNil())
+			Expect(flags.Enabled("newCheckout")).To(BeTrue())
+			Expect(flags.Enabled("darkMode")).To(BeFalse())
+
+			_, err = provider.Flags(nil)
+			Expect(err).To(BeNil())
+			Expect(1).To(Equal(calls))
+		})
+
+		It("Keeps serving the last flags while the extension is down", func() {
+			calls := 0
+			failing := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				if failing {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte(`{"newCheckout":{"enabled":true}}`))
+			}))
+			defer server.Close()
+
+			provider := featureflags.NewAppConfigProvider("app", "prod", "flags")
+			provider.SetEndpoint(server.URL)
+			provider.SetTTL(50 * time.Millisecond)
+			_, err := provider.Flags(nil)
+			Expect(err).To(BeNil())
+
+			failing = true
+			time.Sleep(100 * time.Millisecond)
+			for i := 0; i < 3; i++ {
+				flags, err := provider.Flags(nil)
+				Expect(err).To(BeNil())
+				Expect(flags.Enabled("newCheckout")).To(BeTrue())
+			}
+			Expect(calls).To(Equal(2))
+		})
+
+		It("Returns an error when the extension fails", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer server.Close()
+
+			provider := featureflags.NewAppConfigProvider("app", "prod", "flags")
+			provider.SetEndpoint(server.URL)
+			_, err := provider.Flags(nil)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -4,6 +4,8 @@
 package ginadapter
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -16,6 +18,7 @@ import (
 // creates a proxy response object from the http.ResponseWriter
 type GinLambda struct {
 	core.RequestAccessor
+	core.ResponseOptions
 
 	ginEngine *gin.Engine
 }
@@ -37,8 +40,253 @@ func (g *GinLambda) Proxy(req events.APIGatewayProxyRequest) (events.APIGatewayP
 		return core.GatewayTimeout(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
 	}
 
-	respWriter := core.NewProxyResponseWriter()
-	g.ginEngine.ServeHTTP(http.ResponseWriter(respWriter), ginRequest)
+	return g.proxyRequest(ginRequest)
+}
+
+// ProxyRaw receives the raw JSON payload of an API Gateway proxy event,
+// transforms it into an http.Request object, and sends it to the gin.Engine
+// for routing. The original payload is available to handlers through the
+// GetRawEvent method. Keep-warm pings recognized by the warm-up matcher are
+// answered with core.WarmupResponse without routing.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyRaw(payload json.RawMessage) (events.APIGatewayProxyResponse, error) {
+	if g.IsWarmup(payload) {
+		return core.WarmupResponse(), nil
+	}
+
+	ginRequest, err := g.RawEventToHTTPRequest(payload)
+
+	if err != nil {
+		return core.GatewayTimeout(), core.NewLoggedError("Could not convert raw event to request: %v", err)
+	}
+
+	return g.proxyRequest(ginRequest)
+}
+
+// ProxyALB receives an Application Load Balancer target group event,
+// transforms it into an http.Request object, and sends it to the gin.Engine
+// for routing. Requests for the health check path set with SetHealthCheckPath
+// are answered directly.
+// It returns a target group response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyALB(event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	if resp, ok := g.ALBHealthCheckResponse(event); ok {
+		return resp, nil
+	}
+
+	ginRequest, err := g.ALBTargetGroupRequestToHTTPRequest(event)
+	if err != nil {
+		return core.ALBGatewayTimeout(), core.NewLoggedError("Could not convert ALB event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetALBTargetGroupResponse()
+	if err != nil {
+		return core.ALBGatewayTimeout(), core.NewLoggedError("Error while generating ALB response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyV2 receives an API Gateway HTTP API event using the 2.0 payload
+// format, transforms it into an http.Request object, and sends it to the
+// gin.Engine for routing.
+// It returns a v2 response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyV2(event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	ginRequest, err := g.APIGatewayV2HTTPRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Could not convert v2 event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetAPIGatewayV2HTTPResponse()
+	if err != nil {
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Error while generating v2 response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyFunctionURL receives a Lambda Function URL event, transforms it into
+// an http.Request object, and sends it to the gin.Engine for routing.
+// It returns a function URL response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyFunctionURL(event events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	ginRequest, err := g.LambdaFunctionURLRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutFunctionURL(), core.NewLoggedError("Could not convert function URL event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetLambdaFunctionURLResponse()
+	if err != nil {
+		return core.GatewayTimeoutFunctionURL(), core.NewLoggedError("Error while generating function URL response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyWebsocket receives an API Gateway WebSocket event, transforms it into
+// an http.Request object, and sends it to the gin.Engine for routing on the
+// route key.
+// It returns a proxy response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyWebsocket(event events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	ginRequest, err := g.WebsocketProxyRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeout(), core.NewLoggedError("Could not convert websocket event to request: %v", err)
+	}
+
+	return g.proxyRequest(ginRequest)
+}
+
+// ProxyCloudFront receives a Lambda@Edge CloudFront request event, transforms
+// it into an http.Request object, and sends it to the gin.Engine for routing.
+// It returns a CloudFront response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyCloudFront(event core.CloudFrontEvent) (core.CloudFrontResponse, error) {
+	ginRequest, err := g.CloudFrontEventToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not convert CloudFront event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetCloudFrontResponse()
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Error while generating CloudFront response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyVPCLattice receives a VPC Lattice target event, transforms it into an
+// http.Request object, and sends it to the gin.Engine for routing.
+// It returns a VPC Lattice response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyVPCLattice(event core.VPCLatticeRequest) (core.VPCLatticeResponse, error) {
+	ginRequest, err := g.VPCLatticeRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutVPCLattice(), core.NewLoggedError("Could not convert VPC Lattice event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetVPCLatticeResponse()
+	if err != nil {
+		return core.GatewayTimeoutVPCLattice(), core.NewLoggedError("Error while generating VPC Lattice response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyAny receives the raw JSON payload of a REST API, HTTP API, ALB or
+// Function URL event, detects its type, transforms it into an http.Request
+// object, and sends it to the gin.Engine for routing. The same function can
+// therefore be attached to any of these triggers. Keep-warm pings recognized
+// by the warm-up matcher are answered with core.WarmupResponse.
+// It returns the response object matching the type of the event.
+func (g *GinLambda) ProxyAny(payload json.RawMessage) (interface{}, error) {
+	if g.IsWarmup(payload) {
+		return core.WarmupResponse(), nil
+	}
+
+	ginRequest, eventType, err := g.AnyEventToHTTPRequest(payload)
+	if err != nil {
+		return core.GatewayTimeoutFor(eventType), core.NewLoggedError("Could not convert %s event to request: %v", eventType, err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetResponse(eventType)
+	if err != nil {
+		return core.GatewayTimeoutFor(eventType), core.NewLoggedError("Error while generating %s response: %v", eventType, err)
+	}
+
+	return resp, nil
+}
+
+// ProxyWithContext receives a REST API or HTTP API event, in either payload
+// format, transforms it into an http.Request object with the given context,
+// and sends it to the gin.Engine for routing.
+// It returns a response object matching the payload format of the event.
+func (g *GinLambda) ProxyWithContext(ctx context.Context, event core.SwitchableAPIGatewayRequest) (*core.SwitchableAPIGatewayResponse, error) {
+	ginRequest, cancel, err := g.SwitchableEventToHTTPRequest(ctx, event)
+	if err != nil {
+		return core.GatewayTimeoutSwitchable(event), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+	defer cancel()
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetSwitchableResponse(event)
+	if err != nil {
+		return core.GatewayTimeoutSwitchable(event), core.NewLoggedError("Error while generating proxy response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyAuthorizer receives an API Gateway REQUEST authorizer event,
+// transforms it into an http.Request object, and sends it to the gin.Engine
+// for routing. The handler writes the authorizer response as JSON, or
+// responds with a 401 status code to deny access.
+// It returns the authorizer response decoded from the http.ResponseWriter.
+func (g *GinLambda) ProxyAuthorizer(event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	ginRequest, err := g.CustomAuthorizerRequestToHTTPRequest(event)
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, core.NewLoggedError("Could not convert authorizer event to request: %v", err)
+	}
+
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
+
+	resp, err := respWriter.GetCustomAuthorizerResponse()
+	if err == core.ErrUnauthorized {
+		return resp, err
+	}
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, core.NewLoggedError("Error while generating authorizer response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ProxyCloudFrontOriginResponse receives a Lambda@Edge CloudFront origin
+// response event, transforms its request into an http.Request object, and
+// sends it to the gin.Engine for routing. The http.ResponseWriter is initialized
+// with the status and the headers of the origin response, so handlers only
+// need to apply their changes.
+// It returns a CloudFront response object generated from the http.ResponseWriter.
+func (g *GinLambda) ProxyCloudFrontOriginResponse(event core.CloudFrontEvent) (core.CloudFrontResponse, error) {
+	ginRequest, err := g.CloudFrontEventToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not convert CloudFront event to request: %v", err)
+	}
+
+	respWriter, err := g.NewCloudFrontOriginResponseWriter(ginRequest, event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not read CloudFront origin response: %v", err)
+	}
+	defer respWriter.Release()
+	g.EnforceMethodPolicy(g.EnforceMaxRequestBodyBytes(g.ginEngine)).ServeHTTP(http.ResponseWriter(respWriter), ginRequest)
+
+	resp, err := respWriter.GetCloudFrontOriginResponse()
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Error while generating CloudFront response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (g *GinLambda) proxyRequest(ginRequest *http.Request) (events.APIGatewayProxyResponse, error) {
+	respWriter := g.serve(ginRequest)
+	defer respWriter.Release()
 
 	proxyResponse, err := respWriter.GetProxyResponse()
 	if err != nil {
@@ -47,3 +295,9 @@ func (g *GinLambda) Proxy(req events.APIGatewayProxyRequest) (events.APIGatewayP
 
 	return proxyResponse, nil
 }
+
+func (g *GinLambda) serve(ginRequest *http.Request) *core.ProxyResponseWriter {
+	respWriter := g.NewProxyResponseWriter(ginRequest)
+	g.EnforceRateLimit(g.EnforceMethodPolicy(g.EnforceMaxRequestBodyBytes(g.CacheResponses(g.ginEngine)))).ServeHTTP(http.ResponseWriter(respWriter), ginRequest)
+	return respWriter
+}
@@ -36,4 +36,62 @@ var _ = Describe("GinLambda tests", func() {
 			Expect(resp.StatusCode).To(Equal(200))
 		})
 	})
+
+	Context("HTTP API v2 request", func() {
+		It("Routes on the raw path and returns cookies", func() {
+			r := gin.Default()
+			r.GET("/users/:id", func(c *gin.Context) {
+				session, _ := c.Cookie("session")
+				c.SetCookie("seen", "1", 0, "/", "", false, false)
+				c.String(200, c.Param("id")+"|"+c.Query("expand")+"|"+session)
+			})
+
+			adapter := ginadapter.New(r)
+
+			resp, err := adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RouteKey:       "GET /users/{id}",
+				RawPath:        "/users/42",
+				RawQueryString: "expand=true",
+				Cookies:        []string{"session=abc", "other=1"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("42|true|abc"))
+			Expect(resp.Cookies).To(Equal([]string{"seen=1; Path=/"}))
+			Expect(resp.Headers).ToNot(HaveKey("Set-Cookie"))
+		})
+	})
+
+	Context("Client address", func() {
+		It("Returns the source IP of the event as the client IP", func() {
+			r := gin.Default()
+			r.GET("/ip", func(c *gin.Context) {
+				c.String(200, c.ClientIP())
+			})
+
+			adapter := ginadapter.New(r)
+
+			req := events.APIGatewayProxyRequest{
+				Path:       "/ip",
+				HTTPMethod: "GET",
+			}
+			req.RequestContext.Identity.SourceIP = "203.0.113.5"
+			resp, err := adapter.Proxy(req)
+			Expect(err).To(BeNil())
+			Expect("203.0.113.5").To(Equal(resp.Body))
+
+			v2Resp, err := adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RawPath: "/ip",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", SourceIP: "2001:db8::1"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect("2001:db8::1").To(Equal(v2Resp.Body))
+		})
+	})
 })
@@ -1,6 +1,8 @@
 package gorillamux
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -10,6 +12,7 @@ import (
 
 type GorillaMuxAdapter struct {
 	core.RequestAccessor
+	core.ResponseOptions
 	router *mux.Router
 }
 
@@ -25,8 +28,203 @@ func (h *GorillaMuxAdapter) Proxy(event events.APIGatewayProxyRequest) (events.A
 		return core.GatewayTimeout(), core.NewLoggedError("Could not convert proxy event to request: %v", err)
 	}
 
-	w := core.NewProxyResponseWriter()
-	h.router.ServeHTTP(http.ResponseWriter(w), req)
+	return h.proxyRequest(req)
+}
+
+func (h *GorillaMuxAdapter) ProxyRaw(payload json.RawMessage) (events.APIGatewayProxyResponse, error) {
+	if h.IsWarmup(payload) {
+		return core.WarmupResponse(), nil
+	}
+
+	req, err := h.RawEventToHTTPRequest(payload)
+	if err != nil {
+		return core.GatewayTimeout(), core.NewLoggedError("Could not convert raw event to request: %v", err)
+	}
+
+	return h.proxyRequest(req)
+}
+
+func (h *GorillaMuxAdapter) ProxyALB(event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	if resp, ok := h.ALBHealthCheckResponse(event); ok {
+		return resp, nil
+	}
+
+	req, err := h.ALBTargetGroupRequestToHTTPRequest(event)
+	if err != nil {
+		return core.ALBGatewayTimeout(), core.NewLoggedError("Could not convert ALB event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetALBTargetGroupResponse()
+	if err != nil {
+		return core.ALBGatewayTimeout(), core.NewLoggedError("Error while generating ALB response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyV2(event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	req, err := h.APIGatewayV2HTTPRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Could not convert v2 event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetAPIGatewayV2HTTPResponse()
+	if err != nil {
+		return core.GatewayTimeoutV2(), core.NewLoggedError("Error while generating v2 response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyFunctionURL(event events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	req, err := h.LambdaFunctionURLRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutFunctionURL(), core.NewLoggedError("Could not convert function URL event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetLambdaFunctionURLResponse()
+	if err != nil {
+		return core.GatewayTimeoutFunctionURL(), core.NewLoggedError("Error while generating function URL response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyWebsocket(event events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	req, err := h.WebsocketProxyRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeout(), core.NewLoggedError("Could not convert websocket event to request: %v", err)
+	}
+
+	return h.proxyRequest(req)
+}
+
+func (h *GorillaMuxAdapter) ProxyCloudFront(event core.CloudFrontEvent) (core.CloudFrontResponse, error) {
+	req, err := h.CloudFrontEventToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not convert CloudFront event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetCloudFrontResponse()
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Error while generating CloudFront response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyVPCLattice(event core.VPCLatticeRequest) (core.VPCLatticeResponse, error) {
+	req, err := h.VPCLatticeRequestToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutVPCLattice(), core.NewLoggedError("Could not convert VPC Lattice event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetVPCLatticeResponse()
+	if err != nil {
+		return core.GatewayTimeoutVPCLattice(), core.NewLoggedError("Error while generating VPC Lattice response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyAny(payload json.RawMessage) (interface{}, error) {
+	if h.IsWarmup(payload) {
+		return core.WarmupResponse(), nil
+	}
+
+	req, eventType, err := h.AnyEventToHTTPRequest(payload)
+	if err != nil {
+		return core.GatewayTimeoutFor(eventType), core.NewLoggedError("Could not convert %s event to request: %v", eventType, err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetResponse(eventType)
+	if err != nil {
+		return core.GatewayTimeoutFor(eventType), core.NewLoggedError("Error while generating %s response: %v", eventType, err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyWithContext(ctx context.Context, event core.SwitchableAPIGatewayRequest) (*core.SwitchableAPIGatewayResponse, error) {
+	req, cancel, err := h.SwitchableEventToHTTPRequest(ctx, event)
+	if err != nil {
+		return core.GatewayTimeoutSwitchable(event), core.NewLoggedError("Could not convert proxy event to request: %v", err)
+	}
+	defer cancel()
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetSwitchableResponse(event)
+	if err != nil {
+		return core.GatewayTimeoutSwitchable(event), core.NewLoggedError("Error while generating proxy response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyAuthorizer(event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	req, err := h.CustomAuthorizerRequestToHTTPRequest(event)
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, core.NewLoggedError("Could not convert authorizer event to request: %v", err)
+	}
+
+	w := h.serve(req)
+	defer w.Release()
+
+	resp, err := w.GetCustomAuthorizerResponse()
+	if err == core.ErrUnauthorized {
+		return resp, err
+	}
+	if err != nil {
+		return events.APIGatewayCustomAuthorizerResponse{}, core.NewLoggedError("Error while generating authorizer response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) ProxyCloudFrontOriginResponse(event core.CloudFrontEvent) (core.CloudFrontResponse, error) {
+	req, err := h.CloudFrontEventToHTTPRequest(event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not convert CloudFront event to request: %v", err)
+	}
+
+	w, err := h.NewCloudFrontOriginResponseWriter(req, event)
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Could not read CloudFront origin response: %v", err)
+	}
+	defer w.Release()
+	h.EnforceMethodPolicy(h.EnforceMaxRequestBodyBytes(h.router)).ServeHTTP(http.ResponseWriter(w), req)
+
+	resp, err := w.GetCloudFrontOriginResponse()
+	if err != nil {
+		return core.GatewayTimeoutCloudFront(), core.NewLoggedError("Error while generating CloudFront response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (h *GorillaMuxAdapter) proxyRequest(req *http.Request) (events.APIGatewayProxyResponse, error) {
+	w := h.serve(req)
+	defer w.Release()
 
 	resp, err := w.GetProxyResponse()
 	if err != nil {
@@ -35,3 +233,9 @@ func (h *GorillaMuxAdapter) Proxy(event events.APIGatewayProxyRequest) (events.A
 
 	return resp, nil
 }
+
+func (h *GorillaMuxAdapter) serve(req *http.Request) *core.ProxyResponseWriter {
+	w := h.NewProxyResponseWriter(req)
+	h.EnforceRateLimit(h.EnforceMethodPolicy(h.EnforceMaxRequestBodyBytes(h.CacheResponses(h.router)))).ServeHTTP(http.ResponseWriter(w), req)
+	return w
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/awslabs/aws-lambda-go-api-proxy/gorillamux"
 	"github.com/gorilla/mux"
 
@@ -54,4 +55,152 @@ var _ = Describe("GorillaMuxAdapter tests", func() {
 			Expect(productsPageResp.Body).To(Equal("Products Page"))
 		})
 	})
+
+	Context("ALB target group request", func() {
+		It("Returns a target group response", func() {
+			r := mux.NewRouter()
+			r.HandleFunc("/products", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Add("Set-Cookie", "a=1")
+				w.Header().Add("Set-Cookie", "b=2")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, "Products Page %s", req.URL.Query().Get("page"))
+			}).Methods("POST")
+
+			adapter := gorillamux.New(r)
+
+			resp, err := adapter.ProxyALB(events.ALBTargetGroupRequest{
+				Path:                  "/products",
+				HTTPMethod:            "POST",
+				QueryStringParameters: map[string]string{"page": "2"},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(resp.StatusDescription).To(Equal("201 Created"))
+			Expect(resp.Body).To(Equal("Products Page 2"))
+			Expect(resp.Headers["Set-Cookie"]).To(Equal("a=1"))
+			Expect(resp.MultiValueHeaders["Set-Cookie"]).To(Equal([]string{"a=1", "b=2"}))
+		})
+	})
+
+	Context("WebSocket request", func() {
+		It("Routes the event on the route key", func() {
+			r := mux.NewRouter()
+			r.HandleFunc("/$connect", func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, "connected %s", req.Header.Get(core.WebsocketConnectionIDHeader))
+			}).Methods("GET")
+			r.HandleFunc("/sendmessage", func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, "%s %s", req.Method, req.Header.Get(core.WebsocketEventTypeHeader))
+			})
+
+			adapter := gorillamux.New(r)
+
+			resp, err := adapter.ProxyWebsocket(events.APIGatewayWebsocketProxyRequest{
+				HTTPMethod: "GET",
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					RouteKey:     "$connect",
+					EventType:    "CONNECT",
+					ConnectionID: "abc=",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("connected abc="))
+
+			resp, err = adapter.ProxyWebsocket(events.APIGatewayWebsocketProxyRequest{
+				Body: `{"action":"sendmessage"}`,
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					RouteKey:     "sendmessage",
+					EventType:    "MESSAGE",
+					ConnectionID: "abc=",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("POST MESSAGE"))
+		})
+	})
+
+	Context("Warm-up events", func() {
+		It("Answers keep-warm pings without routing them", func() {
+			called := false
+			r := mux.NewRouter()
+			r.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			adapter := gorillamux.New(r)
+			adapter.SetWarmupMatcher(core.IsWarmupEvent)
+
+			resp, err := adapter.ProxyRaw([]byte(`{"source":"serverless-plugin-warmup"}`))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			anyResp, err := adapter.ProxyAny([]byte(`{"source":"aws.events","detail-type":"Scheduled Event"}`))
+			Expect(err).To(BeNil())
+			Expect(anyResp).To(Equal(core.WarmupResponse()))
+			Expect(called).To(BeFalse())
+
+			resp, err = adapter.ProxyRaw([]byte(`{"path":"/","httpMethod":"GET"}`))
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(called).To(BeTrue())
+		})
+	})
+
+	Context("ALB health checks", func() {
+		It("Answers the health check path without routing", func() {
+			called := false
+			r := mux.NewRouter()
+			r.HandleFunc("/healthcheck", func(w http.ResponseWriter, req *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			adapter := gorillamux.New(r)
+			adapter.SetHealthCheckPath("/healthcheck", http.StatusNoContent)
+
+			resp, err := adapter.ProxyALB(events.ALBTargetGroupRequest{Path: "/healthcheck", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(resp.StatusDescription).To(Equal("204 No Content"))
+			Expect(called).To(BeFalse())
+
+			resp, err = adapter.ProxyALB(events.ALBTargetGroupRequest{Path: "/healthcheck", HTTPMethod: "POST"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(called).To(BeTrue())
+		})
+	})
+
+	Context("Method override", func() {
+		It("Routes POST requests on the overridden method", func() {
+			r := mux.NewRouter()
+			r.HandleFunc("/orders/1", func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, "deleted")
+			}).Methods("DELETE")
+
+			adapter := gorillamux.New(r)
+			adapter.AllowMethodOverride(true)
+			adapter.AllowMethods("GET", "POST", "DELETE")
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/orders/1",
+				HTTPMethod: "POST",
+				Headers:    map[string]string{core.MethodOverrideHeader: "delete"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(Equal("deleted"))
+
+			adapter.AllowMethods("GET", "POST")
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/orders/1",
+				HTTPMethod: "POST",
+				Headers:    map[string]string{core.MethodOverrideHeader: "DELETE"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
 })
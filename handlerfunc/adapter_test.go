@@ -1,11 +1,13 @@
 package handlerfunc_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/awslabs/aws-lambda-go-api-proxy/handlerfunc"
 
 	. "github.com/onsi/ginkgo"
@@ -35,4 +37,90 @@ var _ = Describe("HandlerFuncAdapter tests", func() {
 			Expect(resp.StatusCode).To(Equal(200))
 		})
 	})
+
+	Context("Function URL request", func() {
+		It("Proxies the event and exposes the request context", func() {
+			accessor := core.RequestAccessor{}
+			handler := func(w http.ResponseWriter, req *http.Request) {
+				urlContext, err := accessor.GetFunctionURLContext(req)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Add("Set-Cookie", "id=1")
+				fmt.Fprintf(w, "%s %s %s", req.Method, req.URL.Path, urlContext.DomainPrefix)
+			}
+
+			adapter := handlerfunc.New(handler)
+
+			resp, err := adapter.ProxyFunctionURL(events.LambdaFunctionURLRequest{
+				RawPath: "/ping",
+				RequestContext: events.LambdaFunctionURLRequestContext{
+					DomainPrefix: "abc123",
+					HTTP:         events.LambdaFunctionURLRequestContextHTTPDescription{Method: "PUT"},
+				},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("PUT /ping abc123"))
+			Expect(resp.Cookies).To(Equal([]string{"id=1"}))
+		})
+	})
+
+	Context("Switchable request", func() {
+		handler := func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprintf(w, "%s %v", req.URL.Path, req.Context().Value(contextKey("name")))
+		}
+		ctx := context.WithValue(context.Background(), contextKey("name"), "value")
+
+		It("Returns a v1 response for REST API events", func() {
+			adapter := handlerfunc.New(handler)
+			resp, err := adapter.ProxyWithContext(ctx, *core.NewSwitchableAPIGatewayRequestV1(&events.APIGatewayProxyRequest{
+				Path:       "/v1",
+				HTTPMethod: "GET",
+			}))
+
+			Expect(err).To(BeNil())
+			Expect(resp.Version2()).To(BeNil())
+			Expect(resp.Version1().Body).To(Equal("/v1 value"))
+		})
+
+		It("Returns a v2 response for HTTP API events", func() {
+			adapter := handlerfunc.New(handler)
+			resp, err := adapter.ProxyWithContext(ctx, *core.NewSwitchableAPIGatewayRequestV2(&events.APIGatewayV2HTTPRequest{
+				RawPath: "/v2",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			}))
+
+			Expect(err).To(BeNil())
+			Expect(resp.Version1()).To(BeNil())
+			Expect(resp.Version2().Body).To(Equal("/v2 value"))
+		})
+	})
+
+	Context("Request body limit", func() {
+		It("Answers oversized bodies with a 413", func() {
+			called := false
+			adapter := handlerfunc.New(func(w http.ResponseWriter, req *http.Request) {
+				called = true
+				fmt.Fprintf(w, "%d", req.ContentLength)
+			})
+			adapter.SetMaxRequestBodyBytes(4)
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/upload", HTTPMethod: "POST", Body: "12345"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+			Expect(called).To(BeFalse())
+
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{Path: "/upload", HTTPMethod: "POST", Body: "1234"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(Equal("4"))
+		})
+	})
 })
+
+type contextKey string
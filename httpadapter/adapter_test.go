@@ -1,11 +1,15 @@
 package httpadapter_test
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 
 	. "github.com/onsi/ginkgo"
@@ -42,4 +46,322 @@ var _ = Describe("HTTPAdapter tests", func() {
 			Expect(resp.StatusCode).To(Equal(200))
 		})
 	})
+
+	Context("Raw payload request", func() {
+		It("Exposes the original event to the handler", func() {
+			payload := []byte(`{"path":"/ping","httpMethod":"GET"}`)
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				rawEvent, ok := core.RawEventFromContext(req.Context())
+				if !ok {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write(rawEvent)
+			}))
+
+			resp, err := adapter.ProxyRaw(payload)
+
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal(string(payload)))
+		})
+	})
+
+	Context("Method policy", func() {
+		var httpHandler http.Handler = handler{}
+
+		It("Rejects methods outside of the allowed list", func() {
+			adapter := httpadapter.New(httpHandler)
+			adapter.AllowCommonMethods()
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/ping", HTTPMethod: "TRACE"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+			Expect(resp.Headers["Allow"]).To(Equal("DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT"))
+
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{Path: "/ping", HTTPMethod: "get"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+
+		It("Rejects explicitly denied methods", func() {
+			adapter := httpadapter.New(httpHandler)
+			adapter.RejectMethods("CONNECT", "PURGE")
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/ping", HTTPMethod: "PURGE"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+			Expect(resp.Headers).ToNot(HaveKey("Allow"))
+
+			resp, err = adapter.Proxy(events.APIGatewayProxyRequest{Path: "/ping", HTTPMethod: "PROPFIND"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+	})
+
+	Context("Auto detected events", func() {
+		It("Returns the response matching the event type", func() {
+			adapter := httpadapter.New(handler{})
+
+			resp, err := adapter.ProxyAny([]byte(`{"path":"/ping","httpMethod":"GET"}`))
+			Expect(err).To(BeNil())
+			Expect(resp).To(BeAssignableToTypeOf(events.APIGatewayProxyResponse{}))
+
+			resp, err = adapter.ProxyAny([]byte(`{"version":"2.0","rawPath":"/ping","requestContext":{"http":{"method":"GET"}}}`))
+			Expect(err).To(BeNil())
+			Expect(resp.(events.APIGatewayV2HTTPResponse).Body).To(Equal("Go Lambda!!"))
+
+			resp, err = adapter.ProxyAny([]byte(`{"path":"/ping","httpMethod":"GET","requestContext":{"elb":{}}}`))
+			Expect(err).To(BeNil())
+			Expect(resp.(events.ALBTargetGroupResponse).StatusDescription).To(Equal("200 OK"))
+
+			resp, err = adapter.ProxyAny([]byte(`{"version":"2.0","rawPath":"/ping","requestContext":{"domainName":"x.lambda-url.eu-west-1.on.aws","http":{"method":"GET"}}}`))
+			Expect(err).To(BeNil())
+			Expect(resp).To(BeAssignableToTypeOf(events.LambdaFunctionURLResponse{}))
+
+			_, err = adapter.ProxyAny([]byte(`{"Records":[]}`))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Custom authorizer events", func() {
+		authorizer := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			arn := req.Header.Get(core.AuthorizerMethodArnHeader)
+			fmt.Fprintf(w, `{"principalId":"user","policyDocument":{"Version":"2012-10-17","Statement":[{"Action":["execute-api:Invoke"],"Effect":"Allow","Resource":["%s"]}]}}`, arn)
+		})
+
+		It("Returns the policy written by the handler", func() {
+			adapter := httpadapter.New(authorizer)
+
+			resp, err := adapter.ProxyAuthorizer(events.APIGatewayCustomAuthorizerRequestTypeRequest{
+				MethodArn:  "arn:test",
+				Path:       "/ping",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Authorization": "secret"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.PrincipalID).To(Equal("user"))
+			Expect(resp.PolicyDocument.Statement[0].Resource).To(Equal([]string{"arn:test"}))
+		})
+
+		It("Returns the Unauthorized error on 401 responses", func() {
+			adapter := httpadapter.New(authorizer)
+
+			_, err := adapter.ProxyAuthorizer(events.APIGatewayCustomAuthorizerRequestTypeRequest{
+				Path:       "/ping",
+				HTTPMethod: "GET",
+			})
+			Expect(err).To(Equal(core.ErrUnauthorized))
+			Expect(err.Error()).To(Equal("Unauthorized"))
+		})
+	})
+
+	Context("Range requests", func() {
+		content := append([]byte("abcdefghij"), 0xff, 0xfe, 0x00, 0x89)
+		files := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeContent(w, req, "file.bin", time.Unix(0, 0), bytes.NewReader(content))
+		})
+
+		It("Returns byte ranges as base64 encoded partial content", func() {
+			adapter := httpadapter.New(files)
+			adapter.EnableCompression(1)
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/file.bin",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Range": "bytes=2-5", "Accept-Encoding": "zstd"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusPartialContent))
+			Expect(resp.Headers["Content-Range"]).To(Equal("bytes 2-5/14"))
+			Expect(resp.Headers).ToNot(HaveKey("Content-Encoding"))
+			Expect(resp.IsBase64Encoded).To(BeTrue())
+			body, err := base64.StdEncoding.DecodeString(resp.Body)
+			Expect(err).To(BeNil())
+			Expect(body).To(Equal([]byte("cdef")))
+
+			v2Resp, err := adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RawPath:        "/file.bin",
+				Headers:        map[string]string{"range": "bytes=-4"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"}},
+			})
+			Expect(err).To(BeNil())
+			Expect(v2Resp.StatusCode).To(Equal(http.StatusPartialContent))
+			body, err = base64.StdEncoding.DecodeString(v2Resp.Body)
+			Expect(err).To(BeNil())
+			Expect(body).To(Equal(content[10:]))
+		})
+
+		It("Returns multiple ranges in a multipart body", func() {
+			adapter := httpadapter.New(files)
+
+			resp, err := adapter.ProxyALB(events.ALBTargetGroupRequest{
+				Path:       "/file.bin",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"range": "bytes=0-1,4-5"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusPartialContent))
+			Expect(resp.Headers["Content-Type"]).To(HavePrefix("multipart/byteranges"))
+			Expect(resp.IsBase64Encoded).To(BeTrue())
+			body, err := base64.StdEncoding.DecodeString(resp.Body)
+			Expect(err).To(BeNil())
+			Expect(string(body)).To(ContainSubstring("Content-Range: bytes 4-5/14"))
+			Expect(string(body)).To(ContainSubstring("ef"))
+		})
+	})
+
+	Context("Response cookies", func() {
+		It("Returns the cookies in the 2.0 payload format", func() {
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+				http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			v2Req := events.APIGatewayV2HTTPRequest{RawPath: "/login"}
+			v2Req.RequestContext.HTTP.Method = "POST"
+			v2Resp, err := adapter.ProxyV2(v2Req)
+			Expect(err).To(BeNil())
+			Expect(v2Resp.Cookies).To(Equal([]string{"session=abc; HttpOnly", "theme=dark"}))
+			Expect(v2Resp.Headers).ToNot(HaveKey("Set-Cookie"))
+
+			urlReq := events.LambdaFunctionURLRequest{RawPath: "/login"}
+			urlReq.RequestContext.HTTP.Method = "POST"
+			urlResp, err := adapter.ProxyFunctionURL(urlReq)
+			Expect(err).To(BeNil())
+			Expect(urlResp.Cookies).To(Equal(v2Resp.Cookies))
+		})
+	})
+
+	Context("Response cache", func() {
+		It("Serves repeated requests from the cache", func() {
+			calls := 0
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				w.Header().Set("Vary", "Accept-Language")
+				fmt.Fprintf(w, "%s %d", req.Header.Get("Accept-Language"), calls)
+			}))
+			adapter.SetResponseCache(core.NewResponseCache(10, 50*time.Millisecond))
+
+			request := func(path string, language string) events.APIGatewayProxyResponse {
+				resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+					Path:       path,
+					HTTPMethod: "GET",
+					Headers:    map[string]string{"Accept-Language": language},
+				})
+				Expect(err).To(BeNil())
+				return resp
+			}
+
+			Expect(request("/products", "en").Body).To(Equal("en 1"))
+			cached := request("/products", "en")
+			Expect(cached.Body).To(Equal("en 1"))
+			Expect(cached.Headers["Age"]).To(Equal("0"))
+			Expect(request("/products", "fr").Body).To(Equal("fr 2"))
+			Expect(request("/orders", "fr").Body).To(Equal("fr 3"))
+
+			time.Sleep(100 * time.Millisecond)
+			Expect(request("/orders", "fr").Body).To(Equal("fr 4"))
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/orders", HTTPMethod: "POST"})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal(" 5"))
+		})
+
+		It("Does not cache private responses", func() {
+			calls := 0
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				w.Header().Set("Cache-Control", "private")
+				fmt.Fprintf(w, "%d", calls)
+			}))
+			cache := core.NewResponseCache(10, time.Minute)
+			adapter.SetResponseCache(cache)
+
+			for i := 1; i <= 2; i++ {
+				resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/me", HTTPMethod: "GET"})
+				Expect(err).To(BeNil())
+				Expect(resp.Body).To(Equal(fmt.Sprint(i)))
+			}
+			Expect(cache.Len()).To(Equal(0))
+		})
+
+		It("Keys the responses on the host and skips cookie sessions", func() {
+			calls := 0
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				fmt.Fprintf(w, "%s %d", req.Host, calls)
+			}))
+			cache := core.NewResponseCache(10, time.Minute)
+			adapter.SetResponseCache(cache)
+
+			request := func(headers map[string]string) string {
+				resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/", HTTPMethod: "GET", Headers: headers})
+				Expect(err).To(BeNil())
+				return resp.Body
+			}
+
+			Expect(request(map[string]string{"Host": "a.example.com"})).To(Equal("a.example.com 1"))
+			Expect(request(map[string]string{"Host": "b.example.com"})).To(Equal("b.example.com 2"))
+			Expect(request(map[string]string{"Host": "a.example.com"})).To(Equal("a.example.com 1"))
+			Expect(request(map[string]string{"Host": "a.example.com", "Cookie": "session=1"})).To(Equal("a.example.com 3"))
+			Expect(request(map[string]string{"Host": "a.example.com", "Cookie": "session=1"})).To(Equal("a.example.com 4"))
+			Expect(cache.Len()).To(Equal(2))
+		})
+	})
+
+	Context("Rate limit", func() {
+		It("Throttles the requests beyond the rate", func() {
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+			adapter.SetRateLimiter(core.NewRateLimiter(20, 2))
+
+			request := func() events.APIGatewayProxyResponse {
+				resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/orders", HTTPMethod: "GET"})
+				Expect(err).To(BeNil())
+				return resp
+			}
+
+			Expect(request().StatusCode).To(Equal(http.StatusOK))
+			Expect(request().StatusCode).To(Equal(http.StatusOK))
+			throttled := request()
+			Expect(throttled.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(throttled.Headers["Retry-After"]).To(Equal("1"))
+
+			time.Sleep(100 * time.Millisecond)
+			Expect(request().StatusCode).To(Equal(http.StatusOK))
+
+			adapter.SetRateLimiter(nil)
+			for i := 0; i < 5; i++ {
+				Expect(request().StatusCode).To(Equal(http.StatusOK))
+			}
+		})
+	})
+
+	Context("Informational statuses", func() {
+		It("Returns the final status of Expect requests", func() {
+			adapter := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusContinue)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, "expect=%q", req.Header.Get("Expect"))
+			}))
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/upload",
+				HTTPMethod: "POST",
+				Headers:    map[string]string{"Expect": "100-continue"},
+				Body:       "data",
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(resp.Body).To(Equal(`expect=""`))
+		})
+	})
 })
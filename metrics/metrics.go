@@ -0,0 +1,259 @@
+// Package metrics collects per-route request counters and latency histograms
+// and serves them in the Prometheus text exposition format. The values are
+// scoped to the lifetime of the Lambda container: each container exposes
+// its own series, which are reset on cold starts.
+//
+//	collector := metrics.New()
+//	adapter := httpadapter.New(collector.Handler(mux))
+//
+// The metrics are then served on GET /metrics, which can be scraped through
+// a private API Gateway or an internal ALB.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultPath is the path on which the metrics are served
+const DefaultPath = "/metrics"
+
+// OtherRoute is the route label of requests without an API Gateway resource
+const OtherRoute = "other"
+
+// OtherMethod is the method label of requests with a non-standard method
+const OtherMethod = "OTHER"
+
+// knownMethods are the methods used as is in the method label
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histogram
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type counterKey struct {
+	method string
+	route  string
+	status int
+}
+
+type histogramKey struct {
+	method string
+	route  string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Collector records the requests served by the wrapped handler.
+type Collector struct {
+	path      string
+	buckets   []float64
+	routeFunc func(*http.Request) string
+	started   time.Time
+
+	mu         sync.Mutex
+	counters   map[counterKey]uint64
+	histograms map[histogramKey]*histogram
+}
+
+// New creates a new Collector serving the metrics on DefaultPath. Requests
+// are labelled with the API Gateway resource path or HTTP API route that
+// matched them, for example /users/{id}, to keep the number of series
+// bounded.
+func New() *Collector {
+	c := &Collector{
+		path:       DefaultPath,
+		buckets:    DefaultBuckets,
+		started:    time.Now(),
+		counters:   make(map[counterKey]uint64),
+		histograms: make(map[histogramKey]*histogram),
+	}
+	c.routeFunc = c.resourcePath
+	return c
+}
+
+// SetPath sets the path on which the metrics are served.
+func (c *Collector) SetPath(path string) {
+	c.path = path
+}
+
+// SetBuckets sets the upper bounds, in seconds, of the latency histogram
+// buckets. It should be called before the first request is recorded: the
+// latencies cannot be assigned to the new buckets, so the histograms
+// recorded so far are discarded. The request counters are kept.
+func (c *Collector) SetBuckets(buckets []float64) {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets = sorted
+	c.histograms = make(map[histogramKey]*histogram)
+}
+
+// SetRouteFunc sets the function that returns the route label of a request.
+// The function must return a bounded set of values, for example the route
+// template of the framework router.
+func (c *Collector) SetRouteFunc(f func(*http.Request) string) {
+	c.routeFunc = f
+}
+
+// Handler returns an http.Handler that serves the metrics on the configured
+// path and records all the other requests served by next. Non-standard
+// methods are recorded with the OtherMethod label.
+func (c *Collector) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == c.path && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			c.ServeHTTP(w, req)
+			return
+		}
+
+		start := time.Now()
+		rw := core.NewResponseRecorder(w)
+		next.ServeHTTP(rw, req)
+		c.Observe(methodLabel(req.Method), c.routeFunc(req), rw.StatusCode(), time.Since(start))
+	})
+}
+
+// Observe records a request. It can be used to record requests that are
+// not served through the Handler.
+func (c *Collector) Observe(method string, route string, status int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters[counterKey{method: method, route: route, status: status}]++
+
+	key := histogramKey{method: method, route: route}
+	h, ok := c.histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.histograms[key] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range c.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to the writer.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP http_requests_total Number of HTTP requests served by the container.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	counterKeys := make([]counterKey, 0, len(c.counters))
+	for key := range c.counters {
+		counterKeys = append(counterKeys, key)
+	}
+	sort.Slice(counterKeys, func(i, j int) bool {
+		a, b := counterKeys[i], counterKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range counterKeys {
+		fmt.Fprintf(&b, "http_requests_total{method=\"%s\",route=\"%s\",status=\"%d\"} %d\n",
+			escape(key.method), escape(key.route), key.status, c.counters[key])
+	}
+
+	b.WriteString("# HELP http_request_duration_seconds Latency of the HTTP requests served by the container.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	histogramKeys := make([]histogramKey, 0, len(c.histograms))
+	for key := range c.histograms {
+		histogramKeys = append(histogramKeys, key)
+	}
+	sort.Slice(histogramKeys, func(i, j int) bool {
+		a, b := histogramKeys[i], histogramKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+	for _, key := range histogramKeys {
+		h := c.histograms[key]
+		labels := fmt.Sprintf("method=\"%s\",route=\"%s\"", escape(key.method), escape(key.route))
+		for i, bound := range c.buckets {
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	b.WriteString("# HELP process_start_time_seconds Start time of the container since unix epoch in seconds.\n")
+	b.WriteString("# TYPE process_start_time_seconds gauge\n")
+	fmt.Fprintf(&b, "process_start_time_seconds %d\n", c.started.Unix())
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// resourcePath returns the API Gateway resource matched by the request, or
+// the path of the route key of HTTP API requests, such as /users/{id} for
+// the GET /users/{id} route. It is read from the request context, the
+// headers are sent by the client and would let it create any number of
+// series.
+func (c *Collector) resourcePath(req *http.Request) string {
+	if apiGwContext, ok := core.GetAPIGatewayContextFromContext(req.Context()); ok && apiGwContext.ResourcePath != "" {
+		return apiGwContext.ResourcePath
+	}
+	if resource, ok := core.ResourceFromContext(req.Context()); ok && resource != "" {
+		return resource
+	}
+	return OtherRoute
+}
+
+// methodLabel returns the method label of a request method.
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return OtherMethod
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
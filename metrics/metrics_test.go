@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics collector tests", func() {
+	app := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("ok"))
+	})
+
+	Context("Recording requests", func() {
+		It("Exposes per route counters and histograms", func() {
+			collector := metrics.New()
+			collector.SetBuckets([]float64{1, 0.5})
+			adapter := httpadapter.New(collector.Handler(app))
+
+			for _, path := range []string{"/users/1", "/users/2"} {
+				_, err := adapter.Proxy(events.APIGatewayProxyRequest{
+					Path:       path,
+					HTTPMethod: "GET",
+					RequestContext: events.APIGatewayProxyRequestContext{
+						ResourcePath: "/users/{id}",
+					},
+				})
+				Expect(err).To(BeNil())
+			}
+			_, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/missing", HTTPMethod: "POST"})
+			Expect(err).To(BeNil())
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/metrics", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Headers["Content-Type"]).To(Equal("text/plain; version=0.0.4; charset=utf-8"))
+			Expect(resp.Body).To(ContainSubstring(`http_requests_total{method="GET",route="/users/{id}",status="200"} 2`))
+			Expect(resp.Body).To(ContainSubstring(`http_requests_total{method="POST",route="other",status="404"} 1`))
+			Expect(resp.Body).To(ContainSubstring(`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="0.5"} 2`))
+			Expect(resp.Body).To(ContainSubstring(`http_request_duration_seconds_bucket{method="GET",route="/users/{id}",le="+Inf"} 2`))
+			Expect(resp.Body).To(ContainSubstring(`http_request_duration_seconds_count{method="POST",route="other"} 1`))
+			Expect(resp.Body).To(ContainSubstring("process_start_time_seconds "))
+		})
+
+		It("Labels HTTP API requests with the route key", func() {
+			collector := metrics.New()
+			adapter := httpadapter.New(collector.Handler(app))
+
+			_, err := adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RouteKey: "GET /users/{id}",
+				RawPath:  "/users/1",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+
+			var b strings.Builder
+			collector.WriteTo(&b)
+			Expect(b.String()).To(ContainSubstring(`http_requests_total{method="GET",route="/users/{id}",status="200"} 1`))
+		})
+
+		It("Resets the histograms when the buckets change", func() {
+			collector := metrics.New()
+			collector.Observe("GET", "manual", 200, time.Second)
+			collector.SetBuckets([]float64{0.5})
+			collector.Observe("GET", "manual", 200, 100*time.Millisecond)
+
+			var b strings.Builder
+			_, err := collector.WriteTo(&b)
+			Expect(err).To(BeNil())
+			Expect(b.String()).To(ContainSubstring(`http_requests_total{method="GET",route="manual",status="200"} 2`))
+			Expect(b.String()).To(ContainSubstring(`http_request_duration_seconds_bucket{method="GET",route="manual",le="0.5"} 1`))
+			Expect(b.String()).To(ContainSubstring(`http_request_duration_seconds_count{method="GET",route="manual"} 1`))
+		})
+
+		It("Bounds the labels of forged requests", func() {
+			collector := metrics.New()
+			adapter := httpadapter.New(collector.Handler(app))
+
+			_, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/users/1",
+				HTTPMethod: "BREW",
+				Headers:    map[string]string{"X-GoLambdaProxy-ApiGw-Context": `{"resourcePath":"/random-1"}`},
+			})
+			Expect(err).To(BeNil())
+
+			var b strings.Builder
+			collector.WriteTo(&b)
+			Expect(b.String()).To(ContainSubstring(`http_requests_total{method="OTHER",route="other",status="200"} 1`))
+		})
+
+		It("Uses the custom route function and escapes labels", func() {
+			collector := metrics.New()
+			collector.SetPath("/_metrics")
+			collector.SetRouteFunc(func(req *http.Request) string { return `a"b` })
+			collector.Observe("GET", "manual", 201, 2*time.Second)
+			adapter := httpadapter.New(collector.Handler(app))
+
+			_, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/metrics", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/_metrics", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(ContainSubstring(`http_requests_total{method="GET",route="a\"b",status="200"} 1`))
+			Expect(resp.Body).To(ContainSubstring(`http_requests_total{method="GET",route="manual",status="201"} 1`))
+			Expect(resp.Body).To(ContainSubstring(`http_request_duration_seconds_bucket{method="GET",route="manual",le="10"} 1`))
+			Expect(resp.Body).To(ContainSubstring(`http_request_duration_seconds_bucket{method="GET",route="manual",le="1"} 0`))
+		})
+	})
+})
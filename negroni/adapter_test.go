@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 	"github.com/awslabs/aws-lambda-go-api-proxy/negroni"
 	"github.com/urfave/negroni"
 
@@ -60,4 +61,125 @@ var _ = Describe("NegroniAdapter tests", func() {
 			Expect(productsPageResp.Body).To(Equal("Products Page"))
 		})
 	})
+
+	Context("Lambda@Edge request", func() {
+		It("Returns a CloudFront response", func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/products", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Add("Cache-Control", "max-age=60")
+				fmt.Fprintf(w, "Products %s %s", req.URL.Query().Get("page"), req.RemoteAddr)
+			})
+
+			n := negroni.New()
+			n.UseHandler(mux)
+
+			adapter := negroniadapter.New(n)
+
+			resp, err := adapter.ProxyCloudFront(core.CloudFrontEvent{
+				Records: []core.CloudFrontEventRecord{{
+					CF: core.CloudFrontRecord{
+						Config: core.CloudFrontConfig{EventType: "viewer-request"},
+						Request: core.CloudFrontRequest{
+							ClientIP:    "203.0.113.1",
+							Method:      "GET",
+							URI:         "/products",
+							QueryString: "page=3",
+							Headers: core.CloudFrontHeaders{
+								"host": {{Key: "Host", Value: "d111111abcdef8.cloudfront.net"}},
+							},
+						},
+					},
+				}},
+			})
+
+			Expect(err).To(BeNil())
+			Expect(resp.Status).To(Equal("200"))
+			Expect(resp.StatusDescription).To(Equal("OK"))
+			Expect(resp.Body).To(Equal("Products 3 203.0.113.1:0"))
+			Expect(resp.BodyEncoding).To(Equal("text"))
+			Expect(resp.Headers["cache-control"]).To(Equal([]core.CloudFrontHeader{{Key: "Cache-Control", Value: "max-age=60"}}))
+		})
+	})
+
+	Context("Lambda@Edge origin response", func() {
+		originEvent := func(uri string) core.CloudFrontEvent {
+			return core.CloudFrontEvent{
+				Records: []core.CloudFrontEventRecord{{
+					CF: core.CloudFrontRecord{
+						Config:  core.CloudFrontConfig{EventType: "origin-response"},
+						Request: core.CloudFrontRequest{Method: "GET", URI: uri},
+						Response: &core.CloudFrontResponse{
+							Status:            "404",
+							StatusDescription: "Not Found",
+							Headers: core.CloudFrontHeaders{
+								"content-length": {{Key: "Content-Length", Value: "9"}},
+								"server":         {{Key: "Server", Value: "origin"}},
+							},
+						},
+					},
+				}},
+			}
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/headers", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Del("Server")
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000")
+		})
+		mux.HandleFunc("/missing", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Not here")
+		})
+
+		n := negroni.New()
+		n.UseHandler(mux)
+		adapter := negroniadapter.New(n)
+
+		It("Rewrites the headers of the origin response", func() {
+			resp, err := adapter.ProxyCloudFrontOriginResponse(originEvent("/headers"))
+
+			Expect(err).To(BeNil())
+			Expect(resp.Status).To(Equal("404"))
+			Expect(resp.Body).To(BeEmpty())
+			Expect(resp.BodyEncoding).To(BeEmpty())
+			Expect(resp.Headers).ToNot(HaveKey("server"))
+			Expect(resp.Headers["content-length"]).To(Equal([]core.CloudFrontHeader{{Key: "Content-Length", Value: "9"}}))
+			Expect(resp.Headers["strict-transport-security"]).To(Equal([]core.CloudFrontHeader{{Key: "Strict-Transport-Security", Value: "max-age=63072000"}}))
+		})
+
+		It("Replaces the body of the origin response", func() {
+			resp, err := adapter.ProxyCloudFrontOriginResponse(originEvent("/missing"))
+
+			Expect(err).To(BeNil())
+			Expect(resp.Status).To(Equal("404"))
+			Expect(resp.Body).To(Equal("Not here"))
+			Expect(resp.BodyEncoding).To(Equal("text"))
+			Expect(resp.Headers).ToNot(HaveKey("content-length"))
+		})
+
+		It("Releases pooled writers", func() {
+			pooled := negroniadapter.New(n)
+			pooled.PoolResponseWriters(true)
+			for i := 0; i < 3; i++ {
+				resp, err := pooled.ProxyCloudFrontOriginResponse(originEvent("/missing"))
+				Expect(err).To(BeNil())
+				Expect(resp.Body).To(Equal("Not here"))
+				resp, err = pooled.ProxyCloudFrontOriginResponse(originEvent("/headers"))
+				Expect(err).To(BeNil())
+				Expect(resp.Body).To(BeEmpty())
+				Expect(resp.Headers).ToNot(HaveKey("content-type"))
+			}
+		})
+
+		It("Fails on events without an origin response", func() {
+			event := originEvent("/headers")
+			event.Records[0].CF.Response = nil
+
+			resp, err := adapter.ProxyCloudFrontOriginResponse(event)
+			Expect(err).ToNot(BeNil())
+			Expect(resp.Status).To(Equal("504"))
+		})
+	})
 })
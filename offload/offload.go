@@ -0,0 +1,143 @@
+// Package offload stores the bodies of the responses too large to be
+// returned by Lambda, for example in S3, and replaces the responses with a
+// redirect to the stored copy. Existing handlers can then serve large exports
+// without being rewritten. The Offloader implements the overflow handler of
+// the adapters:
+//
+//	offloader := offload.New(store)
+//	adapter := httpadapter.New(handler)
+//	adapter.SetResponseOverflowHandler(4*1024*1024, offloader.Handler())
+//
+// The package does not depend on the AWS SDK, the Store uploading the bodies
+// is provided by the application, typically with the PutObject and
+// PresignGetObject calls of its S3 client.
+package offload
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultPrefix is the prefix of the keys of the stored bodies.
+const DefaultPrefix = "responses/"
+
+// Store uploads the bodies of the oversized responses.
+type Store interface {
+	// Put stores the body under the given key and returns the URL the
+	// client downloads it from, such as a presigned URL of the S3 object.
+	Put(key string, contentType string, body []byte) (string, error)
+}
+
+// StoreFunc is an adapter to allow the use of ordinary functions as Store.
+type StoreFunc func(key string, contentType string, body []byte) (string, error)
+
+// Put implementation from the Store interface.
+func (f StoreFunc) Put(key string, contentType string, body []byte) (string, error) {
+	return f(key, contentType, body)
+}
+
+// Mode selects the response returned in place of the oversized response.
+type Mode int
+
+const (
+	// RedirectMode returns a 303 See Other response with the URL of the stored
+	// body in the Location header. This is the default.
+	RedirectMode Mode = iota
+	// EnvelopeMode returns a 200 OK response with a JSON Envelope describing the
+	// stored body.
+	EnvelopeMode
+)
+
+// Envelope is the JSON body of the responses returned in the EnvelopeMode.
+type Envelope struct {
+	Location    string `json:"location"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// Offloader replaces the oversized responses with a reference to their body
+// uploaded to a Store.
+type Offloader struct {
+	store  Store
+	prefix string
+	mode   Mode
+}
+
+// New creates a new Offloader uploading the bodies to the given store.
+func New(store Store) *Offloader {
+	return &Offloader{
+		store:  store,
+		prefix: DefaultPrefix,
+	}
+}
+
+// SetPrefix sets the prefix of the keys of the stored bodies.
+func (o *Offloader) SetPrefix(prefix string) {
+	o.prefix = prefix
+}
+
+// SetMode sets the response returned in place of the oversized responses.
+func (o *Offloader) SetMode(mode Mode) {
+	o.mode = mode
+}
+
+// Handler returns the overflow handler to pass to the
+// SetResponseOverflowHandler method of the adapters. Error responses of the
+// handler, with a status code of 400 or more, are not offloaded.
+func (o *Offloader) Handler() core.OverflowHandler {
+	return func(w *core.ProxyResponseWriter, size int) error {
+		status := w.Status()
+		if status >= http.StatusBadRequest {
+			return fmt.Errorf("Could not offload %d response", status)
+		}
+		contentType := w.Header().Get("Content-Type")
+		body := w.Body()
+
+		key, err := o.newKey()
+		if err != nil {
+			return err
+		}
+		location, err := o.store.Put(key, contentType, body)
+		if err != nil {
+			return fmt.Errorf("Could not offload response body: %v", err)
+		}
+
+		w.Reset()
+		w.Header().Set("Cache-Control", "no-store")
+		if o.mode == EnvelopeMode {
+			envelope, err := json.Marshal(Envelope{
+				Location:    location,
+				Status:      status,
+				ContentType: contentType,
+				Size:        len(body),
+			})
+			if err != nil {
+				return err
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, err = w.Write(envelope)
+			return err
+		}
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusSeeOther)
+		return nil
+	}
+}
+
+// newKey returns a unique key under the prefix, starting with the date so
+// that the stored bodies can be expired with a lifecycle rule.
+func (o *Offloader) newKey() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return o.prefix + time.Now().UTC().Format("2006/01/02/") + hex.EncodeToString(id), nil
+}
@@ -0,0 +1,13 @@
+package offload_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOffload(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Offload Suite")
+}
@@ -0,0 +1,87 @@
+package offload_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/offload"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Offloader tests", func() {
+	export := strings.Repeat("id,name\n", 1024)
+	exportHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(export))
+	})
+
+	var stored map[string][]byte
+	store := offload.StoreFunc(func(key string, contentType string, body []byte) (string, error) {
+		stored[key] = body
+		return "https://bucket.s3.amazonaws.com/" + key + "?X-Amz-Signature=x", nil
+	})
+	BeforeEach(func() {
+		stored = map[string][]byte{}
+	})
+
+	Context("Oversized responses", func() {
+		It("Redirects to the stored body", func() {
+			adapter := httpadapter.New(exportHandler)
+			adapter.SetResponseOverflowHandler(1024, offload.New(store).Handler())
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/export", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusSeeOther))
+			Expect(resp.Body).To(Equal(""))
+			Expect(stored).To(HaveLen(1))
+			for key, body := range stored {
+				Expect(key).To(HavePrefix(offload.DefaultPrefix))
+				Expect(string(body)).To(Equal(export))
+				Expect(resp.Headers["Location"]).To(HavePrefix("https://bucket.s3.amazonaws.com/" + key))
+			}
+
+			smallResp, err := httpadapter.New(exportHandler).Proxy(events.APIGatewayProxyRequest{Path: "/export", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(smallResp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("Returns a JSON envelope", func() {
+			offloader := offload.New(store)
+			offloader.SetMode(offload.EnvelopeMode)
+			offloader.SetPrefix("exports/")
+			adapter := httpadapter.New(exportHandler)
+			adapter.SetResponseOverflowHandler(1024, offloader.Handler())
+
+			v2Req := events.APIGatewayV2HTTPRequest{RawPath: "/export"}
+			v2Req.RequestContext.HTTP.Method = "GET"
+			resp, err := adapter.ProxyV2(v2Req)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Headers["Content-Type"]).To(Equal("application/json"))
+
+			envelope := offload.Envelope{}
+			Expect(json.Unmarshal([]byte(resp.Body), &envelope)).To(BeNil())
+			Expect(envelope.Status).To(Equal(http.StatusOK))
+			Expect(envelope.ContentType).To(Equal("text/csv"))
+			Expect(envelope.Size).To(Equal(len(export)))
+			Expect(envelope.Location).To(ContainSubstring("/exports/"))
+		})
+
+		It("Fails when the body cannot be stored", func() {
+			adapter := httpadapter.New(exportHandler)
+			adapter.SetResponseOverflowHandler(1024, offload.New(offload.StoreFunc(func(key string, contentType string, body []byte) (string, error) {
+				return "", errors.New("Access Denied")
+			})).Handler())
+
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/export", HTTPMethod: "GET"})
+			Expect(err).ToNot(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusGatewayTimeout))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// ResponseReporter receives the violations found in a response.
+type ResponseReporter func(req *http.Request, status int, errs []ValidationError)
+
+// LogResponseViolations is the default ResponseReporter, it writes the
+// violations to the function logs.
+func LogResponseViolations(req *http.Request, status int, errs []ValidationError) {
+	log.Printf("OpenAPI response violations for %s %s (status %d)\n", req.Method, req.URL.Path, status)
+	for _, e := range errs {
+		log.Printf("  %s\n", e.Error())
+	}
+}
+
+// EnableResponseValidation instructs the validator to also validate the
+// responses generated by the next handler and to report the violations to
+// the given reporter, or to LogResponseViolations when the reporter is nil.
+// Responses are sent to the client unchanged. Response validation buffers
+// a copy of each response body and is meant for debug and staging stages,
+// enable it conditionally, for example based on an environment variable.
+func (v *Validator) EnableResponseValidation(reporter ResponseReporter) {
+	if reporter == nil {
+		reporter = LogResponseViolations
+	}
+	v.responseReporter = reporter
+}
+
+// ValidateResponse validates the status, and the JSON body of the response,
+// against the operation matching the request.
+func (v *Validator) ValidateResponse(req *http.Request, status int, header http.Header, body []byte) []ValidationError {
+	r, _ := v.spec.findRoute(req.Method, req.URL.Path)
+	if r == nil || len(r.operation.Responses) == 0 {
+		return nil
+	}
+
+	response, found := findResponse(r.operation.Responses, status)
+	if !found {
+		return []ValidationError{{Location: "status", Message: "status " + strconv.Itoa(status) + " is not documented"}}
+	}
+	response, err := v.spec.resolveResponse(response)
+	if err != nil {
+		return []ValidationError{{Location: "status", Message: err.Error()}}
+	}
+	if response == nil || len(response.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	contentType := header.Get("Content-Type")
+	mediaType, found := findMediaType(response.Content, contentType)
+	if !found {
+		return []ValidationError{{Location: "header.Content-Type", Message: "undocumented content type " + contentType}}
+	}
+	return v.spec.validateJSON(mediaType, contentType, body, "body")
+}
+
+// findResponse looks up the status exactly, then by range (2XX) and finally
+// the default response.
+func findResponse(responses map[string]*Response, status int) (*Response, bool) {
+	code := strconv.Itoa(status)
+	if r, ok := responses[code]; ok {
+		return r, true
+	}
+	if r, ok := responses[code[:1]+"XX"]; ok {
+		return r, true
+	}
+	r, ok := responses["default"]
+	return r, ok
+}
+
+func (v *Validator) serveAndValidate(next http.Handler, w http.ResponseWriter, req *http.Request) {
+	var body bytes.Buffer
+	rec := core.NewResponseRecorder(w)
+	rec.CopyBodyTo(&body)
+	next.ServeHTTP(rec, req)
+
+	status := rec.StatusCode()
+	if errs := v.ValidateResponse(req, status, w.Header(), body.Bytes()); len(errs) > 0 {
+		v.responseReporter(req, status, errs)
+	}
+}
@@ -0,0 +1,99 @@
+package openapi_test
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/openapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const ordersSpec = `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/orders/{id}": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "order",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": ["id", "total"],
+                  "properties": {
+                    "id": {"type": "string"},
+                    "total": {"type": "number"}
+                  }
+                }
+              }
+            }
+          },
+          "4XX": {"description": "client error"}
+        }
+      }
+    }
+  }
+}`
+
+var _ = Describe("OpenAPI response validation tests", func() {
+	spec, err := openapi.Load([]byte(ordersSpec))
+	if err != nil {
+		Fail("Could not load spec: " + err.Error())
+	}
+
+	type violation struct {
+		status int
+		errs   []openapi.ValidationError
+	}
+
+	respond := func(status int, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		})
+	}
+
+	run := func(handler http.Handler) ([]violation, events.APIGatewayProxyResponse) {
+		violations := []violation{}
+		validator := openapi.NewValidator(spec)
+		validator.EnableResponseValidation(func(req *http.Request, status int, errs []openapi.ValidationError) {
+			violations = append(violations, violation{status, errs})
+		})
+		resp, err := httpadapter.New(validator.Handler(handler)).Proxy(events.APIGatewayProxyRequest{
+			Path:       "/orders/1",
+			HTTPMethod: "GET",
+		})
+		Expect(err).To(BeNil())
+		return violations, resp
+	}
+
+	It("Does not report valid responses", func() {
+		violations, resp := run(respond(200, `{"id":"1","total":9.5}`))
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(0).To(Equal(len(violations)))
+
+		violations, _ = run(respond(404, `{"message":"not found"}`))
+		Expect(0).To(Equal(len(violations)))
+	})
+
+	It("Reports body violations and forwards the response unchanged", func() {
+		violations, resp := run(respond(200, `{"id":1}`))
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.Body).To(Equal(`{"id":1}`))
+		Expect(1).To(Equal(len(violations)))
+		Expect(2).To(Equal(len(violations[0].errs)))
+	})
+
+	It("Reports undocumented status codes", func() {
+		violations, resp := run(respond(500, `{}`))
+		Expect(resp.StatusCode).To(Equal(500))
+		Expect(1).To(Equal(len(violations)))
+		Expect(500).To(Equal(violations[0].status))
+		Expect("status").To(Equal(violations[0].errs[0].Location))
+	})
+})
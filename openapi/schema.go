@@ -0,0 +1,310 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI schema object supported by the
+// validator.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Items                *Schema            `json:"items"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	AllOf                []*Schema          `json:"allOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+}
+
+// ValidationError is a single validation failure.
+type ValidationError struct {
+	// Location of the invalid value, for example "query.limit" or
+	// "body.items[0].name"
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Location + ": " + e.Message
+}
+
+var patterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patterns.Lock()
+	defer patterns.Unlock()
+	if re, ok := patterns.compiled[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.compiled[pattern] = re
+	return re, nil
+}
+
+// validateValue validates a decoded JSON value against the schema and
+// returns all the failures found.
+func (s *Spec) validateValue(schema *Schema, value interface{}, location string) []ValidationError {
+	schema, err := s.resolveSchema(schema)
+	if err != nil {
+		return []ValidationError{{Location: location, Message: err.Error()}}
+	}
+	if schema == nil {
+		return nil
+	}
+
+	errs := []ValidationError{}
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, ValidationError{Location: location, Message: fmt.Sprintf(format, a...)})
+	}
+
+	for _, sub := range schema.AllOf {
+		errs = append(errs, s.validateValue(sub, value, location)...)
+	}
+	if len(schema.AnyOf) > 0 && s.countMatches(schema.AnyOf, value, location) == 0 {
+		fail("must match at least one of the anyOf schemas")
+	}
+	if len(schema.OneOf) > 0 && s.countMatches(schema.OneOf, value, location) != 1 {
+		fail("must match exactly one of the oneOf schemas")
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			fail("must not be null")
+		}
+		return errs
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		fail("must be one of %v", schema.Enum)
+	}
+
+	switch schema.Type {
+	case "":
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			break
+		}
+		errs = append(errs, s.validateString(schema, str, location)...)
+	case "integer", "number":
+		num, ok := value.(float64)
+		if !ok {
+			fail("must be a %s", schema.Type)
+			break
+		}
+		if schema.Type == "integer" && num != math.Trunc(num) {
+			fail("must be an integer")
+		}
+		if schema.Minimum != nil && (num < *schema.Minimum || (schema.ExclusiveMinimum && num == *schema.Minimum)) {
+			fail("must be greater than %s%v", orEqual(schema.ExclusiveMinimum), *schema.Minimum)
+		}
+		if schema.Maximum != nil && (num > *schema.Maximum || (schema.ExclusiveMaximum && num == *schema.Maximum)) {
+			fail("must be less than %s%v", orEqual(schema.ExclusiveMaximum), *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			break
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			fail("must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			fail("must contain at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			errs = append(errs, s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", location, i))...)
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			break
+		}
+		errs = append(errs, s.validateObject(schema, object, location)...)
+	default:
+		fail("unsupported schema type %s", schema.Type)
+	}
+	return errs
+}
+
+func (s *Spec) validateString(schema *Schema, str string, location string) []ValidationError {
+	errs := []ValidationError{}
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, ValidationError{Location: location, Message: fmt.Sprintf(format, a...)})
+	}
+
+	length := len([]rune(str))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		fail("must be at least %d characters long", *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		fail("must be at most %d characters long", *schema.MaxLength)
+	}
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err != nil {
+			fail("invalid pattern %s: %v", schema.Pattern, err)
+		} else if !re.MatchString(str) {
+			fail("must match the pattern %s", schema.Pattern)
+		}
+	}
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			fail("must be an RFC 3339 date-time")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			fail("must be a full-date")
+		}
+	}
+	return errs
+}
+
+func (s *Spec) validateObject(schema *Schema, object map[string]interface{}, location string) []ValidationError {
+	errs := []ValidationError{}
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, ValidationError{Location: join(location, name), Message: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	additional, allowAdditional := s.additionalProperties(schema)
+	for _, name := range names {
+		if property, ok := schema.Properties[name]; ok {
+			errs = append(errs, s.validateValue(property, object[name], join(location, name))...)
+			continue
+		}
+		if !allowAdditional {
+			errs = append(errs, ValidationError{Location: join(location, name), Message: "is not allowed"})
+			continue
+		}
+		if additional != nil {
+			errs = append(errs, s.validateValue(additional, object[name], join(location, name))...)
+		}
+	}
+	return errs
+}
+
+// additionalProperties returns the schema of the additional properties and
+// whether they are allowed at all.
+func (s *Spec) additionalProperties(schema *Schema) (*Schema, bool) {
+	if len(schema.AdditionalProperties) == 0 {
+		return nil, true
+	}
+	allowed := true
+	if err := json.Unmarshal(schema.AdditionalProperties, &allowed); err == nil {
+		return nil, allowed
+	}
+	additional := &Schema{}
+	if err := json.Unmarshal(schema.AdditionalProperties, additional); err != nil {
+		return nil, true
+	}
+	return additional, true
+}
+
+func (s *Spec) countMatches(schemas []*Schema, value interface{}, location string) int {
+	matches := 0
+	for _, sub := range schemas {
+		if len(s.validateValue(sub, value, location)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// coerce converts the string value of a parameter into the type declared
+// by its schema so that it can be validated like a JSON value.
+func (s *Spec) coerce(schema *Schema, value string) interface{} {
+	schema, err := s.resolveSchema(schema)
+	if err != nil || schema == nil {
+		return value
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if num, err := strconv.ParseFloat(value, 64); err == nil {
+			return num
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(normalizeNumber(allowed), normalizeNumber(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNumber converts the integers produced by the YAML parser into
+// float64, the type used by encoding/json.
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return value
+}
+
+func orEqual(exclusive bool) string {
+	if exclusive {
+		return ""
+	}
+	return "or equal to "
+}
+
+func join(location string, name string) string {
+	if location == "" {
+		return name
+	}
+	return location + "." + name
+}
@@ -0,0 +1,319 @@
+// Package openapi validates the requests converted by the adapters against
+// an OpenAPI 3 specification bundled with the function, and returns 400
+// responses with structured errors before the framework runs. HTTP APIs do
+// not validate requests natively, the Validator centralizes the validation
+// for all the routes.
+//
+// The package supports the subset of OpenAPI used to describe JSON APIs:
+// path, query and header parameters, JSON request bodies, and schemas with
+// types, formats, enums, numeric and length limits, patterns, required
+// properties, additionalProperties, allOf/anyOf/oneOf and local $ref.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is a parsed OpenAPI specification.
+type Spec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components Components                            `json:"components"`
+
+	routes []*route
+}
+
+// Components holds the reusable objects referenced by the specification.
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas"`
+	Parameters    map[string]*Parameter   `json:"parameters"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies"`
+	Responses     map[string]*Response    `json:"responses"`
+}
+
+// Operation is a single API operation of a path.
+type Operation struct {
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path, query or header parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Ref      string               `json:"$ref"`
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema of a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// route is an operation matched against the request method and path.
+type route struct {
+	segments  []string
+	literals  int
+	path      string
+	method    string
+	operation *Operation
+}
+
+// Load parses a JSON or YAML OpenAPI specification. Specifications are
+// normally bundled with the function, for example with go:embed.
+func Load(data []byte) (*Spec, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		converted, err := yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse OpenAPI specification: %v", err)
+		}
+		data = converted
+	}
+
+	spec := &Spec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("Could not parse OpenAPI specification: %v", err)
+	}
+	if err := spec.buildRoutes(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (s *Spec) buildRoutes() error {
+	for path, item := range s.Paths {
+		common := []*Parameter{}
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &common); err != nil {
+				return fmt.Errorf("Invalid parameters for path %s: %v", path, err)
+			}
+		}
+
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			operation := &Operation{}
+			if err := json.Unmarshal(raw, operation); err != nil {
+				return fmt.Errorf("Invalid operation %s %s: %v", strings.ToUpper(method), path, err)
+			}
+			parameters, err := s.mergeParameters(common, operation.Parameters)
+			if err != nil {
+				return err
+			}
+			operation.Parameters = parameters
+			if operation.RequestBody, err = s.resolveRequestBody(operation.RequestBody); err != nil {
+				return err
+			}
+
+			r := &route{
+				segments:  strings.Split(strings.Trim(path, "/"), "/"),
+				path:      path,
+				method:    strings.ToUpper(method),
+				operation: operation,
+			}
+			for _, segment := range r.segments {
+				if !isTemplate(segment) {
+					r.literals++
+				}
+			}
+			s.routes = append(s.routes, r)
+		}
+	}
+
+	// routes with more literal segments win: /users/me before /users/{id}
+	sort.SliceStable(s.routes, func(i, j int) bool {
+		if s.routes[i].literals != s.routes[j].literals {
+			return s.routes[i].literals > s.routes[j].literals
+		}
+		return s.routes[i].path < s.routes[j].path
+	})
+	return nil
+}
+
+// mergeParameters resolves the references and lets the operation parameters
+// override the path parameters with the same name and location.
+func (s *Spec) mergeParameters(common []*Parameter, own []*Parameter) ([]*Parameter, error) {
+	merged := []*Parameter{}
+	index := map[string]int{}
+	for _, list := range [][]*Parameter{common, own} {
+		for _, p := range list {
+			resolved, err := s.resolveParameter(p)
+			if err != nil {
+				return nil, err
+			}
+			key := resolved.In + ":" + resolved.Name
+			if i, ok := index[key]; ok {
+				merged[i] = resolved
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, resolved)
+		}
+	}
+	return merged, nil
+}
+
+func (s *Spec) resolveParameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "#/components/parameters/")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := s.Components.Parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown parameter reference %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (s *Spec) resolveRequestBody(b *RequestBody) (*RequestBody, error) {
+	if b == nil || b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "#/components/requestBodies/")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := s.Components.RequestBodies[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown request body reference %s", b.Ref)
+	}
+	return resolved, nil
+}
+
+func (s *Spec) resolveResponse(r *Response) (*Response, error) {
+	if r == nil || r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "#/components/responses/")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := s.Components.Responses[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown response reference %s", r.Ref)
+	}
+	return resolved, nil
+}
+
+func (s *Spec) resolveSchema(schema *Schema) (*Schema, error) {
+	for depth := 0; schema != nil && schema.Ref != ""; depth++ {
+		if depth > 32 {
+			return nil, errors.New("Too many nested schema references")
+		}
+		name, err := refName(schema.Ref, "#/components/schemas/")
+		if err != nil {
+			return nil, err
+		}
+		resolved, ok := s.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown schema reference %s", schema.Ref)
+		}
+		schema = resolved
+	}
+	return schema, nil
+}
+
+// findRoute returns the operation for the method and path, and the values
+// of the path parameters.
+func (s *Spec) findRoute(method string, path string) (*route, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range s.routes {
+		if r.method != method || len(r.segments) != len(segments) {
+			continue
+		}
+		params := map[string]string{}
+		matched := true
+		for i, segment := range r.segments {
+			if isTemplate(segment) {
+				params[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r, params
+		}
+	}
+	return nil, nil
+}
+
+func isTemplate(segment string) bool {
+	return len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}'
+}
+
+func refName(ref string, prefix string) (string, error) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("Unsupported reference %s, only local references to %s are supported", ref, prefix)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// yamlToJSON converts a YAML document into JSON so that the specification
+// can be parsed with the JSON struct tags.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	converted, err := convertYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(converted)
+}
+
+func convertYAML(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := convertYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, item := range v {
+			converted, err := convertYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	}
+	return value, nil
+}
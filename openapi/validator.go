@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrorResponse is the body of the 400 responses generated by the Validator.
+type ErrorResponse struct {
+	Message string            `json:"message"`
+	Errors  []ValidationError `json:"errors"`
+}
+
+// Validator validates requests against a Spec.
+type Validator struct {
+	spec             *Spec
+	rejectUnknown    bool
+	responseReporter ResponseReporter
+}
+
+// NewValidator creates a new Validator for the given specification. By
+// default requests that do not match any operation are passed to the next
+// handler, which normally generates a 404 response.
+func NewValidator(spec *Spec) *Validator {
+	return &Validator{spec: spec}
+}
+
+// SetRejectUnknownRoutes instructs the validator to answer requests that do
+// not match any operation of the specification with a 404 response.
+func (v *Validator) SetRejectUnknownRoutes(reject bool) {
+	v.rejectUnknown = reject
+}
+
+// Handler returns an http.Handler that validates the request and calls the
+// next handler. Invalid requests receive a 400 response with a JSON
+// ErrorResponse body.
+func (v *Validator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, _ := v.spec.findRoute(req.Method, req.URL.Path)
+		if r == nil {
+			if v.rejectUnknown {
+				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		errs, err := v.ValidateRequest(req)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if len(errs) > 0 {
+			writeErrors(w, errs)
+			return
+		}
+		if v.responseReporter != nil {
+			v.serveAndValidate(next, w, req)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// ValidateRequest validates the parameters and body of the request against
+// the matching operation. The request body is read and replaced, so that it
+// can still be read by the handlers. An error is returned only when the
+// body cannot be read.
+func (v *Validator) ValidateRequest(req *http.Request) ([]ValidationError, error) {
+	r, pathParams := v.spec.findRoute(req.Method, req.URL.Path)
+	if r == nil {
+		return nil, nil
+	}
+
+	errs := []ValidationError{}
+	query := req.URL.Query()
+	for _, p := range r.operation.Parameters {
+		location := p.In + "." + p.Name
+		var values []string
+		switch p.In {
+		case "path":
+			if value, ok := pathParams[p.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = req.Header[http.CanonicalHeaderKey(p.Name)]
+		default:
+			continue
+		}
+		errs = append(errs, v.validateParameter(p, values, location)...)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	errs = append(errs, v.validateBody(r.operation.RequestBody, req.Header.Get("Content-Type"), body)...)
+	return errs, nil
+}
+
+func (v *Validator) validateParameter(p *Parameter, values []string, location string) []ValidationError {
+	if len(values) == 0 {
+		if p.Required {
+			return []ValidationError{{Location: location, Message: "is required"}}
+		}
+		return nil
+	}
+
+	schema, err := v.spec.resolveSchema(p.Schema)
+	if err != nil {
+		return []ValidationError{{Location: location, Message: err.Error()}}
+	}
+	if schema != nil && schema.Type == "array" {
+		// form style: ?id=1&id=2 or ?id=1,2
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = v.spec.coerce(schema.Items, value)
+		}
+		return v.spec.validateValue(schema, items, location)
+	}
+	return v.spec.validateValue(schema, v.spec.coerce(schema, values[0]), location)
+}
+
+func (v *Validator) validateBody(requestBody *RequestBody, contentType string, body []byte) []ValidationError {
+	if requestBody == nil {
+		return nil
+	}
+	if len(body) == 0 {
+		if requestBody.Required {
+			return []ValidationError{{Location: "body", Message: "is required"}}
+		}
+		return nil
+	}
+
+	mediaType, found := findMediaType(requestBody.Content, contentType)
+	if !found {
+		return []ValidationError{{Location: "header.Content-Type", Message: "unsupported content type " + contentType}}
+	}
+	return v.spec.validateJSON(mediaType, contentType, body, "body")
+}
+
+// validateJSON decodes and validates JSON payloads. Payloads of other
+// content types are not validated.
+func (s *Spec) validateJSON(mediaType MediaType, contentType string, body []byte, location string) []ValidationError {
+	if mediaType.Schema == nil || !isJSON(contentType) {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []ValidationError{{Location: location, Message: "is not valid JSON: " + err.Error()}}
+	}
+	return s.validateValue(mediaType.Schema, value, location)
+}
+
+// findMediaType looks up the content type exactly, then by type wildcard
+// (application/*) and finally the catch all */*.
+func findMediaType(content map[string]MediaType, contentType string) (MediaType, bool) {
+	if len(content) == 0 {
+		return MediaType{}, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	mediaType = strings.ToLower(mediaType)
+	if m, ok := content[mediaType]; ok {
+		return m, true
+	}
+	if i := strings.Index(mediaType, "/"); i > 0 {
+		if m, ok := content[mediaType[:i]+"/*"]; ok {
+			return m, true
+		}
+	}
+	m, ok := content["*/*"]
+	return m, ok
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func writeErrors(w http.ResponseWriter, errs []ValidationError) {
+	body, _ := json.Marshal(ErrorResponse{
+		Message: "Request validation failed",
+		Errors:  errs,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
@@ -0,0 +1,178 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/openapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const petsSpec = `
+openapi: 3.0.0
+info:
+  title: Pets
+  version: "1"
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            minimum: 1
+            maximum: 50
+      responses:
+        200:
+          description: pets
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        201:
+          description: created
+  /pets/{id}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      responses:
+        200:
+          description: pet
+components:
+  parameters:
+    PetID:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: string
+        pattern: '^[0-9a-f]{8}$'
+  schemas:
+    Pet:
+      type: object
+      required: [name, kind]
+      additionalProperties: false
+      properties:
+        name:
+          type: string
+          minLength: 1
+        kind:
+          type: string
+          enum: [dog, cat]
+        age:
+          type: integer
+`
+
+var _ = Describe("OpenAPI validator tests", func() {
+	spec, specErr := openapi.Load([]byte(petsSpec))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	adapter := httpadapter.New(openapi.NewValidator(spec).Handler(ok))
+
+	decodeErrors := func(body string) []openapi.ValidationError {
+		resp := openapi.ErrorResponse{}
+		Expect(json.Unmarshal([]byte(body), &resp)).To(BeNil())
+		return resp.Errors
+	}
+
+	It("Loads YAML specifications", func() {
+		Expect(specErr).To(BeNil())
+	})
+
+	Context("Parameters", func() {
+		It("Accepts valid query parameters", func() {
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:                  "/pets",
+				HTTPMethod:            "GET",
+				QueryStringParameters: map[string]string{"limit": "10"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+
+		It("Rejects invalid query parameters", func() {
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:                  "/pets",
+				HTTPMethod:            "GET",
+				QueryStringParameters: map[string]string{"limit": "100"},
+			})
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			errs := decodeErrors(resp.Body)
+			Expect(1).To(Equal(len(errs)))
+			Expect("query.limit").To(Equal(errs[0].Location))
+
+			resp, _ = adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:                  "/pets",
+				HTTPMethod:            "GET",
+				QueryStringParameters: map[string]string{"limit": "ten"},
+			})
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("Validates path parameters declared at the path level", func() {
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/pets/0123abcd", HTTPMethod: "GET"})
+			Expect(resp.StatusCode).To(Equal(200))
+
+			resp, _ = adapter.Proxy(events.APIGatewayProxyRequest{Path: "/pets/nope", HTTPMethod: "GET"})
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect("path.id").To(Equal(decodeErrors(resp.Body)[0].Location))
+		})
+	})
+
+	Context("Request bodies", func() {
+		post := func(body string) events.APIGatewayProxyResponse {
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/pets",
+				HTTPMethod: "POST",
+				Headers:    map[string]string{"Content-Type": "application/json; charset=utf-8"},
+				Body:       body,
+			})
+			Expect(err).To(BeNil())
+			return resp
+		}
+
+		It("Accepts valid bodies", func() {
+			Expect(post(`{"name":"Rex","kind":"dog","age":3}`).StatusCode).To(Equal(200))
+		})
+
+		It("Reports all the errors in the body", func() {
+			resp := post(`{"kind":"fish","age":1.5,"owner":"me"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			locations := []string{}
+			for _, e := range decodeErrors(resp.Body) {
+				locations = append(locations, e.Location)
+			}
+			Expect(locations).To(ConsistOf("body.name", "body.kind", "body.age", "body.owner"))
+		})
+
+		It("Rejects missing and malformed bodies", func() {
+			Expect(post("").StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(post("{").StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("Unknown routes", func() {
+		It("Passes unknown routes to the handler by default", func() {
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/owners", HTTPMethod: "GET"})
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+
+		It("Can reject unknown routes", func() {
+			validator := openapi.NewValidator(spec)
+			validator.SetRejectUnknownRoutes(true)
+			strict := httpadapter.New(validator.Handler(ok))
+			resp, _ := strict.Proxy(events.APIGatewayProxyRequest{Path: "/owners", HTTPMethod: "GET"})
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+// Package replay protects handlers from replayed requests. Clients send a
+// timestamp and a unique nonce with each request, optionally signed with a
+// shared secret, and the Middleware rejects requests that are too old or
+// whose nonce was already seen. This is a common requirement for webhook
+// receivers running behind API Gateway without a WAF.
+package replay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default header names read by the Middleware.
+const (
+	DefaultTimestampHeader = "X-Request-Timestamp"
+	DefaultNonceHeader     = "X-Request-Nonce"
+	DefaultSignatureHeader = "X-Request-Signature"
+)
+
+// DefaultTolerance is the maximum age, and clock skew, accepted for the
+// request timestamp.
+const DefaultTolerance = 5 * time.Minute
+
+// NonceStore records the nonces seen by the Middleware.
+type NonceStore interface {
+	// Seen atomically records the nonce until the given expiration and
+	// returns true if the nonce was already recorded.
+	Seen(nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryStore is a NonceStore that keeps the nonces in memory. Nonces are
+// only shared by the requests served by the same container, functions that
+// scale to multiple containers should use a store backed by a shared
+// database, for example a DynamoDB table with conditional writes.
+type MemoryStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewMemoryStore creates a new, empty, MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nonces: make(map[string]time.Time)}
+}
+
+// Seen implementation from the NonceStore interface. Expired nonces are
+// removed from the store on each call.
+func (s *MemoryStore) Seen(nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for n, exp := range s.nonces {
+		if now.After(exp) {
+			delete(s.nonces, n)
+		}
+	}
+	if _, ok := s.nonces[nonce]; ok {
+		return true, nil
+	}
+	s.nonces[nonce] = expires
+	return false, nil
+}
+
+// Middleware validates the timestamp, nonce and, when a secret is set, the
+// signature of each request.
+type Middleware struct {
+	store           NonceStore
+	tolerance       time.Duration
+	secret          []byte
+	timestampHeader string
+	nonceHeader     string
+	signatureHeader string
+}
+
+// New creates a new Middleware that records nonces in the given store.
+func New(store NonceStore) *Middleware {
+	return &Middleware{
+		store:           store,
+		tolerance:       DefaultTolerance,
+		timestampHeader: DefaultTimestampHeader,
+		nonceHeader:     DefaultNonceHeader,
+		signatureHeader: DefaultSignatureHeader,
+	}
+}
+
+// SetTolerance sets the maximum age of the request timestamp.
+func (m *Middleware) SetTolerance(tolerance time.Duration) {
+	m.tolerance = tolerance
+}
+
+// SetSecret enables signature verification. The signature header must
+// contain the hex encoded HMAC-SHA256, computed with the secret, of the
+// string "timestamp.nonce.body".
+func (m *Middleware) SetSecret(secret []byte) {
+	m.secret = secret
+}
+
+// SetHeaders changes the names of the timestamp, nonce and signature
+// headers.
+func (m *Middleware) SetHeaders(timestamp string, nonce string, signature string) {
+	m.timestampHeader = timestamp
+	m.nonceHeader = nonce
+	m.signatureHeader = signature
+}
+
+// Handler returns an http.Handler that validates the request and calls the
+// next handler. Invalid and replayed requests receive a 401 response.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timestamp := req.Header.Get(m.timestampHeader)
+		nonce := req.Header.Get(m.nonceHeader)
+		if timestamp == "" || nonce == "" {
+			http.Error(w, "Missing request timestamp or nonce", http.StatusUnauthorized)
+			return
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid request timestamp", http.StatusUnauthorized)
+			return
+		}
+		requestTime := time.Unix(seconds, 0)
+		if age := time.Since(requestTime); age > m.tolerance || age < -m.tolerance {
+			http.Error(w, "Request timestamp outside of the tolerance window", http.StatusUnauthorized)
+			return
+		}
+
+		if m.secret != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if !m.validSignature(req.Header.Get(m.signatureHeader), timestamp, nonce, body) {
+				http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		// the nonce must be remembered for as long as the timestamp is valid
+		seen, err := m.store.Seen(nonce, requestTime.Add(m.tolerance))
+		if err != nil {
+			log.Println("Could not check request nonce")
+			log.Println(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if seen {
+			http.Error(w, "Replayed request", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Sign returns the signature the Middleware expects for the given
+// timestamp, nonce and body. It can be used by clients and in tests.
+func Sign(secret []byte, timestamp string, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *Middleware) validSignature(signature string, timestamp string, nonce string, body []byte) bool {
+	expected := Sign(m.secret, timestamp, nonce, body)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
@@ -0,0 +1,94 @@
+package replay_test
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/replay"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replay protection tests", func() {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	webhook := func(timestamp string, nonce string, signature string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Path:       "/webhook",
+			HTTPMethod: "POST",
+			Body:       "payload",
+			Headers: map[string]string{
+				replay.DefaultTimestampHeader: timestamp,
+				replay.DefaultNonceHeader:     nonce,
+				replay.DefaultSignatureHeader: signature,
+			},
+		}
+	}
+	now := func() string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	Context("Timestamp and nonce", func() {
+		It("Accepts a request once and rejects replays", func() {
+			adapter := httpadapter.New(replay.New(replay.NewMemoryStore()).Handler(ok))
+			req := webhook(now(), "nonce-1", "")
+
+			resp, err := adapter.Proxy(req)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+
+			resp, err = adapter.Proxy(req)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("Rejects requests without nonce", func() {
+			adapter := httpadapter.New(replay.New(replay.NewMemoryStore()).Handler(ok))
+			resp, _ := adapter.Proxy(webhook(now(), "", ""))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("Rejects old timestamps", func() {
+			adapter := httpadapter.New(replay.New(replay.NewMemoryStore()).Handler(ok))
+			old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+			resp, _ := adapter.Proxy(webhook(old, "nonce-1", ""))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("Signatures", func() {
+		It("Validates the request signature", func() {
+			secret := []byte("secret")
+			m := replay.New(replay.NewMemoryStore())
+			m.SetSecret(secret)
+			adapter := httpadapter.New(m.Handler(ok))
+
+			timestamp := now()
+			resp, _ := adapter.Proxy(webhook(timestamp, "nonce-1", "bad"))
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+			signature := replay.Sign(secret, timestamp, "nonce-2", []byte("payload"))
+			resp, _ = adapter.Proxy(webhook(timestamp, "nonce-2", signature))
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+	})
+
+	Context("Memory store", func() {
+		It("Forgets expired nonces", func() {
+			store := replay.NewMemoryStore()
+			seen, err := store.Seen("nonce", time.Now().Add(-time.Second))
+			Expect(err).To(BeNil())
+			Expect(seen).To(BeFalse())
+
+			seen, _ = store.Seen("nonce", time.Now().Add(time.Minute))
+			Expect(seen).To(BeFalse())
+			seen, _ = store.Seen("nonce", time.Now().Add(time.Minute))
+			Expect(seen).To(BeTrue())
+		})
+	})
+})
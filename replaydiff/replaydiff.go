@@ -0,0 +1,192 @@
+// Package replaydiff runs the same recorded API Gateway proxy events through
+// two adapters, or two handlers, and produces a structured report of the
+// differences between their responses. It is meant to be used from the
+// tests of applications migrating from one framework adapter to another:
+//
+//	events, _ := replaydiff.LoadEvents("testdata/events")
+//	report := replaydiff.Compare(events, ginLambda.Proxy, chiLambda.Proxy)
+//	if !report.Equal() {
+//		report.WriteJSON(os.Stdout)
+//		t.Fail()
+//	}
+package replaydiff
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ProxyFunc is the signature of the Proxy method exposed by the adapters.
+type ProxyFunc func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Event is a recorded proxy event.
+type Event struct {
+	Name    string
+	Request events.APIGatewayProxyRequest
+}
+
+// Difference is a single field that differs between the two responses.
+type Difference struct {
+	Field     string `json:"field"`
+	Baseline  string `json:"baseline"`
+	Candidate string `json:"candidate"`
+}
+
+// Result is the comparison of the responses for a single event.
+type Result struct {
+	Name        string       `json:"name"`
+	Equal       bool         `json:"equal"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// Report is the comparison of the responses for all the events.
+type Report struct {
+	Total   int      `json:"total"`
+	Matched int      `json:"matched"`
+	Results []Result `json:"results"`
+}
+
+// Equal returns true when all the responses matched.
+func (r Report) Equal() bool {
+	return r.Total == r.Matched
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// LoadEvents reads all the .json files in the given directory as proxy
+// events. The events are named after the file and sorted by name.
+func LoadEvents(dir string) ([]Event, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	loaded := make([]Event, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		event := Event{Name: strings.TrimSuffix(filepath.Base(file), ".json")}
+		if err := json.Unmarshal(data, &event.Request); err != nil {
+			return nil, fmt.Errorf("Could not unmarshal event %s: %v", file, err)
+		}
+		loaded = append(loaded, event)
+	}
+	return loaded, nil
+}
+
+// Compare sends each event to the baseline and the candidate and compares
+// the responses. Errors returned by either function are reported as a
+// difference on the "error" field.
+func Compare(recorded []Event, baseline ProxyFunc, candidate ProxyFunc) Report {
+	report := Report{Results: make([]Result, 0, len(recorded))}
+	for _, event := range recorded {
+		baselineResp, baselineErr := baseline(event.Request)
+		candidateResp, candidateErr := candidate(event.Request)
+
+		result := Result{Name: event.Name}
+		result.Differences = append(result.Differences, diffErrors(baselineErr, candidateErr)...)
+		result.Differences = append(result.Differences, diffResponses(baselineResp, candidateResp)...)
+		result.Equal = len(result.Differences) == 0
+
+		report.Total++
+		if result.Equal {
+			report.Matched++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func diffErrors(baseline error, candidate error) []Difference {
+	if baseline == nil && candidate == nil {
+		return nil
+	}
+	b, c := "", ""
+	if baseline != nil {
+		b = baseline.Error()
+	}
+	if candidate != nil {
+		c = candidate.Error()
+	}
+	if b == c {
+		return nil
+	}
+	return []Difference{{Field: "error", Baseline: b, Candidate: c}}
+}
+
+func diffResponses(baseline events.APIGatewayProxyResponse, candidate events.APIGatewayProxyResponse) []Difference {
+	diffs := []Difference{}
+	if baseline.StatusCode != candidate.StatusCode {
+		diffs = append(diffs, Difference{
+			Field:     "statusCode",
+			Baseline:  strconv.Itoa(baseline.StatusCode),
+			Candidate: strconv.Itoa(candidate.StatusCode),
+		})
+	}
+
+	names := map[string]bool{}
+	for name := range baseline.Headers {
+		names[name] = true
+	}
+	for name := range candidate.Headers {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	for _, name := range sortedNames {
+		if baseline.Headers[name] != candidate.Headers[name] {
+			diffs = append(diffs, Difference{
+				Field:     "headers." + name,
+				Baseline:  baseline.Headers[name],
+				Candidate: candidate.Headers[name],
+			})
+		}
+	}
+
+	// bodies are compared once decoded so that a difference in the base64
+	// decision alone is reported on the isBase64Encoded field only
+	baselineBody := decodedBody(baseline)
+	candidateBody := decodedBody(candidate)
+	if baselineBody != candidateBody {
+		diffs = append(diffs, Difference{Field: "body", Baseline: baselineBody, Candidate: candidateBody})
+	}
+	if baseline.IsBase64Encoded != candidate.IsBase64Encoded {
+		diffs = append(diffs, Difference{
+			Field:     "isBase64Encoded",
+			Baseline:  strconv.FormatBool(baseline.IsBase64Encoded),
+			Candidate: strconv.FormatBool(candidate.IsBase64Encoded),
+		})
+	}
+	return diffs
+}
+
+func decodedBody(resp events.APIGatewayProxyResponse) string {
+	if !resp.IsBase64Encoded {
+		return resp.Body
+	}
+	decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+	if err != nil {
+		return resp.Body
+	}
+	return string(decoded)
+}
@@ -0,0 +1,13 @@
+package replaydiff_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestReplayDiff(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Replay Diff Suite")
+}
@@ -0,0 +1,78 @@
+package replaydiff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/replaydiff"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replay diff tests", func() {
+	baseline := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("path " + req.URL.Path))
+	}))
+	candidate := httpadapter.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if req.URL.Path == "/changed" {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		w.Write([]byte("path " + req.URL.Path))
+	}))
+
+	recorded := []replaydiff.Event{
+		{Name: "same", Request: events.APIGatewayProxyRequest{Path: "/same", HTTPMethod: "GET"}},
+		{Name: "changed", Request: events.APIGatewayProxyRequest{Path: "/changed", HTTPMethod: "GET"}},
+	}
+
+	Context("Comparing adapters", func() {
+		It("Reports the differing events", func() {
+			report := replaydiff.Compare(recorded, baseline.Proxy, candidate.Proxy)
+			Expect(report.Equal()).To(BeFalse())
+			Expect(2).To(Equal(report.Total))
+			Expect(1).To(Equal(report.Matched))
+			Expect(report.Results[0].Equal).To(BeTrue())
+			Expect(report.Results[1].Equal).To(BeFalse())
+			Expect([]replaydiff.Difference{{Field: "statusCode", Baseline: "200", Candidate: "202"}}).To(Equal(report.Results[1].Differences))
+		})
+
+		It("Writes the report as JSON", func() {
+			report := replaydiff.Compare(recorded, baseline.Proxy, baseline.Proxy)
+			Expect(report.Equal()).To(BeTrue())
+
+			buf := &bytes.Buffer{}
+			Expect(report.WriteJSON(buf)).To(BeNil())
+			decoded := replaydiff.Report{}
+			Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(BeNil())
+			Expect(2).To(Equal(decoded.Matched))
+		})
+	})
+
+	Context("Loading events", func() {
+		It("Reads the json files in a directory", func() {
+			dir, err := ioutil.TempDir("", "replaydiff")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+
+			Expect(ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"path":"/b","httpMethod":"GET"}`), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"path":"/a","httpMethod":"POST"}`), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0644)).To(BeNil())
+
+			loaded, err := replaydiff.LoadEvents(dir)
+			Expect(err).To(BeNil())
+			Expect(2).To(Equal(len(loaded)))
+			Expect("a").To(Equal(loaded[0].Name))
+			Expect("POST").To(Equal(loaded[0].Request.HTTPMethod))
+			Expect("/b").To(Equal(loaded[1].Request.Path))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+// Package secrets retrieves values from AWS Secrets Manager and AWS Systems
+// Manager Parameter Store through the AWS Parameters and Secrets Lambda
+// extension. The extension must be added to the function as a layer; it
+// exposes a local HTTP endpoint, so no AWS SDK dependency is required.
+// Values are cached in the container for a configurable TTL.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultExtensionEndpoint is the local endpoint of the Parameters and
+// Secrets Lambda extension when the PARAMETERS_SECRETS_EXTENSION_HTTP_PORT
+// environment variable is not set.
+const DefaultExtensionEndpoint = "http://localhost:2773"
+
+// DefaultTTL is how long values are cached by the Client.
+const DefaultTTL = 5 * time.Minute
+
+// ExtensionPortVariable is the name of the environment variable that
+// contains the port of the extension.
+const ExtensionPortVariable = "PARAMETERS_SECRETS_EXTENSION_HTTP_PORT"
+
+const tokenHeader = "X-Aws-Parameters-Secrets-Token"
+
+// Source returns the current value of a secret or parameter. It is
+// implemented by the values returned by the Secret and Parameter methods
+// of the Client, and can be implemented by tests with static values.
+type Source interface {
+	Value() (string, error)
+}
+
+// StaticSource is a Source that always returns the same value.
+type StaticSource string
+
+// Value implementation from the Source interface.
+func (s StaticSource) Value() (string, error) {
+	return string(s), nil
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Client retrieves and caches values from the extension.
+type Client struct {
+	endpoint   string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewClient creates a new Client for the extension running in the current
+// Lambda execution environment.
+func NewClient() *Client {
+	endpoint := DefaultExtensionEndpoint
+	if port, ok := os.LookupEnv(ExtensionPortVariable); ok {
+		endpoint = "http://localhost:" + port
+	}
+	return &Client{
+		endpoint:   endpoint,
+		ttl:        DefaultTTL,
+		httpClient: &http.Client{Timeout: 2 * time.Second},
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// SetEndpoint overrides the address of the extension.
+func (c *Client) SetEndpoint(endpoint string) {
+	c.endpoint = endpoint
+}
+
+// SetTTL sets how long values are cached.
+func (c *Client) SetTTL(ttl time.Duration) {
+	c.ttl = ttl
+}
+
+// GetSecretString returns the SecretString of a Secrets Manager secret.
+func (c *Client) GetSecretString(secretID string) (string, error) {
+	return c.get("/secretsmanager/get?secretId="+url.QueryEscape(secretID), func(body []byte) (string, error) {
+		secret := struct {
+			SecretString string `json:"SecretString"`
+		}{}
+		err := json.Unmarshal(body, &secret)
+		return secret.SecretString, err
+	})
+}
+
+// GetParameter returns the decrypted value of a Parameter Store parameter.
+func (c *Client) GetParameter(name string) (string, error) {
+	return c.get("/systemsmanager/parameters/get?withDecryption=true&name="+url.QueryEscape(name), func(body []byte) (string, error) {
+		parameter := struct {
+			Parameter struct {
+				Value string `json:"Value"`
+			} `json:"Parameter"`
+		}{}
+		err := json.Unmarshal(body, &parameter)
+		return parameter.Parameter.Value, err
+	})
+}
+
+// Secret returns a Source for the given Secrets Manager secret.
+func (c *Client) Secret(secretID string) Source {
+	return sourceFunc(func() (string, error) {
+		return c.GetSecretString(secretID)
+	})
+}
+
+// Parameter returns a Source for the given Parameter Store parameter.
+func (c *Client) Parameter(name string) Source {
+	return sourceFunc(func() (string, error) {
+		return c.GetParameter(name)
+	})
+}
+
+type sourceFunc func() (string, error)
+
+func (f sourceFunc) Value() (string, error) {
+	return f()
+}
+
+// get returns the cached value for the path or calls the extension. When the
+// extension fails and a stale value is cached, the stale value is returned.
+func (c *Client) get(path string, parse func([]byte) (string, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, cached := c.cache[path]
+	if cached && time.Now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := c.fetch(path, parse)
+	if err != nil {
+		if cached {
+			return entry.value, nil
+		}
+		return "", err
+	}
+	c.cache[path] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+	return value, nil
+}
+
+func (c *Client) fetch(path string, parse func([]byte) (string, error)) (string, error) {
+	req, err := http.NewRequest("GET", c.endpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(tokenHeader, os.Getenv("AWS_SESSION_TOKEN"))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Secrets extension returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return parse(body)
+}
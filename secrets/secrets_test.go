@@ -0,0 +1,80 @@
+package secrets_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/secrets"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secrets client tests", func() {
+	var server *httptest.Server
+	calls := 0
+	failing := false
+
+	BeforeEach(func() {
+		calls = 0
+		failing = false
+		os.Setenv("AWS_SESSION_TOKEN", "token")
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if failing || req.Header.Get("X-Aws-Parameters-Secrets-Token") != "token" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			switch req.URL.Path {
+			case "/secretsmanager/get":
+				Expect(req.URL.Query().Get("secretId")).To(Equal("my/secret"))
+				w.Write([]byte(`{"Name":"my/secret","SecretString":"s3cr3t"}`))
+			case "/systemsmanager/parameters/get":
+				Expect(req.URL.Query().Get("name")).To(Equal("/app/param"))
+				Expect(req.URL.Query().Get("withDecryption")).To(Equal("true"))
+				w.Write([]byte(`{"Parameter":{"Name":"/app/param","Value":"v1"}}`))
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.Unsetenv("AWS_SESSION_TOKEN")
+	})
+
+	It("Reads secrets and parameters through the extension", func() {
+		client := secrets.NewClient()
+		client.SetEndpoint(server.URL)
+
+		value, err := client.GetSecretString("my/secret")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("s3cr3t"))
+
+		value, err = client.Parameter("/app/param").Value()
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1"))
+	})
+
+	It("Caches values and falls back to stale values on errors", func() {
+		client := secrets.NewClient()
+		client.SetEndpoint(server.URL)
+		client.SetTTL(0)
+
+		_, err := client.GetSecretString("my/secret")
+		Expect(err).To(BeNil())
+		failing = true
+		value, err := client.GetSecretString("my/secret")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("s3cr3t"))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("Returns errors when nothing is cached", func() {
+		failing = true
+		client := secrets.NewClient()
+		client.SetEndpoint(server.URL)
+		_, err := client.GetParameter("/app/param")
+		Expect(err).ToNot(BeNil())
+	})
+})
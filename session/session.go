@@ -0,0 +1,194 @@
+// Package session stores signed session data in cookies that fit within the
+// header size constraints of API Gateway. Values that do not fit in a single
+// cookie are split across numbered chunk cookies on the way out and
+// reassembled, and verified, on the way in.
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChunkSize is the maximum length of the value of each chunk cookie.
+// It leaves room for the cookie name and attributes within the 4KB that
+// browsers accept for a single cookie.
+const DefaultChunkSize = 3800
+
+// DefaultMaxChunks is the maximum number of chunk cookies written by the
+// Store. API Gateway limits the total size of the request headers, larger
+// sessions should be stored server side.
+const DefaultMaxChunks = 3
+
+var (
+	// ErrNotFound is returned when the request does not contain the session cookie.
+	ErrNotFound = errors.New("Session cookie not found")
+	// ErrInvalid is returned when the session cookie is malformed or its
+	// signature does not match.
+	ErrInvalid = errors.New("Invalid session cookie")
+	// ErrExpired is returned when the session is older than the configured max age.
+	ErrExpired = errors.New("Session cookie expired")
+	// ErrTooLarge is returned when the encoded session needs more chunks
+	// than the configured maximum.
+	ErrTooLarge = errors.New("Session too large for the cookie size limits")
+)
+
+// Store signs, chunks and reassembles session cookies.
+type Store struct {
+	secret    []byte
+	chunkSize int
+	maxChunks int
+	maxAge    time.Duration
+
+	// Cookie is the template used for the attributes of the chunk cookies.
+	// Name, Value, Expires and MaxAge are ignored.
+	Cookie http.Cookie
+}
+
+// NewStore creates a new Store that signs cookies with the given secret.
+// Cookies are HttpOnly, Secure, SameSite=Lax and valid for the whole domain
+// by default.
+func NewStore(secret []byte) *Store {
+	return &Store{
+		secret:    secret,
+		chunkSize: DefaultChunkSize,
+		maxChunks: DefaultMaxChunks,
+		maxAge:    24 * time.Hour,
+		Cookie: http.Cookie{
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+}
+
+// SetChunkSize sets the maximum length of the value of each cookie. A size
+// of zero or less restores the DefaultChunkSize.
+func (s *Store) SetChunkSize(size int) {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	s.chunkSize = size
+}
+
+// SetMaxChunks sets the maximum number of cookies used for a session.
+func (s *Store) SetMaxChunks(maxChunks int) {
+	s.maxChunks = maxChunks
+}
+
+// SetMaxAge sets how long a session remains valid after it was saved.
+func (s *Store) SetMaxAge(maxAge time.Duration) {
+	s.maxAge = maxAge
+}
+
+// Save signs the value and writes it to the response as one or more cookies
+// named name.0, name.1, and so on. Chunks left over from a previous, larger,
+// session in the request are expired.
+func (s *Store) Save(w http.ResponseWriter, req *http.Request, name string, value []byte) error {
+	encoded := s.encode(name, value, time.Now())
+	chunks := (len(encoded) + s.chunkSize - 1) / s.chunkSize
+	if chunks > s.maxChunks {
+		return ErrTooLarge
+	}
+
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * s.chunkSize
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		cookie := s.Cookie
+		cookie.Name = chunkName(name, i)
+		cookie.Value = encoded[i*s.chunkSize : end]
+		cookie.MaxAge = int(s.maxAge / time.Second)
+		http.SetCookie(w, &cookie)
+	}
+	s.expireChunks(w, req, name, chunks)
+	return nil
+}
+
+// Load reassembles the session cookies from the request, verifies their
+// signature and age, and returns the session value.
+func (s *Store) Load(req *http.Request, name string) ([]byte, error) {
+	encoded := ""
+	for i := 0; ; i++ {
+		cookie, err := req.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+		encoded += cookie.Value
+	}
+	if encoded == "" {
+		return nil, ErrNotFound
+	}
+	return s.decode(name, encoded, time.Now())
+}
+
+// Clear expires all the session cookies sent with the request.
+func (s *Store) Clear(w http.ResponseWriter, req *http.Request, name string) {
+	s.expireChunks(w, req, name, 0)
+}
+
+func (s *Store) expireChunks(w http.ResponseWriter, req *http.Request, name string, from int) {
+	if req == nil {
+		return
+	}
+	for i := from; ; i++ {
+		if _, err := req.Cookie(chunkName(name, i)); err != nil {
+			return
+		}
+		cookie := s.Cookie
+		cookie.Name = chunkName(name, i)
+		cookie.MaxAge = -1
+		http.SetCookie(w, &cookie)
+	}
+}
+
+// encode returns base64(timestamp + value) + "." + base64(hmac). The cookie
+// name is part of the signature so that values cannot be moved between
+// sessions.
+func (s *Store) encode(name string, value []byte, now time.Time) string {
+	payload := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(payload, uint64(now.Unix()))
+	copy(payload[8:], value)
+
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(s.sign(name, encodedPayload))
+}
+
+func (s *Store) decode(name string, encoded string, now time.Time) ([]byte, error) {
+	parts := strings.SplitN(encoded, ".", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, s.sign(name, parts[0])) {
+		return nil, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(payload) < 8 {
+		return nil, ErrInvalid
+	}
+
+	saved := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if s.maxAge > 0 && now.Sub(saved) > s.maxAge {
+		return nil, ErrExpired
+	}
+	return payload[8:], nil
+}
+
+func (s *Store) sign(name string, encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(name + "|" + encodedPayload))
+	return mac.Sum(nil)
+}
+
+func chunkName(name string, index int) string {
+	return name + "." + strconv.Itoa(index)
+}
@@ -0,0 +1,114 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/session"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// roundTrip copies the cookies set on the recorder into a new request.
+func roundTrip(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge >= 0 {
+			req.AddCookie(cookie)
+		}
+	}
+	return req
+}
+
+var _ = Describe("Session store tests", func() {
+	store := session.NewStore([]byte("secret"))
+
+	Context("Saving and loading", func() {
+		It("Round trips a small session in a single cookie", func() {
+			rec := httptest.NewRecorder()
+			Expect(store.Save(rec, nil, "sess", []byte("user=1"))).To(BeNil())
+			Expect(1).To(Equal(len(rec.Result().Cookies())))
+
+			value, err := store.Load(roundTrip(rec), "sess")
+			Expect(err).To(BeNil())
+			Expect("user=1").To(Equal(string(value)))
+		})
+
+		It("Chunks large sessions", func() {
+			large := []byte(strings.Repeat("x", 5000))
+			rec := httptest.NewRecorder()
+			Expect(store.Save(rec, nil, "sess", large)).To(BeNil())
+			cookies := rec.Result().Cookies()
+			Expect(2).To(Equal(len(cookies)))
+			for _, cookie := range cookies {
+				Expect(len(cookie.Value)).To(BeNumerically("<=", session.DefaultChunkSize))
+			}
+
+			value, err := store.Load(roundTrip(rec), "sess")
+			Expect(err).To(BeNil())
+			Expect(large).To(Equal(value))
+		})
+
+		It("Expires leftover chunks from a larger session", func() {
+			rec := httptest.NewRecorder()
+			Expect(store.Save(rec, nil, "sess", []byte(strings.Repeat("x", 5000)))).To(BeNil())
+			req := roundTrip(rec)
+
+			rec = httptest.NewRecorder()
+			Expect(store.Save(rec, req, "sess", []byte("small"))).To(BeNil())
+			cookies := rec.Result().Cookies()
+			Expect(2).To(Equal(len(cookies)))
+			Expect("sess.1").To(Equal(cookies[1].Name))
+			Expect(cookies[1].MaxAge).To(BeNumerically("<", 0))
+		})
+
+		It("Uses the default chunk size for invalid sizes", func() {
+			store := session.NewStore([]byte("secret"))
+			store.SetChunkSize(0)
+			rec := httptest.NewRecorder()
+			Expect(store.Save(rec, nil, "sess", []byte(strings.Repeat("x", 5000)))).To(BeNil())
+			Expect(2).To(Equal(len(rec.Result().Cookies())))
+		})
+
+		It("Refuses sessions above the chunk limit", func() {
+			rec := httptest.NewRecorder()
+			err := store.Save(rec, nil, "sess", []byte(strings.Repeat("x", 20000)))
+			Expect(err).To(Equal(session.ErrTooLarge))
+		})
+	})
+
+	Context("Validation", func() {
+		It("Rejects tampered cookies", func() {
+			rec := httptest.NewRecorder()
+			Expect(store.Save(rec, nil, "sess", []byte("user=1"))).To(BeNil())
+			cookie := rec.Result().Cookies()[0]
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: "AAAA" + cookie.Value})
+			_, err := store.Load(req, "sess")
+			Expect(err).To(Equal(session.ErrInvalid))
+
+			other := session.NewStore([]byte("other-secret"))
+			_, err = other.Load(roundTrip(rec), "sess")
+			Expect(err).To(Equal(session.ErrInvalid))
+		})
+
+		It("Rejects expired sessions", func() {
+			short := session.NewStore([]byte("secret"))
+			short.SetMaxAge(time.Nanosecond)
+			rec := httptest.NewRecorder()
+			Expect(short.Save(rec, nil, "sess", []byte("user=1"))).To(BeNil())
+			time.Sleep(10 * time.Millisecond)
+			_, err := short.Load(roundTrip(rec), "sess")
+			Expect(err).To(Equal(session.ErrExpired))
+		})
+
+		It("Returns ErrNotFound without cookies", func() {
+			_, err := store.Load(httptest.NewRequest("GET", "/", nil), "sess")
+			Expect(err).To(Equal(session.ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,171 @@
+// Package shadow sends each request to a primary and to a shadow handler,
+// returns the response of the primary handler and reports the differences
+// between the two responses. It is meant to validate the migration to a new
+// framework or handler implementation under real traffic: wrap the current
+// and the new handler with New and pass the result to any of the adapters.
+package shadow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// Diff describes the comparison of the primary and shadow responses for a
+// single request.
+type Diff struct {
+	Method          string
+	Path            string
+	PrimaryStatus   int
+	ShadowStatus    int
+	Headers         []string
+	PrimaryBodyHash string
+	ShadowBodyHash  string
+	ShadowPanic     interface{}
+}
+
+// Equal returns true when the two responses have the same status, headers
+// and body.
+func (d Diff) Equal() bool {
+	return d.PrimaryStatus == d.ShadowStatus &&
+		len(d.Headers) == 0 &&
+		d.PrimaryBodyHash == d.ShadowBodyHash &&
+		d.ShadowPanic == nil
+}
+
+// Reporter receives the result of the comparison of each request.
+type Reporter func(diff Diff)
+
+// Handler is an http.Handler that mirrors requests to a shadow handler.
+type Handler struct {
+	primary        http.Handler
+	shadow         http.Handler
+	reporter       Reporter
+	reportMatches  bool
+	ignoredHeaders map[string]bool
+
+	contentTypeDefault core.ContentTypeDefault
+	defaultContentType string
+}
+
+// New creates a new Handler. The reporter is called for every request whose
+// shadow response differs from the primary response.
+func New(primary http.Handler, shadow http.Handler, reporter Reporter) *Handler {
+	return &Handler{
+		primary:        primary,
+		shadow:         shadow,
+		reporter:       reporter,
+		ignoredHeaders: map[string]bool{"Date": true},
+	}
+}
+
+// SetReportMatches instructs the handler to call the reporter for all
+// requests, including the ones where the responses match.
+func (h *Handler) SetReportMatches(reportMatches bool) {
+	h.reportMatches = reportMatches
+}
+
+// IgnoreHeaders excludes the given headers from the comparison. The Date
+// header is ignored by default.
+func (h *Handler) IgnoreHeaders(names ...string) {
+	for _, name := range names {
+		h.ignoredHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+// SetContentTypeDefault sets the Content-Type applied to the shadow
+// responses written without one. It should match the default of the
+// adapter serving the primary responses, see
+// core.ResponseOptions.SetContentTypeDefault, otherwise every response
+// without an explicit type is reported. The type is detected from the
+// body by default, like the adapters do.
+func (h *Handler) SetContentTypeDefault(d core.ContentTypeDefault, contentType string) {
+	h.contentTypeDefault = d
+	h.defaultContentType = contentType
+}
+
+// ServeHTTP implementation from the http.Handler interface. The shadow
+// handler runs after the primary handler returns, within the same
+// invocation, so that the comparison completes before Lambda freezes the
+// container. Panics in the shadow handler are recovered and reported.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Println("Could not read request body for shadow traffic")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	shadowReq := req.Clone(req.Context())
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	shadowReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	primaryHash := sha256.New()
+	primary := core.NewResponseRecorder(w)
+	primary.CopyBodyTo(primaryHash)
+	h.primary.ServeHTTP(primary, req)
+
+	shadowHash := sha256.New()
+	shadow := core.NewResponseRecorder(nil)
+	shadow.SetContentTypeDefault(h.contentTypeDefault, h.defaultContentType)
+	shadow.CopyBodyTo(shadowHash)
+	diff := Diff{Method: req.Method, Path: req.URL.Path}
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				diff.ShadowPanic = p
+			}
+		}()
+		h.shadow.ServeHTTP(shadow, shadowReq)
+	}()
+
+	diff.PrimaryStatus = primary.StatusCode()
+	diff.ShadowStatus = shadow.StatusCode()
+	diff.PrimaryBodyHash = hex.EncodeToString(primaryHash.Sum(nil))
+	diff.ShadowBodyHash = hex.EncodeToString(shadowHash.Sum(nil))
+	diff.Headers = h.headerDiff(primary.Header(), shadow.Header())
+
+	if h.reportMatches || !diff.Equal() {
+		h.reporter(diff)
+	}
+}
+
+// headerDiff returns the sorted names of the headers whose values differ.
+func (h *Handler) headerDiff(primary http.Header, shadow http.Header) []string {
+	names := map[string]bool{}
+	for name := range primary {
+		names[name] = true
+	}
+	for name := range shadow {
+		names[name] = true
+	}
+
+	diff := []string{}
+	for name := range names {
+		if h.ignoredHeaders[name] {
+			continue
+		}
+		if !equalValues(primary[name], shadow[name]) {
+			diff = append(diff, name)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+func equalValues(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
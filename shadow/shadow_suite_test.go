@@ -0,0 +1,13 @@
+package shadow_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestShadow(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Shadow Suite")
+}
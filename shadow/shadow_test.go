@@ -0,0 +1,114 @@
+package shadow_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/shadow"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func echoHandler(status int, header string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("X-Version", header)
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+var _ = Describe("Shadow handler tests", func() {
+	postRequest := events.APIGatewayProxyRequest{
+		Path:       "/orders",
+		HTTPMethod: "POST",
+		Body:       "order-1",
+	}
+
+	Context("Comparing responses", func() {
+		It("Returns the primary response and reports differences", func() {
+			diffs := []shadow.Diff{}
+			h := shadow.New(echoHandler(201, "v1"), echoHandler(200, "v2"), func(d shadow.Diff) {
+				diffs = append(diffs, d)
+			})
+			adapter := httpadapter.New(h)
+
+			resp, err := adapter.Proxy(postRequest)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(201))
+			Expect(resp.Body).To(Equal("order-1"))
+			Expect(resp.Headers["X-Version"]).To(Equal("v1"))
+
+			Expect(1).To(Equal(len(diffs)))
+			Expect(diffs[0].Equal()).To(BeFalse())
+			Expect("/orders").To(Equal(diffs[0].Path))
+			Expect(201).To(Equal(diffs[0].PrimaryStatus))
+			Expect(200).To(Equal(diffs[0].ShadowStatus))
+			Expect([]string{"X-Version"}).To(Equal(diffs[0].Headers))
+			Expect(diffs[0].PrimaryBodyHash).To(Equal(diffs[0].ShadowBodyHash))
+		})
+
+		It("Does not report matching responses by default", func() {
+			reported := 0
+			h := shadow.New(echoHandler(200, "v1"), echoHandler(200, "v1"), func(d shadow.Diff) {
+				reported++
+			})
+			adapter := httpadapter.New(h)
+			adapter.Proxy(postRequest)
+			Expect(0).To(Equal(reported))
+
+			h.SetReportMatches(true)
+			adapter.Proxy(postRequest)
+			Expect(1).To(Equal(reported))
+		})
+
+		It("Ignores the configured headers", func() {
+			reported := 0
+			h := shadow.New(echoHandler(200, "v1"), echoHandler(200, "v2"), func(d shadow.Diff) {
+				reported++
+			})
+			h.IgnoreHeaders("x-version")
+			httpadapter.New(h).Proxy(postRequest)
+			Expect(0).To(Equal(reported))
+		})
+
+		It("Recovers from panics in the shadow handler", func() {
+			var diff shadow.Diff
+			panicking := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				panic("boom")
+			})
+			h := shadow.New(echoHandler(200, "v1"), panicking, func(d shadow.Diff) {
+				diff = d
+			})
+			resp, err := httpadapter.New(h).Proxy(postRequest)
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(diff.ShadowPanic).To(Equal("boom"))
+		})
+
+		It("Applies the content type default of the adapter to the shadow", func() {
+			untyped := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte("<html></html>"))
+			})
+			reported := 0
+			h := shadow.New(untyped, untyped, func(d shadow.Diff) {
+				reported++
+			})
+			adapter := httpadapter.New(h)
+			adapter.SetContentTypeDefault(core.NoContentType, "")
+
+			resp, err := adapter.Proxy(postRequest)
+			Expect(err).To(BeNil())
+			Expect(resp.Headers).ToNot(HaveKey("Content-Type"))
+			Expect(1).To(Equal(reported))
+
+			h.SetContentTypeDefault(core.NoContentType, "")
+			adapter.Proxy(postRequest)
+			Expect(1).To(Equal(reported))
+		})
+	})
+})
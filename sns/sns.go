@@ -0,0 +1,126 @@
+// Package sns dispatches SNS notifications through an HTTP handler, so
+// notification processing can share routing and middleware with the API.
+// Each record becomes a POST request with the message as the body and the
+// same x-amz-sns-* headers SNS sends to HTTP subscriptions:
+//
+//	adapter := sns.New(router)
+//	adapter.Map("arn:aws:sns:us-east-1:123456789012:orders", "/notifications/orders")
+//	lambda.Start(adapter.Handle)
+//
+// Handle returns an error when a handler answers with a 5xx status code, so
+// that Lambda retries the asynchronous invocation.
+package sns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultPath is the path of the requests for topics that are not mapped
+const DefaultPath = "/sns"
+
+// TopicARNHeader is the header key containing the ARN of the topic
+const TopicARNHeader = "X-Amz-Sns-Topic-Arn"
+
+// MessageIDHeader is the header key containing the id of the message
+const MessageIDHeader = "X-Amz-Sns-Message-Id"
+
+// MessageTypeHeader is the header key containing the type of the message
+const MessageTypeHeader = "X-Amz-Sns-Message-Type"
+
+// SubscriptionARNHeader is the header key containing the ARN of the
+// subscription that delivered the message
+const SubscriptionARNHeader = "X-Amz-Sns-Subscription-Arn"
+
+// SubjectHeader is the custom header key containing the subject of the
+// message, when it is set
+const SubjectHeader = "X-GoLambdaProxy-SNS-Subject"
+
+type contextKey struct{}
+
+// RecordFromContext returns the SNS record that generated the request.
+func RecordFromContext(ctx context.Context) (events.SNSEventRecord, bool) {
+	record, ok := ctx.Value(contextKey{}).(events.SNSEventRecord)
+	return record, ok
+}
+
+// Adapter converts SNS records into requests for the wrapped handler.
+type Adapter struct {
+	handler     http.Handler
+	defaultPath string
+	paths       map[string]string
+}
+
+// New creates a new Adapter that sends the notifications to the given
+// handler.
+func New(handler http.Handler) *Adapter {
+	return &Adapter{
+		handler:     handler,
+		defaultPath: DefaultPath,
+		paths:       make(map[string]string),
+	}
+}
+
+// Map sets the path of the requests generated for the notifications of the
+// given topic.
+func (a *Adapter) Map(topicARN string, path string) {
+	a.paths[topicARN] = path
+}
+
+// SetDefaultPath sets the path of the requests generated for the topics
+// that are not mapped.
+func (a *Adapter) SetDefaultPath(path string) {
+	a.defaultPath = path
+}
+
+// Handle dispatches each record of the event through the handler. Returns
+// an error if any of the requests is answered with a 5xx status code.
+func (a *Adapter) Handle(event events.SNSEvent) error {
+	failed := []string{}
+	for _, record := range event.Records {
+		req, err := a.RecordToHTTPRequest(record)
+		if err != nil {
+			return core.NewLoggedError("Could not convert SNS record to request: %v", err)
+		}
+
+		w := core.NewProxyResponseWriter()
+		a.handler.ServeHTTP(http.ResponseWriter(w), req)
+		if resp, err := w.GetProxyResponse(); err == nil && resp.StatusCode >= http.StatusInternalServerError {
+			failed = append(failed, fmt.Sprintf("%s (%d)", record.SNS.MessageID, resp.StatusCode))
+		}
+	}
+	if len(failed) > 0 {
+		return core.NewLoggedError("Handler failed for messages: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// RecordToHTTPRequest converts an SNS record into a POST http.Request
+// object. The record is stored in the context of the request and can be
+// read with RecordFromContext.
+func (a *Adapter) RecordToHTTPRequest(record events.SNSEventRecord) (*http.Request, error) {
+	path, ok := a.paths[record.SNS.TopicArn]
+	if !ok {
+		path = a.defaultPath
+	}
+
+	req, err := http.NewRequest(http.MethodPost, core.DefaultServerAddress+path, strings.NewReader(record.SNS.Message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+	req.Header.Set(TopicARNHeader, record.SNS.TopicArn)
+	req.Header.Set(MessageIDHeader, record.SNS.MessageID)
+	req.Header.Set(MessageTypeHeader, record.SNS.Type)
+	req.Header.Set(SubscriptionARNHeader, record.EventSubscriptionArn)
+	if record.SNS.Subject != "" {
+		req.Header.Set(SubjectHeader, record.SNS.Subject)
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, record)), nil
+}
@@ -0,0 +1,65 @@
+package sns_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/sns"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SNS adapter tests", func() {
+	var received []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		received = append(received, req.Method+" "+req.URL.Path+" "+string(body)+" "+req.Header.Get(sns.TopicARNHeader)+" "+req.Header.Get(sns.SubjectHeader))
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	record := func(topic, subject, message string) events.SNSEventRecord {
+		return events.SNSEventRecord{SNS: events.SNSEntity{
+			MessageID: "id-" + message,
+			TopicArn:  topic,
+			Subject:   subject,
+			Message:   message,
+		}}
+	}
+
+	BeforeEach(func() {
+		received = nil
+	})
+
+	Context("Dispatching notifications", func() {
+		It("Posts the messages to the mapped paths", func() {
+			adapter := sns.New(mux)
+			adapter.Map("arn:orders", "/notifications/orders")
+			err := adapter.Handle(events.SNSEvent{Records: []events.SNSEventRecord{
+				record("arn:orders", "created", "order-1"),
+				record("arn:other", "", "other-1"),
+			}})
+			Expect(err).To(BeNil())
+			Expect(received).To(Equal([]string{
+				"POST /notifications/orders order-1 arn:orders created",
+				"POST /sns other-1 arn:other ",
+			}))
+		})
+
+		It("Returns an error when a handler fails", func() {
+			adapter := sns.New(mux)
+			adapter.SetDefaultPath("/events")
+			err := adapter.Handle(events.SNSEvent{Records: []events.SNSEventRecord{
+				record("arn:orders", "", "fail"),
+				record("arn:orders", "", "ok"),
+			}})
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("Handler failed for messages: id-fail (503)"))
+			Expect(received).To(HaveLen(2))
+		})
+	})
+})
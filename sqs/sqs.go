@@ -0,0 +1,126 @@
+// Package sqs dispatches the messages of an SQS queue through an HTTP
+// handler, so webhooks can be buffered in a queue and processed by the same
+// handlers that serve the API. Each message body must contain either the
+// JSON representation of an API Gateway proxy event or a serialized
+// HTTP/1.1 request, as produced by http.Request.Write:
+//
+//	adapter := sqs.New(router)
+//	lambda.Start(adapter.Handle)
+//
+// Messages whose request is answered with a 5xx status code, or that cannot
+// be converted to a request, are returned as batch item failures. The event
+// source mapping must be configured with the ReportBatchItemFailures
+// function response type for the other messages to be deleted.
+package sqs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// MessageIDHeader is the custom header key containing the id of the message
+const MessageIDHeader = "X-GoLambdaProxy-SQS-Message-Id"
+
+// ReceiveCountHeader is the custom header key containing the number of times
+// the message has been received from the queue
+const ReceiveCountHeader = "X-GoLambdaProxy-SQS-Receive-Count"
+
+type contextKey struct{}
+
+// MessageFromContext returns the SQS message that generated the request.
+func MessageFromContext(ctx context.Context) (events.SQSMessage, bool) {
+	message, ok := ctx.Value(contextKey{}).(events.SQSMessage)
+	return message, ok
+}
+
+// Adapter converts SQS messages into requests for the wrapped handler.
+type Adapter struct {
+	core.RequestAccessor
+
+	handler http.Handler
+}
+
+// New creates a new Adapter that sends the messages to the given handler.
+func New(handler http.Handler) *Adapter {
+	return &Adapter{handler: handler}
+}
+
+// Handle dispatches each message of the event through the handler, in
+// order, and returns the messages that must be retried.
+func (a *Adapter) Handle(event events.SQSEvent) (events.SQSEventResponse, error) {
+	response := events.SQSEventResponse{}
+	for _, message := range event.Records {
+		status, err := a.dispatch(message)
+		if err != nil {
+			log.Printf("Could not convert message %s to request: %v\n", message.MessageId, err)
+		} else if status < http.StatusInternalServerError {
+			continue
+		}
+		response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+			ItemIdentifier: message.MessageId,
+		})
+	}
+	return response, nil
+}
+
+// MessageToHTTPRequest converts the body of a message into an http.Request
+// object. The message is stored in the context of the request and can be
+// read with MessageFromContext.
+func (a *Adapter) MessageToHTTPRequest(message events.SQSMessage) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(message.Body), "{") {
+		proxyEvent := events.APIGatewayProxyRequest{}
+		if err = json.Unmarshal([]byte(message.Body), &proxyEvent); err != nil {
+			return nil, err
+		}
+		req, err = a.ProxyEventToHTTPRequest(proxyEvent)
+	} else {
+		req, err = http.ReadRequest(bufio.NewReader(strings.NewReader(message.Body)))
+		if err == nil {
+			// requests read from the wire cannot be sent to a handler as
+			// client requests, the URI is kept in the URL only
+			req.RequestURI = ""
+			// the headers are written by the sender of the message, unlike
+			// those of the proxy events they are not filtered by the
+			// conversion
+			a.RemoveCustomHeaders(req.Header)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(MessageIDHeader, message.MessageId)
+	if count, ok := message.Attributes["ApproximateReceiveCount"]; ok {
+		req.Header.Set(ReceiveCountHeader, count)
+	}
+	return req.WithContext(context.WithValue(req.Context(), contextKey{}, message)), nil
+}
+
+func (a *Adapter) dispatch(message events.SQSMessage) (int, error) {
+	req, err := a.MessageToHTTPRequest(message)
+	if err != nil {
+		return 0, err
+	}
+
+	w := core.NewProxyResponseWriter()
+	a.handler.ServeHTTP(http.ResponseWriter(w), req)
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		// handlers that do not write a response succeed, as they would
+		// with net/http
+		return http.StatusOK, nil
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		log.Printf("Handler returned %d for message %s\n", resp.StatusCode, message.MessageId)
+	}
+	return resp.StatusCode, nil
+}
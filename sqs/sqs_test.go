@@ -0,0 +1,85 @@
+package sqs_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/awslabs/aws-lambda-go-api-proxy/sqs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQS adapter tests", func() {
+	var received []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhooks/", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		message, _ := sqs.MessageFromContext(req.Context())
+		received = append(received, req.Method+" "+req.URL.Path+" "+string(body)+" "+req.Header.Get(sqs.MessageIDHeader)+" "+message.EventSourceARN)
+		if req.URL.Path == "/webhooks/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+
+	BeforeEach(func() {
+		received = nil
+	})
+
+	Context("Dispatching messages", func() {
+		It("Accepts API Gateway events and raw HTTP requests", func() {
+			adapter := sqs.New(mux)
+			resp, err := adapter.Handle(events.SQSEvent{Records: []events.SQSMessage{
+				{
+					MessageId:      "1",
+					EventSourceARN: "arn:aws:sqs:queue",
+					Body:           `{"httpMethod":"POST","path":"/webhooks/github","body":"push"}`,
+				},
+				{
+					MessageId:      "2",
+					EventSourceARN: "arn:aws:sqs:queue",
+					Body:           "PUT /webhooks/stripe HTTP/1.1\r\nHost: example.com\r\nContent-Length: 6\r\n\r\ncharge",
+				},
+			}})
+			Expect(err).To(BeNil())
+			Expect(resp.BatchItemFailures).To(BeEmpty())
+			Expect(received).To(Equal([]string{
+				"POST /webhooks/github push 1 arn:aws:sqs:queue",
+				"PUT /webhooks/stripe charge 2 arn:aws:sqs:queue",
+			}))
+		})
+
+		It("Drops the custom headers of raw HTTP requests", func() {
+			adapter := sqs.New(mux)
+			adapter.SetHeaderPrefix("X-Lambda-")
+			req, err := adapter.MessageToHTTPRequest(events.SQSMessage{
+				MessageId: "3",
+				Body: "POST /webhooks/github HTTP/1.1\r\nHost: example.com\r\n" +
+					"X-GoLambdaProxy-ApiGw-Context: {\"stage\":\"forged\"}\r\n" +
+					"X-Lambda-Stage-Vars: {\"env\":\"forged\"}\r\n" +
+					"X-Hub-Signature: sha1=abc\r\nContent-Length: 4\r\n\r\npush",
+			})
+			Expect(err).To(BeNil())
+			Expect("").To(Equal(req.Header.Get(core.APIGwContextHeader)))
+			Expect("").To(Equal(req.Header.Get("X-Lambda-Stage-Vars")))
+			Expect("sha1=abc").To(Equal(req.Header.Get("X-Hub-Signature")))
+			Expect("3").To(Equal(req.Header.Get(sqs.MessageIDHeader)))
+		})
+
+		It("Reports failed and invalid messages", func() {
+			adapter := sqs.New(mux)
+			resp, err := adapter.Handle(events.SQSEvent{Records: []events.SQSMessage{
+				{MessageId: "ok", Body: `{"httpMethod":"POST","path":"/webhooks/ok"}`},
+				{MessageId: "failed", Body: `{"httpMethod":"POST","path":"/webhooks/fail"}`},
+				{MessageId: "invalid", Body: "not a request"},
+			}})
+			Expect(err).To(BeNil())
+			Expect(resp.BatchItemFailures).To(Equal([]events.SQSBatchItemFailure{
+				{ItemIdentifier: "failed"},
+				{ItemIdentifier: "invalid"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,132 @@
+// Package tenant resolves a tenant identifier from the custom domain name
+// used to invoke the API and injects it into the request context and a
+// custom header, so multi-tenant APIs served by a single function behind
+// several custom domains get consistent tenant awareness.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// DefaultHeader is the custom header the Middleware uses to store the
+// resolved tenant identifier in the request.
+const DefaultHeader = "X-GoLambdaProxy-Tenant"
+
+// ErrUnknownTenant is returned by resolvers when the domain does not belong
+// to any tenant. The Middleware answers these requests with a 404.
+var ErrUnknownTenant = errors.New("Unknown tenant")
+
+type contextKey struct{}
+
+// Resolver maps the domain name used by a request to a tenant identifier.
+type Resolver interface {
+	ResolveTenant(domain string) (string, error)
+}
+
+// ResolverFunc is an adapter that allows the use of ordinary functions as
+// tenant resolvers.
+type ResolverFunc func(domain string) (string, error)
+
+// ResolveTenant calls f(domain).
+func (f ResolverFunc) ResolveTenant(domain string) (string, error) {
+	return f(domain)
+}
+
+// MapResolver is a static Resolver backed by a map of domain names to
+// tenant identifiers. Domain names must be lowercase, internationalized
+// domain names must be punycode encoded.
+type MapResolver map[string]string
+
+// ResolveTenant returns the tenant mapped to the domain or ErrUnknownTenant.
+func (m MapResolver) ResolveTenant(domain string) (string, error) {
+	if tenantID, ok := m[domain]; ok {
+		return tenantID, nil
+	}
+	return "", ErrUnknownTenant
+}
+
+// FromContext returns the tenant identifier stored in the context by the
+// Middleware.
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(contextKey{}).(string)
+	return tenantID, ok
+}
+
+// Middleware resolves the tenant of each request before passing it to the
+// wrapped handler.
+type Middleware struct {
+	resolver Resolver
+	header   string
+}
+
+// New creates a new Middleware that uses the given resolver.
+func New(resolver Resolver) *Middleware {
+	return &Middleware{
+		resolver: resolver,
+		header:   DefaultHeader,
+	}
+}
+
+// SetHeader changes the name of the header used to store the tenant
+// identifier. An empty name disables the header, the tenant is then only
+// available through the FromContext function.
+func (m *Middleware) SetHeader(name string) {
+	m.header = name
+}
+
+// Handler returns an http.Handler that resolves the tenant of the request
+// and calls the next handler. Requests for unknown tenants receive a 404
+// response, other resolver errors generate a 500 response.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		domain := m.domainName(req)
+		tenantID, err := m.resolver.ResolveTenant(domain)
+		if err != nil {
+			if err == ErrUnknownTenant {
+				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			log.Printf("Could not resolve tenant for domain %s: %v\n", domain, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if m.header != "" {
+			req.Header.Set(m.header, tenantID)
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextKey{}, tenantID)))
+	})
+}
+
+// domainName returns the lowercase ASCII domain name used by the request. The
+// domain name of the API Gateway or HTTP API context stored in the request
+// context by the event conversion is preferred over the host of the request,
+// which is sent by the client.
+func (m *Middleware) domainName(req *http.Request) string {
+	domain := ""
+	if apiGwContext, ok := core.GetAPIGatewayContextFromContext(req.Context()); ok {
+		domain = apiGwContext.DomainName
+	}
+	if domain == "" {
+		if v2Context, ok := core.GetAPIGatewayV2ContextFromContext(req.Context()); ok {
+			domain = v2Context.DomainName
+		}
+	}
+	if domain == "" {
+		domain = req.Host
+	}
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	if normalized, err := core.NormalizeHost(domain); err == nil {
+		domain = normalized
+	}
+	return strings.ToLower(domain)
+}
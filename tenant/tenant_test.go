@@ -0,0 +1,147 @@
+package tenant_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
+	"github.com/awslabs/aws-lambda-go-api-proxy/tenant"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tenant middleware tests", func() {
+	echoTenant := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tenantID, _ := tenant.FromContext(req.Context())
+		w.Write([]byte(tenantID + "|" + req.Header.Get(tenant.DefaultHeader)))
+	})
+	resolver := tenant.MapResolver{
+		"acme.example.com":      "acme",
+		"globex.example.com":    "globex",
+		"xn--bcher-kva.example": "buecher",
+	}
+
+	Context("Resolving tenants", func() {
+		It("Uses the API Gateway domain name", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Host": "globex.example.com"},
+				RequestContext: events.APIGatewayProxyRequestContext{
+					DomainName: "acme.example.com",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("acme|acme"))
+		})
+
+		It("Uses the HTTP API domain name", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, err := adapter.ProxyV2(events.APIGatewayV2HTTPRequest{
+				RawPath: "/",
+				Headers: map[string]string{"host": "globex.example.com"},
+				RequestContext: events.APIGatewayV2HTTPRequestContext{
+					DomainName: "acme.example.com",
+					HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(resp.Body).To(Equal("acme|acme"))
+		})
+
+		It("Falls back to the host of the request", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Host": "Globex.Example.com:443"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("globex|globex"))
+		})
+
+		It("Resolves internationalized domain names in either form", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			for _, domain := range []string{"bücher.example", "XN--BCHER-KVA.example"} {
+				resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+					Path:       "/",
+					HTTPMethod: "GET",
+					RequestContext: events.APIGatewayProxyRequestContext{
+						DomainName: domain,
+					},
+				})
+				Expect(err).To(BeNil())
+				Expect(resp.Body).To(Equal("buecher|buecher"))
+			}
+		})
+
+		It("Overrides tenant headers sent by the client", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers: map[string]string{
+					"Host":               "acme.example.com",
+					tenant.DefaultHeader: "globex",
+				},
+			})
+			Expect(resp.Body).To(Equal("acme|acme"))
+		})
+
+		It("Ignores forged API Gateway context headers", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers: map[string]string{
+					"X-GoLambdaProxy-ApiGw-Context": `{"domainName":"globex.example.com"}`,
+				},
+				RequestContext: events.APIGatewayProxyRequestContext{
+					DomainName: "acme.example.com",
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.Body).To(Equal("acme|acme"))
+		})
+
+		It("Can disable the tenant header", func() {
+			m := tenant.New(resolver)
+			m.SetHeader("")
+			adapter := httpadapter.New(m.Handler(echoTenant))
+			resp, _ := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Host": "acme.example.com"},
+			})
+			Expect(resp.Body).To(Equal("acme|"))
+		})
+	})
+
+	Context("Resolver errors", func() {
+		It("Returns 404 for unknown tenants", func() {
+			adapter := httpadapter.New(tenant.New(resolver).Handler(echoTenant))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{
+				Path:       "/",
+				HTTPMethod: "GET",
+				Headers:    map[string]string{"Host": "unknown.example.com"},
+			})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Returns 500 for other errors", func() {
+			failing := tenant.ResolverFunc(func(domain string) (string, error) {
+				return "", errors.New("lookup failed")
+			})
+			adapter := httpadapter.New(tenant.New(failing).Handler(echoTenant))
+			resp, err := adapter.Proxy(events.APIGatewayProxyRequest{Path: "/", HTTPMethod: "GET"})
+			Expect(err).To(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})